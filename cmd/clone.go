@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zhoucq/mysql-exporter/exporter"
+)
+
+var (
+	cfgExisting  string
+	cfgBatchSize int
+)
+
+// cloneCmd copies the source database straight into a target database
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: msgs.CmdCloneShort,
+	Long:  msgs.CmdCloneLong,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cfgTargetDSN == "" {
+			return fmt.Errorf(msgs.ErrMissingTargetDSN)
+		}
+		if err := checkTargetDSN(cfgTargetDSN); err != nil {
+			return err
+		}
+
+		config, err := resolveConfig(cmd, "")
+		if err != nil {
+			return err
+		}
+		config.Observer = newProgressObserver(consoleObserver{})
+		config.ProgressRows = progressRows
+		config.Logger = logger
+		config.MaxRows = cfgRows
+		config.SingleTransaction = cfgSingleTransaction
+		config.Sample = cfgSample
+		config.Definer = cfgDefiner
+		config.AutoIncrement = cfgAutoIncrement
+		config.SQLSecurityInvoker = cfgSQLSecurityInvoker
+		config.SampleColumn = cfgSampleColumn
+		config.SamplePercent = cfgSamplePercent
+		config.MaxRowsPerSecond = cfgMaxRowsPerSecond
+		config.MaxBytesPerSecond = cfgMaxBytesPerSecond
+		config.MaxLoad = cfgMaxLoad
+		config.CheckReplicaDSN = cfgCheckReplicaDSN
+		config.MaxLag = cfgMaxLag
+		config.MaxStatementBytes = cfgMaxStatementBytes
+
+		exp, err := exporter.NewContext(cmd.Context(), config)
+		if err != nil {
+			return err
+		}
+		defer exp.Close()
+		target, _, err := exporter.OpenContext(cmd.Context(), exporter.Config{DSN: cfgTargetDSN})
+		if err != nil {
+			return err
+		}
+		defer target.Close()
+
+		return exp.CloneContext(cmd.Context(), target, exporter.CloneOptions{
+			Existing:  cfgExisting,
+			BatchSize: cfgBatchSize,
+		})
+	},
+}
+
+func init() {
+	cloneCmd.Flags().StringVar(&cfgTargetDSN, "target-dsn", "", msgs.FlagTargetDSN)
+	cloneCmd.Flags().StringVar(&cfgExisting, "existing", exporter.ExistingSkip, msgs.FlagExisting)
+	cloneCmd.Flags().IntVar(&cfgBatchSize, "batch-size", 1000, msgs.FlagBatchSize)
+	cloneCmd.Flags().IntVar(&cfgMaxStatementBytes, "max-statement-bytes", 0, msgs.FlagCloneMaxStatementBytes)
+	cloneCmd.Flags().IntVar(&cfgRows, "rows", 1000, msgs.FlagRows)
+	cloneCmd.Flags().BoolVar(&cfgSingleTransaction, "single-transaction", false, msgs.FlagSingleTransaction)
+	cloneCmd.Flags().StringVar(&cfgAutoIncrement, "auto-increment", exporter.AutoIncrementReset, msgs.FlagAutoIncrement)
+	cloneCmd.Flags().StringVar(&cfgDefiner, "definer", exporter.DefinerKeep, msgs.FlagDefiner)
+	cloneCmd.Flags().BoolVar(&cfgSQLSecurityInvoker, "sql-security-invoker", false, msgs.FlagSQLSecurityInvoker)
+	cloneCmd.Flags().StringVar(&cfgSample, "sample", exporter.SampleFirst, msgs.FlagSample)
+	cloneCmd.Flags().StringVar(&cfgSampleColumn, "sample-column", "", msgs.FlagSampleColumn)
+	cloneCmd.Flags().Float64Var(&cfgSamplePercent, "sample-percent", 0, msgs.FlagSamplePercent)
+	cloneCmd.Flags().IntVar(&cfgMaxRowsPerSecond, "max-rows-per-second", 0, msgs.FlagMaxRowsPerSecond)
+	cloneCmd.Flags().Int64Var(&cfgMaxBytesPerSecond, "max-bytes-per-second", 0, msgs.FlagMaxBytesPerSecond)
+	cloneCmd.Flags().StringVar(&cfgMaxLoad, "max-load", "", msgs.FlagMaxLoad)
+	cloneCmd.Flags().StringVar(&cfgCheckReplicaDSN, "check-replica-dsn", "", msgs.FlagCheckReplicaDSN)
+	cloneCmd.Flags().DurationVar(&cfgMaxLag, "max-lag", time.Second, msgs.FlagMaxLag)
+	rootCmd.AddCommand(cloneCmd)
+}
@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/spf13/cobra"
+	"github.com/zhoucq/mysql-exporter/exporter"
+	"golang.org/x/term"
+)
+
+// optionGroups are the option file groups read for connection settings,
+// later groups override earlier ones
+var optionGroups = []string{"client", "mysql-exporter"}
+
+// defaultOptionFiles returns the option files read when --defaults-file is not given
+func defaultOptionFiles() []string {
+	files := []string{"/etc/my.cnf", "/etc/mysql/my.cnf"}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".my.cnf"))
+	}
+	return files
+}
+
+// resolveConfig builds the connection part of the exporter configuration from
+// all credential sources. fallbackDatabase is used when no source names a database.
+//
+// Each connection setting is taken from the first source that provides it:
+//
+//  1. an explicitly set command line flag (--host, --port, --socket, --user, --password, --database)
+//  2. --password-file (password only)
+//  3. the --dsn flag
+//  4. environment variables (MYSQL_HOST, MYSQL_TCP_PORT, MYSQL_UNIX_PORT, MYSQL_PWD)
+//  5. the [client] and [mysql-exporter] groups of the option files
+//  6. the flag default, or an interactive prompt for the password
+func resolveConfig(cmd *cobra.Command, fallbackDatabase string) (exporter.Config, error) {
+	flags := cmd.Flags()
+
+	dsn := mysql.NewConfig()
+	if cfgDSN != "" {
+		parsed, err := mysql.ParseDSN(cfgDSN)
+		if err != nil {
+			return exporter.Config{}, fmt.Errorf(msgs.ErrParseDSN, err)
+		}
+		dsn = parsed
+	}
+	var dsnHost, dsnPort, dsnSocket string
+	switch dsn.Net {
+	case "tcp":
+		if host, port, err := net.SplitHostPort(dsn.Addr); err == nil {
+			dsnHost, dsnPort = host, port
+		}
+	case "unix":
+		dsnSocket = dsn.Addr
+	}
+
+	options, err := loadOptions()
+	if err != nil {
+		return exporter.Config{}, err
+	}
+
+	host := firstSet(flagValue(cmd, "host"), dsnHost, os.Getenv("MYSQL_HOST"), options["host"], cfgHost)
+	user := firstSet(flagValue(cmd, "user"), dsn.User, options["user"], cfgUser)
+	database := firstSet(flagValue(cmd, "database"), dsn.DBName, options["database"], fallbackDatabase)
+	if database == "" {
+		return exporter.Config{}, fmt.Errorf(msgs.ErrMissingDatabase)
+	}
+
+	port := cfgPort
+	if !flags.Changed("port") {
+		sources := []struct{ name, value string }{
+			{"--dsn", dsnPort},
+			{"MYSQL_TCP_PORT", os.Getenv("MYSQL_TCP_PORT")},
+			{"option files", options["port"]},
+		}
+		for _, source := range sources {
+			if source.value == "" {
+				continue
+			}
+			if port, err = strconv.Atoi(source.value); err != nil {
+				return exporter.Config{}, fmt.Errorf(msgs.ErrInvalidPort, source.value, source.name)
+			}
+			break
+		}
+	}
+
+	// Like the mysql client, a configured socket is only used for localhost
+	// unless it was requested explicitly
+	socket := firstSet(flagValue(cmd, "socket"), dsnSocket)
+	if socket == "" && host == "localhost" && !flags.Changed("port") {
+		socket = firstSet(os.Getenv("MYSQL_UNIX_PORT"), options["socket"])
+	}
+
+	password, err := resolvePassword(cmd, dsn.Passwd, options["password"])
+	if err != nil {
+		return exporter.Config{}, err
+	}
+
+	cleartext := cfgEnableCleartextPlugin
+	if !flags.Changed("enable-cleartext-plugin") {
+		if value, ok := options["enable-cleartext-plugin"]; ok {
+			cleartext = value == "" || value == "1" || strings.EqualFold(value, "true") || strings.EqualFold(value, "on")
+		}
+	}
+
+	// A timeout in the DSN wins over the default of the flag
+	connectTimeout := cfgConnectTimeout
+	if !flags.Changed("connect-timeout") && dsn.Timeout > 0 {
+		connectTimeout = dsn.Timeout
+	}
+
+	return exporter.Config{
+		DSN:      cfgDSN,
+		Host:     host,
+		Port:     port,
+		User:     user,
+		Password: password,
+		Socket:   socket,
+		Database: database,
+
+		SSLMode:               firstSet(flagValue(cmd, "ssl-mode"), options["ssl-mode"]),
+		SSLCA:                 firstSet(flagValue(cmd, "ssl-ca"), options["ssl-ca"]),
+		EnableCleartextPlugin: cleartext,
+		ServerPublicKeyPath:   firstSet(flagValue(cmd, "server-public-key-path"), options["server-public-key-path"]),
+
+		ConnectTimeout: connectTimeout,
+		ReadTimeout:    cfgReadTimeout,
+		WriteTimeout:   cfgWriteTimeout,
+		Retries:        cfgRetries,
+	}, nil
+}
+
+// resolvePassword finds the password following the precedence of resolveConfig,
+// prompting for it only when no other source provides one
+func resolvePassword(cmd *cobra.Command, dsnPassword, optionPassword string) (string, error) {
+	if cmd.Flags().Changed("password") {
+		return cfgPassword, nil
+	}
+	if cfgPasswordFile != "" {
+		content, err := os.ReadFile(cfgPasswordFile)
+		if err != nil {
+			return "", fmt.Errorf(msgs.ErrReadPasswordFile, cfgPasswordFile, err)
+		}
+		line, _, _ := strings.Cut(string(content), "\n")
+		return strings.TrimSuffix(line, "\r"), nil
+	}
+	if dsnPassword != "" {
+		return dsnPassword, nil
+	}
+	if password, ok := os.LookupEnv("MYSQL_PWD"); ok {
+		return password, nil
+	}
+	if optionPassword != "" {
+		return optionPassword, nil
+	}
+
+	// Never block a non-interactive job on a prompt nobody can answer
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf(msgs.ErrNoPassword)
+	}
+	fmt.Fprint(os.Stderr, msgs.PromptPassword)
+	return readPassword(cmd.Context(), int(syscall.Stdin))
+}
+
+// readPassword reads a password from the terminal without echo. Execute
+// catches Ctrl-C, so the prompt gives up once ctx is canceled and restores
+// the echo itself; the abandoned read ends with the process.
+func readPassword(ctx context.Context, fd int) (string, error) {
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "", fmt.Errorf(msgs.ErrReadPassword, err)
+	}
+	type result struct {
+		password []byte
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		password, err := term.ReadPassword(fd)
+		done <- result{password, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return "", fmt.Errorf(msgs.ErrReadPassword, r.err)
+		}
+		fmt.Fprintln(os.Stderr) // 添加换行符，因为ReadPassword不会自动添加
+		return string(r.password), nil
+	case <-ctx.Done():
+		term.Restore(fd, state)
+		fmt.Fprintln(os.Stderr)
+		return "", &exporter.CanceledError{Err: ctx.Err()}
+	}
+}
+
+// flagValue returns the value of a string flag only when it was set explicitly
+func flagValue(cmd *cobra.Command, name string) string {
+	if !cmd.Flags().Changed(name) {
+		return ""
+	}
+	value, _ := cmd.Flags().GetString(name)
+	return value
+}
+
+// firstSet returns the first non-empty value
+func firstSet(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// loadOptions reads the connection settings from the MySQL option files
+func loadOptions() (map[string]string, error) {
+	options := map[string]string{}
+	visited := map[string]bool{}
+	if cfgDefaultsFile != "" {
+		// An explicitly requested file must exist
+		if err := readOptionFile(cfgDefaultsFile, options, visited); err != nil {
+			return nil, err
+		}
+		return options, nil
+	}
+	for _, file := range defaultOptionFiles() {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if err := readOptionFile(file, options, visited); err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+// readOptionFile parses a my.cnf style option file and stores the settings of
+// optionGroups into options. Option names are normalized to use dashes, so
+// ssl_ca and ssl-ca are the same option. !include and !includedir directives
+// are followed; visited holds the files already read, so that a file
+// including itself is read only once.
+func readOptionFile(path string, options map[string]string, visited map[string]bool) error {
+	if abs, err := filepath.Abs(path); err == nil {
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		if visited[abs] {
+			return nil
+		}
+		visited[abs] = true
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrReadOptionFile, path, err)
+	}
+	defer file.Close()
+
+	inGroup := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case strings.HasPrefix(line, "!include "):
+			include := optionFilePath(path, strings.TrimPrefix(line, "!include "))
+			if err := readOptionFile(include, options, visited); err != nil {
+				return err
+			}
+			continue
+		case strings.HasPrefix(line, "!includedir "):
+			dir := optionFilePath(path, strings.TrimPrefix(line, "!includedir "))
+			if err := readOptionDir(dir, options, visited); err != nil {
+				return err
+			}
+			continue
+		case line[0] == '[':
+			group := strings.TrimSpace(strings.Trim(line, "[]"))
+			inGroup = false
+			for _, g := range optionGroups {
+				if strings.EqualFold(group, g) {
+					inGroup = true
+				}
+			}
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, "=")
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-")
+		options[name] = parseOptionValue(strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf(msgs.ErrReadOptionFile, path, err)
+	}
+	return nil
+}
+
+// readOptionDir reads the *.cnf files of an !includedir directory in the
+// order of their names, like the mysql client
+func readOptionDir(dir string, options map[string]string, visited map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrReadOptionFile, dir, err)
+	}
+	// os.ReadDir returns the entries sorted by name
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".cnf" {
+			continue
+		}
+		if err := readOptionFile(filepath.Join(dir, entry.Name()), options, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// optionFilePath resolves the target of an include directive relative to the
+// directory of the option file containing it
+func optionFilePath(from, target string) string {
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(from), target)
+	}
+	return target
+}
+
+// parseOptionValue unquotes an option value and strips trailing comments
+func parseOptionValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	quote := value[0]
+	if quote != '\'' && quote != '"' {
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		return strings.TrimSpace(value)
+	}
+
+	var result strings.Builder
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		if c == quote {
+			break
+		}
+		if c == '\\' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			case 'b':
+				c = '\b'
+			case 's':
+				c = ' '
+			default:
+				c = value[i]
+			}
+		}
+		result.WriteByte(c)
+	}
+	return result.String()
+}
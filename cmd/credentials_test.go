@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// writeFile writes content to name in dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadOptionFile(t *testing.T) {
+	dir := t.TempDir()
+	confd := filepath.Join(dir, "conf.d")
+	if err := os.Mkdir(confd, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, confd, "20-port.cnf", "[client]\nport = 3320\nuser = second\n")
+	writeFile(t, confd, "10-user.cnf", "[client]\nuser = first\n")
+	writeFile(t, confd, "30-ignored.txt", "[client]\nuser = ignored\n")
+	writeFile(t, dir, "extra.cnf", "!include my.cnf\n[mysql-exporter]\nssl_ca = /etc/ca.pem\n")
+	path := writeFile(t, dir, "my.cnf", `# comment
+; another comment
+[mysqld]
+port = 1
+[client]
+host = db.example.com  # trailing comment
+password = "p#ss\tword"
+socket = '/tmp/my sql.sock'
+enable-cleartext-plugin
+!include extra.cnf
+!include my.cnf
+!includedir conf.d
+`)
+
+	options := map[string]string{}
+	if err := readOptionFile(path, options, map[string]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"host":                    "db.example.com",
+		"password":                "p#ss\tword",
+		"socket":                  "/tmp/my sql.sock",
+		"enable-cleartext-plugin": "",
+		"ssl-ca":                  "/etc/ca.pem",
+		"user":                    "second",
+		"port":                    "3320",
+	}
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("options = %q, want %q", options, want)
+	}
+
+	if err := readOptionFile(filepath.Join(dir, "missing.cnf"), map[string]string{}, map[string]bool{}); err == nil {
+		t.Error("reading a missing option file succeeded")
+	}
+	broken := writeFile(t, dir, "broken.cnf", "!includedir missing.d\n")
+	if err := readOptionFile(broken, map[string]string{}, map[string]bool{}); err == nil {
+		t.Error("including a missing directory succeeded")
+	}
+}
+
+// newTestCommand returns a command with the connection flags parsed from
+// args, resetting the flags of earlier tests and the environment
+func newTestCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	flags := rootCmd.PersistentFlags()
+	flags.VisitAll(func(f *pflag.Flag) {
+		f.Value.Set(f.DefValue)
+		f.Changed = false
+	})
+	for _, name := range []string{"MYSQL_HOST", "MYSQL_TCP_PORT", "MYSQL_UNIX_PORT", "MYSQL_PWD"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	cmd := &cobra.Command{}
+	cmd.Flags().AddFlagSet(flags)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestResolveConfigPrecedence(t *testing.T) {
+	optionFile := writeFile(t, t.TempDir(), "my.cnf",
+		"[client]\nhost = opthost\nport = 3310\nuser = optuser\npassword = optpass\ndatabase = optdb\n")
+	dsn := "dsnuser:dsnpass@tcp(dsnhost:3320)/dsndb"
+	flags := []string{"--host", "flaghost", "--port", "3330", "--user", "flaguser", "--password", "flagpass", "--database", "flagdb"}
+
+	type settings struct {
+		host, user, password, database string
+		port                           int
+	}
+	tests := []struct {
+		name string
+		args []string
+		want settings
+	}{
+		{"option file", nil, settings{"opthost", "optuser", "optpass", "optdb", 3310}},
+		{"dsn over option file", []string{"--dsn", dsn}, settings{"dsnhost", "dsnuser", "dsnpass", "dsndb", 3320}},
+		{"flags over dsn", append([]string{"--dsn", dsn}, flags...), settings{"flaghost", "flaguser", "flagpass", "flagdb", 3330}},
+		{"single flag", []string{"--dsn", dsn, "--user", "flaguser"}, settings{"dsnhost", "flaguser", "dsnpass", "dsndb", 3320}},
+	}
+	for _, test := range tests {
+		cmd := newTestCommand(t, append([]string{"--defaults-file", optionFile}, test.args...)...)
+		config, err := resolveConfig(cmd, "fallback")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got := settings{config.Host, config.User, config.Password, config.Database, config.Port}
+		if got != test.want {
+			t.Errorf("%s: resolveConfig = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+
+	// The environment sits between the DSN and the option files
+	cmd := newTestCommand(t, "--defaults-file", optionFile)
+	t.Setenv("MYSQL_HOST", "envhost")
+	t.Setenv("MYSQL_PWD", "envpass")
+	config, err := resolveConfig(cmd, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "envhost" || config.Password != "envpass" || config.User != "optuser" {
+		t.Errorf("resolveConfig = %s:%s@%s, want optuser:envpass@envhost", config.User, config.Password, config.Host)
+	}
+
+	cmd = newTestCommand(t, "--defaults-file", optionFile, "--dsn", dsn)
+	t.Setenv("MYSQL_TCP_PORT", "not-a-port")
+	if config, err := resolveConfig(cmd, ""); err != nil || config.Port != 3320 {
+		t.Errorf("resolveConfig = %d, %v, want the port of the DSN", config.Port, err)
+	}
+	cmd = newTestCommand(t, "--defaults-file", optionFile)
+	t.Setenv("MYSQL_TCP_PORT", "not-a-port")
+	if _, err := resolveConfig(cmd, ""); err == nil {
+		t.Error("resolveConfig with an invalid port succeeded")
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/spf13/cobra"
+	"github.com/zhoucq/mysql-exporter/exporter"
+)
+
+var (
+	cfgTargetDSN    string
+	cfgTargetSchema string
+	cfgAlterFile    string
+)
+
+// diffCmd compares the schema of the source database with a target
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: msgs.CmdDiffShort,
+	Long:  msgs.CmdDiffLong,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (cfgTargetDSN == "") == (cfgTargetSchema == "") {
+			return fmt.Errorf(msgs.ErrDiffTarget)
+		}
+		if cfgTargetDSN != "" {
+			if err := checkTargetDSN(cfgTargetDSN); err != nil {
+				return err
+			}
+		}
+
+		config, err := resolveConfig(cmd, "")
+		if err != nil {
+			return err
+		}
+		source, err := exporter.NewContext(cmd.Context(), config)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		sourceSchema, err := source.LoadSchema()
+		if err != nil {
+			return err
+		}
+
+		var targetSchema *exporter.Schema
+		if cfgTargetSchema != "" {
+			targetSchema, err = exporter.ParseSchemaFile(cfgTargetSchema)
+		} else {
+			var target *exporter.Exporter
+			target, err = exporter.NewContext(cmd.Context(), exporter.Config{DSN: cfgTargetDSN})
+			if err == nil {
+				defer target.Close()
+				targetSchema, err = target.LoadSchema()
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		diff := exporter.DiffSchemas(sourceSchema, targetSchema)
+
+		// A script on standard output is meant to be piped, so it is printed alone
+		if cfgAlterFile == "-" {
+			fmt.Print(diff.AlterScript())
+			return nil
+		}
+		printSchemaDiff(diff)
+		if cfgAlterFile == "" {
+			return nil
+		}
+		if err := os.WriteFile(cfgAlterFile, []byte(diff.AlterScript()), 0644); err != nil {
+			return fmt.Errorf(msgs.ErrWriteAlterScript, err)
+		}
+		return nil
+	},
+}
+
+// checkTargetDSN rejects a target DSN that names no database. Every source
+// table would otherwise look missing from the target.
+func checkTargetDSN(dsn string) error {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrParseDSN, err)
+	}
+	if config.DBName == "" {
+		return fmt.Errorf(msgs.ErrTargetDSNNoDatabase)
+	}
+	return nil
+}
+
+// printSchemaDiff prints a readable summary of the schema differences
+func printSchemaDiff(diff *exporter.SchemaDiff) {
+	if diff.RoutinesNotCompared {
+		fmt.Println(msgs.DiffRoutinesNotCompared)
+	}
+	if diff.Empty() {
+		fmt.Println(msgs.DiffIdentical)
+		return
+	}
+
+	printNames := func(format, indent, entity string, names []string) {
+		for _, name := range names {
+			fmt.Printf(format+"\n", indent, entity, name)
+		}
+	}
+
+	printNames(msgs.DiffAdded, "", msgs.EntityTable, diff.AddedTables)
+	printNames(msgs.DiffRemoved, "", msgs.EntityTable, diff.RemovedTables)
+	for _, table := range diff.ChangedTables {
+		fmt.Printf(msgs.DiffChanged+"\n", "", msgs.EntityTable, table.Table)
+		printNames(msgs.DiffAdded, "    ", msgs.EntityColumn, table.AddedColumns)
+		printNames(msgs.DiffRemoved, "    ", msgs.EntityColumn, table.RemovedColumns)
+		printNames(msgs.DiffChanged, "    ", msgs.EntityColumn, table.ChangedColumns)
+		printNames(msgs.DiffAdded, "    ", msgs.EntityIndex, table.AddedIndexes)
+		printNames(msgs.DiffRemoved, "    ", msgs.EntityIndex, table.RemovedIndexes)
+		printNames(msgs.DiffChanged, "    ", msgs.EntityIndex, table.ChangedIndexes)
+		if len(table.SourceOptions) > 0 || len(table.TargetOptions) > 0 {
+			fmt.Printf(msgs.DiffOptions+"\n", strings.Join(table.SourceOptions, " "), strings.Join(table.TargetOptions, " "))
+		}
+	}
+	printNames(msgs.DiffAdded, "", msgs.EntityView, diff.AddedViews)
+	printNames(msgs.DiffRemoved, "", msgs.EntityView, diff.RemovedViews)
+	printNames(msgs.DiffChanged, "", msgs.EntityView, diff.ChangedViews)
+	printNames(msgs.DiffAdded, "", msgs.EntityRoutine, diff.AddedRoutines)
+	printNames(msgs.DiffRemoved, "", msgs.EntityRoutine, diff.RemovedRoutines)
+	printNames(msgs.DiffChanged, "", msgs.EntityRoutine, diff.ChangedRoutines)
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&cfgTargetDSN, "target-dsn", "", msgs.FlagDiffTargetDSN)
+	diffCmd.Flags().StringVar(&cfgTargetSchema, "target-schema", "", msgs.FlagTargetSchema)
+	diffCmd.Flags().StringVar(&cfgAlterFile, "alter-file", "", msgs.FlagAlterFile)
+	rootCmd.AddCommand(diffCmd)
+}
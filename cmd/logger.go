@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Formats of --log-format
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+var (
+	cfgLogFormat string
+	cfgQuiet     bool
+	cfgVerbose   bool
+)
+
+// logger writes the messages of the CLI to standard error, so that standard
+// output only carries results such as the diff script or the verify report
+var logger = slog.New(newTextHandler(os.Stderr, slog.LevelInfo))
+
+// setupLogger applies --log-format, --quiet and --verbose
+func setupLogger() error {
+	level := slog.LevelInfo
+	if cfgQuiet {
+		level = slog.LevelWarn
+	} else if cfgVerbose {
+		level = slog.LevelDebug
+	}
+
+	switch cfgLogFormat {
+	case logFormatText:
+		logger = slog.New(newTextHandler(os.Stderr, level))
+	case logFormatJSON:
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	default:
+		return fmt.Errorf(msgs.ErrInvalidLogFormat, cfgLogFormat)
+	}
+	return nil
+}
+
+// textHandler writes one plain line per record. Warnings get the localized
+// warning prefix, and the attributes are only written for debug records,
+// since the messages of the other levels already contain them.
+type textHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Leveler
+	attrs []slog.Attr
+}
+
+func newTextHandler(w io.Writer, level slog.Leveler) *textHandler {
+	return &textHandler{w: w, mu: &sync.Mutex{}, level: level}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString(r.Message)
+	case r.Level >= slog.LevelWarn:
+		fmt.Fprintf(&b, msgs.Warning, r.Message)
+	default:
+		b.WriteString(r.Message)
+	}
+	if r.Level < slog.LevelInfo {
+		write := func(a slog.Attr) bool {
+			fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+			return true
+		}
+		for _, a := range h.attrs {
+			write(a)
+		}
+		r.Attrs(write)
+	}
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+// WithGroup is not needed for plain lines, the attributes keep their keys
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/zhoucq/mysql-exporter/exporter"
+)
+
+func TestTextHandler(t *testing.T) {
+	var b bytes.Buffer
+	l := slog.New(newTextHandler(&b, slog.LevelDebug)).With(slog.String("operation", "export"))
+	l.Info("exporting orders", slog.String("table", "orders"))
+	l.Warn("view failed", slog.String("table", "recent"))
+	l.Debug("query", slog.String("sql", "SELECT 1"))
+	l.Error("export failed")
+
+	// Only debug lines carry their attributes, the messages of the other
+	// levels already contain them
+	want := "exporting orders\n" +
+		fmt.Sprintf(msgs.Warning, "view failed") + "\n" +
+		"query operation=export sql=SELECT 1\n" +
+		"export failed\n"
+	if b.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestSetupLogger(t *testing.T) {
+	defer func(format string, quiet, verbose bool, l *slog.Logger) {
+		cfgLogFormat, cfgQuiet, cfgVerbose, logger = format, quiet, verbose, l
+	}(cfgLogFormat, cfgQuiet, cfgVerbose, logger)
+
+	tests := []struct {
+		format         string
+		quiet, verbose bool
+		// lowest is the lowest level logged
+		lowest slog.Level
+	}{
+		{logFormatText, false, false, slog.LevelInfo},
+		{logFormatText, true, false, slog.LevelWarn},
+		{logFormatText, false, true, slog.LevelDebug},
+		{logFormatJSON, false, false, slog.LevelInfo},
+		// --quiet wins over --verbose
+		{logFormatJSON, true, true, slog.LevelWarn},
+	}
+	for _, test := range tests {
+		cfgLogFormat, cfgQuiet, cfgVerbose = test.format, test.quiet, test.verbose
+		if err := setupLogger(); err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+		for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn} {
+			if got, want := logger.Enabled(context.Background(), level), level >= test.lowest; got != want {
+				t.Errorf("%s quiet %v verbose %v: %s enabled = %v, want %v", test.format, test.quiet, test.verbose, level, got, want)
+			}
+		}
+		_, isJSON := logger.Handler().(*slog.JSONHandler)
+		if isJSON != (test.format == logFormatJSON) {
+			t.Errorf("%s: handler %T", test.format, logger.Handler())
+		}
+	}
+
+	cfgLogFormat = "xml"
+	if err := setupLogger(); err == nil {
+		t.Error("setupLogger with the format xml succeeded")
+	}
+}
+
+func TestConsoleObserverJSON(t *testing.T) {
+	defer func(l *slog.Logger) { logger = l }(logger)
+	var b bytes.Buffer
+	logger = slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	events := []exporter.Event{
+		{Type: exporter.EventStarted, Operation: exporter.OperationExport, Database: "shop"},
+		{Type: exporter.EventTableStarted, Operation: exporter.OperationExport, Table: "orders"},
+		// Row progress is only logged with --verbose
+		{Type: exporter.EventRowsWritten, Operation: exporter.OperationExport, Table: "orders", Rows: 1000},
+		{Type: exporter.EventWarning, Operation: exporter.OperationExport, Table: "recent", Message: "view failed"},
+		{Type: exporter.EventTableFinished, Operation: exporter.OperationExport, Table: "orders", Rows: 1500},
+		{Type: exporter.EventFileFinalized, Operation: exporter.OperationExport, Path: "/out/data.sql"},
+		{Type: exporter.EventFinished, Operation: exporter.OperationExport},
+	}
+	for _, event := range events {
+		consoleObserver{}.Observe(event)
+	}
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	want := []struct {
+		level string
+		attr  string
+		value interface{}
+	}{
+		{"INFO", "database", "shop"},
+		{"INFO", "table", "orders"},
+		{"WARN", "table", "recent"},
+		{"INFO", "rows", float64(1500)},
+		{"INFO", "path", "/out/data.sql"},
+		{"INFO", "operation", "export"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("records = %v, want %d", records, len(want))
+	}
+	for i, record := range records {
+		if record["level"] != want[i].level || record[want[i].attr] != want[i].value {
+			t.Errorf("record %d = %v, want level %s and %s=%v", i, record, want[i].level, want[i].attr, want[i].value)
+		}
+	}
+	if records[2]["msg"] != "view failed" {
+		t.Errorf("warning = %v, want the message without the text prefix", records[2])
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/zhoucq/mysql-exporter/exporter"
+)
+
+// consoleObserver logs the progress of a run
+type consoleObserver struct{}
+
+func (consoleObserver) Observe(event exporter.Event) {
+	attrs := []any{slog.String("operation", event.Operation)}
+	if event.Table != "" {
+		attrs = append(attrs, slog.String("table", event.Table))
+	}
+
+	var message string
+	clone := event.Operation == exporter.OperationClone
+	switch event.Type {
+	case exporter.EventStarted:
+		switch event.Operation {
+		case exporter.OperationExport:
+			message = fmt.Sprintf(msgs.ExportStart, event.Database)
+		case exporter.OperationClone:
+			message = fmt.Sprintf(msgs.CloneStart, event.Database)
+		}
+		attrs = append(attrs, slog.String("database", event.Database))
+
+	case exporter.EventTablesFound:
+		message = fmt.Sprintf(msgs.ExportFoundTables, event.Tables)
+		attrs = append(attrs, slog.Int("tables", event.Tables))
+
+	case exporter.EventTableStarted:
+		switch event.Operation {
+		case exporter.OperationExport:
+			message = fmt.Sprintf(msgs.ExportTableStart, event.Table)
+		case exporter.OperationClone:
+			message = fmt.Sprintf(msgs.CloneTableStart, event.Table)
+		case exporter.OperationVerify:
+			message = fmt.Sprintf(msgs.VerifyTableStart, event.Table)
+		}
+
+	case exporter.EventRowsWritten:
+		logger.Debug(fmt.Sprintf(msgs.ProgressRows, event.Rows, event.Table), append(attrs, slog.Int64("rows", event.Rows))...)
+		return
+
+	case exporter.EventTableFinished:
+		attrs = append(attrs, slog.Int64("rows", event.Rows), slog.Bool("view", event.IsView))
+		switch {
+		case event.Skipped:
+			message = fmt.Sprintf(msgs.CloneTableSkipped, event.Table)
+			attrs = append(attrs, slog.Bool("skipped", true))
+		case clone && !event.IsView:
+			message = fmt.Sprintf(msgs.CloneTableRows, event.Rows, event.Table)
+		case event.Operation == exporter.OperationExport:
+			entityType := msgs.EntityTable
+			if event.IsView {
+				entityType = msgs.EntityView
+			}
+			message = fmt.Sprintf(msgs.ExportTableRows, event.Rows, entityType, event.Table)
+		}
+
+	case exporter.EventWarning:
+		if event.Path != "" {
+			attrs = append(attrs, slog.String("path", event.Path))
+		}
+		logger.Warn(event.Message, attrs...)
+		return
+
+	case exporter.EventFileFinalized:
+		message = fmt.Sprintf(msgs.ExportFileWritten, event.Path)
+		attrs = append(attrs, slog.String("path", event.Path))
+
+	case exporter.EventFinished:
+		switch event.Operation {
+		case exporter.OperationExport:
+			message = msgs.ExportComplete
+		case exporter.OperationClone:
+			message = msgs.CloneComplete
+		}
+	}
+
+	if message != "" {
+		logger.Info(message, attrs...)
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zhoucq/mysql-exporter/exporter"
+	"golang.org/x/term"
+)
+
+const (
+	// progressRedraw is the shortest time between two redraws of the live display
+	progressRedraw = 200 * time.Millisecond
+	// progressLogInterval is the time between progress log lines without a terminal
+	progressLogInterval = 10 * time.Second
+	// progressBarWidth is the number of cells of the overall bar
+	progressBarWidth = 24
+	// progressRows is the number of rows between progress events
+	progressRows = 1000
+)
+
+// progressObserver tracks the rows of a run against the estimates and shows
+// them as a live line on a terminal, or as a log line every few seconds
+// otherwise. The events are passed on to next.
+type progressObserver struct {
+	next exporter.Observer
+	// live draws on w, a terminal of width columns, instead of logging
+	live  bool
+	w     io.Writer
+	width int
+
+	started  time.Time
+	lastShow time.Time
+	shown    bool
+
+	totalRows  int64
+	totalBytes int64
+	// doneRows and doneBytes belong to the finished tables
+	doneRows  int64
+	doneBytes int64
+
+	table         string
+	tableRows     int64
+	tableBytes    int64
+	tableEstimate int64
+}
+
+// newProgressObserver draws on standard error when it is a terminal and the
+// plain text log shows progress, otherwise progress is logged
+func newProgressObserver(next exporter.Observer) *progressObserver {
+	live := term.IsTerminal(int(os.Stderr.Fd())) && cfgLogFormat == logFormatText && !cfgQuiet && !cfgVerbose
+	width, _, err := term.GetSize(int(os.Stderr.Fd()))
+	if err != nil {
+		width = 0
+	}
+	progress = &progressObserver{next: next, live: live, w: os.Stderr, width: width, started: time.Now()}
+	return progress
+}
+
+// progress is the display of the running command, its live line is removed
+// before the error of a failed run is logged
+var progress *progressObserver
+
+func (p *progressObserver) Observe(event exporter.Event) {
+	switch event.Type {
+	case exporter.EventTablesFound:
+		p.totalRows, p.totalBytes = event.EstimatedRows, event.EstimatedBytes
+	case exporter.EventTableStarted:
+		p.table, p.tableRows, p.tableBytes, p.tableEstimate = event.Table, 0, 0, event.EstimatedRows
+	case exporter.EventRowsWritten:
+		p.tableRows, p.tableBytes = event.Rows, event.Bytes
+		p.show(false)
+		return
+	case exporter.EventTableFinished:
+		p.doneRows += event.Rows
+		p.doneBytes += event.Bytes
+		p.table, p.tableRows, p.tableBytes, p.tableEstimate = "", 0, 0, 0
+	}
+
+	// Other output goes above the live line
+	p.clear()
+	p.next.Observe(event)
+	if event.Type != exporter.EventFinished {
+		p.show(true)
+	}
+}
+
+// show updates the live line, or logs the progress when it is due
+func (p *progressObserver) show(force bool) {
+	now := time.Now()
+	if p.live {
+		if !force && now.Sub(p.lastShow) < progressRedraw {
+			return
+		}
+		p.lastShow = now
+		// A line wider than the terminal would wrap and could not be redrawn
+		line := []rune(p.line(now))
+		if p.width > 1 && len(line) >= p.width {
+			line = line[:p.width-1]
+		}
+		fmt.Fprint(p.w, "\r\033[K"+string(line))
+		p.shown = true
+		return
+	}
+	if p.lastShow.IsZero() {
+		p.lastShow = p.started
+	}
+	if now.Sub(p.lastShow) >= progressLogInterval {
+		p.lastShow = now
+		logger.Info(p.line(now), slog.Int64("rows", p.doneRows+p.tableRows),
+			slog.Int64("estimated_rows", p.totalRows), slog.Int64("bytes", p.doneBytes+p.tableBytes))
+	}
+}
+
+// clear removes the live line
+func (p *progressObserver) clear() {
+	if p != nil && p.shown {
+		fmt.Fprint(p.w, "\r\033[K")
+		p.shown = false
+	}
+}
+
+// line renders the overall and table progress with rate and ETA
+func (p *progressObserver) line(now time.Time) string {
+	rows := p.doneRows + p.tableRows
+	elapsed := now.Sub(p.started).Seconds()
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(rows) / elapsed
+	}
+
+	var b strings.Builder
+	if p.live {
+		filled := int(fraction(rows, p.totalRows) * progressBarWidth)
+		b.WriteString("[" + strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled) + "] ")
+	}
+	fmt.Fprintf(&b, msgs.ProgressOverall, 100*fraction(rows, p.totalRows), rows, p.totalRows,
+		rate, formatBytes(p.doneBytes+p.tableBytes))
+	if rate > 0 && p.totalRows > rows {
+		eta := time.Duration(float64(p.totalRows-rows) / rate * float64(time.Second))
+		fmt.Fprintf(&b, msgs.ProgressETA, eta.Round(time.Second))
+	}
+	if p.table != "" {
+		fmt.Fprintf(&b, msgs.ProgressTable, p.table, 100*fraction(p.tableRows, p.tableEstimate))
+	}
+	return b.String()
+}
+
+// fraction returns done/total between 0 and 1. Estimates may be exceeded,
+// the fraction then stays just below complete.
+func fraction(done, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	f := float64(done) / float64(total)
+	if f > 0.99 {
+		f = 0.99
+	}
+	return f
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhoucq/mysql-exporter/exporter"
+)
+
+func TestFraction(t *testing.T) {
+	tests := []struct {
+		done, total int64
+		want        float64
+	}{
+		{0, 0, 0},
+		{5, 0, 0},
+		{25, 100, 0.25},
+		// Exceeded estimates stay just below complete
+		{100, 100, 0.99},
+		{300, 100, 0.99},
+	}
+	for _, test := range tests {
+		if got := fraction(test.done, test.total); got != test.want {
+			t.Errorf("fraction(%d, %d) = %v, want %v", test.done, test.total, got, test.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, test := range tests {
+		if got := formatBytes(test.n); got != test.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", test.n, got, test.want)
+		}
+	}
+}
+
+// eventTypes is an Observer keeping the types of the events passed on
+type eventTypes []exporter.EventType
+
+func (e *eventTypes) Observe(event exporter.Event) { *e = append(*e, event.Type) }
+
+func TestProgressObserver(t *testing.T) {
+	var next eventTypes
+	var b bytes.Buffer
+	started := time.Now().Add(-10 * time.Second)
+	p := &progressObserver{next: &next, live: true, w: &b, started: started}
+
+	p.Observe(exporter.Event{Type: exporter.EventTablesFound, EstimatedRows: 1000, EstimatedBytes: 1 << 20})
+	p.Observe(exporter.Event{Type: exporter.EventTableStarted, Table: "orders", EstimatedRows: 400})
+	p.Observe(exporter.Event{Type: exporter.EventRowsWritten, Table: "orders", Rows: 200, Bytes: 2048})
+	p.Observe(exporter.Event{Type: exporter.EventTableFinished, Table: "orders", Rows: 400, Bytes: 4096})
+	p.Observe(exporter.Event{Type: exporter.EventTableStarted, Table: "users", EstimatedRows: 600})
+	p.Observe(exporter.Event{Type: exporter.EventRowsWritten, Table: "users", Rows: 100, Bytes: 1024})
+
+	// Row progress only updates the display
+	want := eventTypes{exporter.EventTablesFound, exporter.EventTableStarted, exporter.EventTableFinished, exporter.EventTableStarted}
+	if fmt.Sprint(next) != fmt.Sprint(want) {
+		t.Errorf("events passed on = %v, want %v", next, want)
+	}
+	if p.doneRows != 400 || p.doneBytes != 4096 || p.tableRows != 100 || p.table != "users" {
+		t.Errorf("progress = %d rows %d bytes done, %d rows of %s, want 400, 4096, 100 of users", p.doneRows, p.doneBytes, p.tableRows, p.table)
+	}
+
+	// 500 of 1000 rows in 10 seconds leave 10 seconds
+	line := p.line(started.Add(10 * time.Second))
+	wantLine := "[" + strings.Repeat("#", 12) + strings.Repeat("-", 12) + "] " +
+		fmt.Sprintf(msgs.ProgressOverall, 50.0, 500, 1000, 50.0, "5.0 KiB") +
+		fmt.Sprintf(msgs.ProgressETA, 10*time.Second) +
+		fmt.Sprintf(msgs.ProgressTable, "users", 100.0/6)
+	if line != wantLine {
+		t.Errorf("line = %q, want %q", line, wantLine)
+	}
+	if !p.shown || !strings.HasPrefix(b.String(), "\r\033[K") {
+		t.Errorf("display = %q, want a redrawn line", b.String())
+	}
+
+	// Other output clears the line, the finished run leaves it cleared
+	b.Reset()
+	p.Observe(exporter.Event{Type: exporter.EventTableFinished, Table: "users", Rows: 600})
+	p.Observe(exporter.Event{Type: exporter.EventFinished})
+	if p.shown || !strings.HasSuffix(b.String(), "\r\033[K") {
+		t.Errorf("display after finishing = %q, want a cleared line", b.String())
+	}
+}
+
+func TestProgressObserverWidth(t *testing.T) {
+	var next eventTypes
+	var b bytes.Buffer
+	p := &progressObserver{next: &next, live: true, w: &b, width: 20, started: time.Now()}
+	p.Observe(exporter.Event{Type: exporter.EventTableStarted, Table: "a_table_with_a_long_name"})
+
+	// A line as wide as the terminal would wrap
+	drawn := strings.TrimPrefix(b.String(), "\r\033[K")
+	if n := len([]rune(drawn)); n != 19 {
+		t.Errorf("line %q has %d columns, want 19", drawn, n)
+	}
+}
+
+func TestProgressObserverLogged(t *testing.T) {
+	var next eventTypes
+	var b bytes.Buffer
+	p := &progressObserver{next: &next, w: &b, started: time.Now()}
+	p.Observe(exporter.Event{Type: exporter.EventTableStarted, Table: "orders"})
+	p.Observe(exporter.Event{Type: exporter.EventRowsWritten, Table: "orders", Rows: 100})
+
+	// Without a terminal nothing is drawn and the line has no bar
+	if b.Len() > 0 || p.shown {
+		t.Errorf("display = %q, want nothing drawn", b.String())
+	}
+	if line := p.line(time.Now()); strings.HasPrefix(line, "[") {
+		t.Errorf("logged line = %q, want no bar", line)
+	}
+}
@@ -1,14 +1,16 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"os"
+	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/zhoucq/mysql-exporter/exporter"
 	"github.com/zhoucq/mysql-exporter/i18n"
-	"golang.org/x/term"
 )
 
 var (
@@ -20,6 +22,52 @@ var (
 	cfgRows     int
 	cfgOutput   string
 	cfgCompress bool
+
+	cfgSingleTransaction  bool
+	cfgSourceData         int
+	cfgDialect            string
+	cfgMaxStatementBytes  int
+	cfgRowsPerStatement   int
+	cfgExtendedInsert     bool
+	cfgInsertMode         string
+	cfgUpsertRowAlias     bool
+	cfgSample             string
+	cfgSampleColumn       string
+	cfgSamplePercent      float64
+	cfgNoData             bool
+	cfgDefiner            string
+	cfgOverwrite          bool
+	cfgKeepPartial        bool
+	cfgAutoIncrement      string
+	cfgSQLSecurityInvoker bool
+	cfgNoCreateInfo       bool
+	cfgNoRowDigests       bool
+	cfgNoDrop             bool
+	cfgIfNotExists        bool
+
+	cfgMaxRowsPerSecond  int
+	cfgMaxBytesPerSecond int64
+	cfgMaxLoad           string
+	cfgCheckReplicaDSN   string
+	cfgMaxLag            time.Duration
+	cfgMaxReplicaLag     time.Duration
+	cfgReplicaLagTimeout time.Duration
+	cfgStopSQLThread     bool
+
+	cfgDSN          string
+	cfgDefaultsFile string
+	cfgPasswordFile string
+
+	cfgSocket                string
+	cfgSSLMode               string
+	cfgSSLCA                 string
+	cfgEnableCleartextPlugin bool
+	cfgServerPublicKeyPath   string
+
+	cfgConnectTimeout time.Duration
+	cfgReadTimeout    time.Duration
+	cfgWriteTimeout   time.Duration
+	cfgRetries        int
 )
 
 // Get the messages for the current language
@@ -30,59 +78,133 @@ var rootCmd = &cobra.Command{
 	Use:   "mysql-exporter",
 	Short: msgs.CmdShort,
 	Long:  msgs.CmdLong,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setupLogger()
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// If the password is empty, prompt the user to enter a password
-		if cfgPassword == "" {
-			fmt.Print(msgs.PromptPassword)
-			passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
-			if err != nil {
-				return fmt.Errorf(msgs.ErrReadPassword, err)
-			}
-			fmt.Println() // 添加换行符，因为ReadPassword不会自动添加
-			cfgPassword = string(passwordBytes)
-		}
-
-		config := exporter.Config{
-			Host:     cfgHost,
-			Port:     cfgPort,
-			User:     cfgUser,
-			Password: cfgPassword,
-			Database: cfgDatabase,
-			MaxRows:  cfgRows,
-			Output:   cfgOutput,
-			Compress: cfgCompress,
+		config, err := resolveConfig(cmd, "")
+		if err != nil {
+			return err
 		}
+		config.Observer = newProgressObserver(consoleObserver{})
+		config.ProgressRows = progressRows
+		config.Logger = logger
+		config.MaxRows = cfgRows
+		config.Output = cfgOutput
+		config.Overwrite = cfgOverwrite
+		config.KeepPartial = cfgKeepPartial
+		config.Compress = cfgCompress
+		config.SingleTransaction = cfgSingleTransaction
+		config.SourceData = cfgSourceData
+		config.Dialect = cfgDialect
+		config.MaxStatementBytes = cfgMaxStatementBytes
+		config.RowsPerStatement = cfgRowsPerStatement
+		config.SingleRowInserts = !cfgExtendedInsert
+		config.InsertMode = cfgInsertMode
+		config.UpsertRowAlias = cfgUpsertRowAlias
+		config.Sample = cfgSample
+		config.SampleColumn = cfgSampleColumn
+		config.SamplePercent = cfgSamplePercent
+		config.NoData = cfgNoData
+		config.Definer = cfgDefiner
+		config.AutoIncrement = cfgAutoIncrement
+		config.SQLSecurityInvoker = cfgSQLSecurityInvoker
+		config.NoCreateInfo = cfgNoCreateInfo
+		config.NoRowDigests = cfgNoRowDigests
+		config.NoDrop = cfgNoDrop
+		config.IfNotExists = cfgIfNotExists
+		config.MaxRowsPerSecond = cfgMaxRowsPerSecond
+		config.MaxBytesPerSecond = cfgMaxBytesPerSecond
+		config.MaxLoad = cfgMaxLoad
+		config.CheckReplicaDSN = cfgCheckReplicaDSN
+		config.MaxLag = cfgMaxLag
+		config.MaxReplicaLag = cfgMaxReplicaLag
+		config.ReplicaLagTimeout = cfgReplicaLagTimeout
+		config.StopSQLThread = cfgStopSQLThread
 
-		exp, err := exporter.New(config)
+		exp, err := exporter.NewContext(cmd.Context(), config)
 		if err != nil {
 			return err
 		}
+		defer exp.Close()
 
-		return exp.Execute()
+		return exp.ExecuteContext(cmd.Context())
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	// SIGINT and SIGTERM cancel the running command, which stops its queries
+	// and closes its files before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		progress.clear()
+		logger.Error(err.Error())
+		var canceled *exporter.CanceledError
+		if errors.As(err, &canceled) {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
-	rootCmd.Flags().StringVar(&cfgHost, "host", "localhost", msgs.FlagHost)
-	rootCmd.Flags().IntVar(&cfgPort, "port", 3306, msgs.FlagPort)
-	rootCmd.Flags().StringVar(&cfgUser, "user", "root", msgs.FlagUser)
-	rootCmd.Flags().StringVar(&cfgPassword, "password", "", msgs.FlagPassword)
-	rootCmd.Flags().StringVar(&cfgDatabase, "database", "", msgs.FlagDatabase)
+	// Connection flags are shared by all subcommands
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&cfgLogFormat, "log-format", logFormatText, msgs.FlagLogFormat)
+	flags.BoolVar(&cfgQuiet, "quiet", false, msgs.FlagQuiet)
+	flags.BoolVar(&cfgVerbose, "verbose", false, msgs.FlagVerbose)
+	flags.StringVar(&cfgHost, "host", "localhost", msgs.FlagHost)
+	flags.IntVar(&cfgPort, "port", 3306, msgs.FlagPort)
+	flags.StringVar(&cfgUser, "user", "root", msgs.FlagUser)
+	flags.StringVar(&cfgPassword, "password", "", msgs.FlagPassword)
+	flags.StringVar(&cfgDatabase, "database", "", msgs.FlagDatabase)
+	flags.StringVar(&cfgDSN, "dsn", "", msgs.FlagDSN)
+	flags.StringVar(&cfgDefaultsFile, "defaults-file", "", msgs.FlagDefaultsFile)
+	flags.StringVar(&cfgPasswordFile, "password-file", "", msgs.FlagPasswordFile)
+	flags.StringVar(&cfgSocket, "socket", "", msgs.FlagSocket)
+	flags.StringVar(&cfgSSLMode, "ssl-mode", "", msgs.FlagSSLMode)
+	flags.StringVar(&cfgSSLCA, "ssl-ca", "", msgs.FlagSSLCA)
+	flags.BoolVar(&cfgEnableCleartextPlugin, "enable-cleartext-plugin", false, msgs.FlagEnableCleartextPlugin)
+	flags.StringVar(&cfgServerPublicKeyPath, "server-public-key-path", "", msgs.FlagServerPublicKeyPath)
+	flags.DurationVar(&cfgConnectTimeout, "connect-timeout", 10*time.Second, msgs.FlagConnectTimeout)
+	flags.DurationVar(&cfgReadTimeout, "read-timeout", 0, msgs.FlagReadTimeout)
+	flags.DurationVar(&cfgWriteTimeout, "write-timeout", 0, msgs.FlagWriteTimeout)
+	flags.IntVar(&cfgRetries, "retries", 3, msgs.FlagRetries)
+
 	rootCmd.Flags().IntVar(&cfgRows, "rows", 1000, msgs.FlagRows)
 	rootCmd.Flags().StringVar(&cfgOutput, "output", "./output", msgs.FlagOutput)
+	rootCmd.Flags().BoolVar(&cfgOverwrite, "overwrite", false, msgs.FlagOverwrite)
+	rootCmd.Flags().BoolVar(&cfgKeepPartial, "keep-partial", false, msgs.FlagKeepPartial)
 	rootCmd.Flags().BoolVar(&cfgCompress, "compress", true, msgs.FlagCompress)
-
-	if err := rootCmd.MarkFlagRequired("database"); err != nil {
-		fmt.Printf(msgs.ErrMarkRequiredFlag, err)
-		os.Exit(1)
-	}
+	rootCmd.Flags().BoolVar(&cfgSingleTransaction, "single-transaction", false, msgs.FlagSingleTransaction)
+	rootCmd.Flags().IntVar(&cfgSourceData, "source-data", 0, msgs.FlagSourceData)
+	rootCmd.Flags().IntVar(&cfgMaxStatementBytes, "max-statement-bytes", 0, msgs.FlagMaxStatementBytes)
+	rootCmd.Flags().IntVar(&cfgRowsPerStatement, "rows-per-statement", 1000, msgs.FlagRowsPerStatement)
+	rootCmd.Flags().BoolVar(&cfgExtendedInsert, "extended-insert", true, msgs.FlagExtendedInsert)
+	rootCmd.Flags().StringVar(&cfgInsertMode, "insert-mode", exporter.InsertModeInsert, msgs.FlagInsertMode)
+	rootCmd.Flags().BoolVar(&cfgUpsertRowAlias, "upsert-row-alias", false, msgs.FlagUpsertRowAlias)
+	rootCmd.Flags().StringVar(&cfgSample, "sample", exporter.SampleFirst, msgs.FlagSample)
+	rootCmd.Flags().StringVar(&cfgSampleColumn, "sample-column", "", msgs.FlagSampleColumn)
+	rootCmd.Flags().Float64Var(&cfgSamplePercent, "sample-percent", 0, msgs.FlagSamplePercent)
+	rootCmd.Flags().BoolVar(&cfgNoData, "no-data", false, msgs.FlagNoData)
+	rootCmd.Flags().BoolVar(&cfgNoCreateInfo, "no-create-info", false, msgs.FlagNoCreateInfo)
+	rootCmd.Flags().BoolVar(&cfgNoRowDigests, "no-row-digests", false, msgs.FlagNoRowDigests)
+	rootCmd.Flags().BoolVar(&cfgNoDrop, "no-drop", false, msgs.FlagNoDrop)
+	rootCmd.Flags().BoolVar(&cfgIfNotExists, "if-not-exists", false, msgs.FlagIfNotExists)
+	rootCmd.Flags().StringVar(&cfgAutoIncrement, "auto-increment", exporter.AutoIncrementReset, msgs.FlagAutoIncrement)
+	rootCmd.Flags().StringVar(&cfgDefiner, "definer", exporter.DefinerKeep, msgs.FlagDefiner)
+	rootCmd.Flags().BoolVar(&cfgSQLSecurityInvoker, "sql-security-invoker", false, msgs.FlagSQLSecurityInvoker)
+	rootCmd.Flags().StringVar(&cfgDialect, "dialect", exporter.DialectMySQL, msgs.FlagDialect)
+	rootCmd.Flags().IntVar(&cfgMaxRowsPerSecond, "max-rows-per-second", 0, msgs.FlagMaxRowsPerSecond)
+	rootCmd.Flags().Int64Var(&cfgMaxBytesPerSecond, "max-bytes-per-second", 0, msgs.FlagMaxBytesPerSecond)
+	rootCmd.Flags().StringVar(&cfgMaxLoad, "max-load", "", msgs.FlagMaxLoad)
+	rootCmd.Flags().StringVar(&cfgCheckReplicaDSN, "check-replica-dsn", "", msgs.FlagCheckReplicaDSN)
+	rootCmd.Flags().DurationVar(&cfgMaxLag, "max-lag", time.Second, msgs.FlagMaxLag)
+	rootCmd.Flags().DurationVar(&cfgMaxReplicaLag, "max-replica-lag", 0, msgs.FlagMaxReplicaLag)
+	rootCmd.Flags().DurationVar(&cfgReplicaLagTimeout, "replica-lag-timeout", 10*time.Minute, msgs.FlagReplicaLagTimeout)
+	rootCmd.Flags().BoolVar(&cfgStopSQLThread, "stop-sql-thread", false, msgs.FlagStopSQLThread)
 }
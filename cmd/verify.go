@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zhoucq/mysql-exporter/exporter"
+)
+
+var cfgManifest string
+
+// maxReportedRows limits the number of row keys printed per table
+const maxReportedRows = 10
+
+// verifyCmd compares a restored database with an export
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: msgs.CmdVerifyShort,
+	Long:  msgs.CmdVerifyLong,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifest, err := exporter.ReadManifest(cfgManifest)
+		if err != nil {
+			return err
+		}
+
+		// Default to the exported database name when the target is not named
+		config, err := resolveConfig(cmd, manifest.Database)
+		if err != nil {
+			return err
+		}
+
+		config.Observer = consoleObserver{}
+		config.Logger = logger
+		exp, err := exporter.NewContext(cmd.Context(), config)
+		if err != nil {
+			return err
+		}
+		defer exp.Close()
+
+		report, err := exp.VerifyContext(cmd.Context(), cfgManifest)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, table := range report.Tables {
+			switch {
+			case table.OK():
+				fmt.Printf(msgs.VerifyTableOK+"\n", table.Table, table.ActualRows)
+			case table.Missing:
+				failed++
+				fmt.Printf(msgs.VerifyTableMissing+"\n", table.Table)
+			default:
+				failed++
+				fmt.Printf(msgs.VerifyTableMismatch+"\n", table.Table,
+					table.ExpectedRows, table.ExpectedChecksum, table.ActualRows, table.ActualChecksum)
+				printRowKeys(msgs.VerifyMissingRows, table.MissingRows)
+				printRowKeys(msgs.VerifyChangedRows, table.ChangedRows)
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf(msgs.ErrVerifyFailed, failed, len(report.Tables))
+		}
+		fmt.Printf(msgs.VerifyComplete+"\n", len(report.Tables))
+		return nil
+	},
+}
+
+// printRowKeys prints at most maxReportedRows row keys
+func printRowKeys(format string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	shown := keys
+	if len(shown) > maxReportedRows {
+		shown = shown[:maxReportedRows]
+	}
+	list := strings.Join(shown, ", ")
+	if len(keys) > len(shown) {
+		list += fmt.Sprintf(" ... +%d", len(keys)-len(shown))
+	}
+	fmt.Printf(format+"\n", list)
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&cfgManifest, "manifest", "./output/manifest.json", msgs.FlagManifest)
+	rootCmd.AddCommand(verifyCmd)
+}
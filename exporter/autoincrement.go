@@ -0,0 +1,127 @@
+package exporter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// How the AUTO_INCREMENT counter of exported tables is written
+const (
+	// AutoIncrementKeep keeps the counter of the source table
+	AutoIncrementKeep = "keep"
+	// AutoIncrementReset starts the counter at 1
+	AutoIncrementReset = "reset"
+	// AutoIncrementStrip removes the table option, the target picks the counter
+	AutoIncrementStrip = "strip"
+	// AutoIncrementMaxExported sets the counter just above the highest exported ID
+	AutoIncrementMaxExported = "max-exported"
+)
+
+// validAutoIncrement reports whether mode is one of the auto-increment modes
+func validAutoIncrement(mode string) bool {
+	switch mode {
+	case AutoIncrementKeep, AutoIncrementReset, AutoIncrementStrip, AutoIncrementMaxExported:
+		return true
+	}
+	return false
+}
+
+// rewriteAutoIncrement changes the AUTO_INCREMENT table option of a CREATE
+// TABLE statement. Only the options after the column list are looked at, so
+// column definitions, comments and defaults are never touched. The counter
+// of AutoIncrementMaxExported is set with the data, the statement starts it at 1.
+func rewriteAutoIncrement(create, mode string) string {
+	if mode == AutoIncrementKeep {
+		return create
+	}
+
+	tokens := tokenize(create)
+	options := len(tokens)
+	depth := 0
+	for i, t := range tokens {
+		if t.is("(") {
+			depth++
+		} else if t.is(")") {
+			depth--
+			if depth == 0 {
+				options = i + 1
+				break
+			}
+		}
+	}
+
+	for i := options; i < len(tokens); i++ {
+		if !tokens[i].is("AUTO_INCREMENT") {
+			continue
+		}
+		eq := nextSignificant(tokens, i+1)
+		value := nextSignificant(tokens, eq+1)
+		if value >= len(tokens) || !tokens[eq].is("=") {
+			continue
+		}
+		if mode == AutoIncrementStrip {
+			start := i
+			if start > 0 && tokens[start-1].kind == tokenSpace {
+				start--
+			}
+			return joinTokens(tokens[:start]) + joinTokens(tokens[value+1:])
+		}
+		tokens[value].text = "1"
+		return joinTokens(tokens)
+	}
+	return create
+}
+
+// maxValue tracks the highest value of an integer column
+type maxValue struct {
+	index int
+	max   uint64
+	found bool
+	// limit is the largest value the column type holds
+	limit uint64
+}
+
+// exhausted reports whether the highest value is the largest the column
+// holds, so that no counter is left above it
+func (m *maxValue) exhausted() bool {
+	return m.max >= m.limit
+}
+
+// integerLimit returns the largest value of an integer column, types that
+// are not known take the full uint64 range
+func integerLimit(column ColumnInfo) uint64 {
+	unsigned := strings.Contains(strings.ToLower(column.ColumnType), "unsigned")
+	bits := 64
+	switch strings.ToLower(column.DataType) {
+	case "tinyint":
+		bits = 8
+	case "smallint":
+		bits = 16
+	case "mediumint":
+		bits = 24
+	case "int", "integer":
+		bits = 32
+	case "bigint":
+	default:
+		return math.MaxUint64
+	}
+	if unsigned {
+		return math.MaxUint64 >> (64 - bits)
+	}
+	return math.MaxUint64 >> (65 - bits)
+}
+
+// add records the column's value of a row
+func (m *maxValue) add(raw [][]byte) {
+	if m == nil || raw[m.index] == nil {
+		return
+	}
+	n, err := strconv.ParseUint(string(raw[m.index]), 10, 64)
+	if err != nil {
+		return
+	}
+	if !m.found || n > m.max {
+		m.max, m.found = n, true
+	}
+}
@@ -0,0 +1,144 @@
+package exporter
+
+import (
+	"database/sql/driver"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteAutoIncrement(t *testing.T) {
+	spaced := "CREATE TABLE `t` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB AUTO_INCREMENT = 99 /* AUTO_INCREMENT=3 */ DEFAULT CHARSET=utf8mb4"
+	noCounter := "CREATE TABLE `t` (\n  `AUTO_INCREMENT` int DEFAULT '5'\n) ENGINE=InnoDB COMMENT='AUTO_INCREMENT=5'"
+	tests := []struct {
+		name   string
+		create string
+		mode   string
+		want   string
+	}{
+		{"keep", ordersTable, AutoIncrementKeep, ordersTable},
+		{"reset", ordersTable, AutoIncrementReset, strings.Replace(ordersTable, "AUTO_INCREMENT=1042", "AUTO_INCREMENT=1", 1)},
+		{"strip", ordersTable, AutoIncrementStrip, strings.Replace(ordersTable, " AUTO_INCREMENT=1042", "", 1)},
+		{"max exported", ordersTable, AutoIncrementMaxExported, strings.Replace(ordersTable, "AUTO_INCREMENT=1042", "AUTO_INCREMENT=1", 1)},
+		{"reset spaced", spaced, AutoIncrementReset, strings.Replace(spaced, "AUTO_INCREMENT = 99", "AUTO_INCREMENT = 1", 1)},
+		{"strip spaced", spaced, AutoIncrementStrip, strings.Replace(spaced, " AUTO_INCREMENT = 99", "", 1)},
+		{"no counter", noCounter, AutoIncrementReset, noCounter},
+		{"no counter strip", noCounter, AutoIncrementStrip, noCounter},
+	}
+	for _, test := range tests {
+		if got := rewriteAutoIncrement(test.create, test.mode); got != test.want {
+			t.Errorf("%s: rewriteAutoIncrement =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMaxValue(t *testing.T) {
+	m := &maxValue{index: 1}
+	for _, value := range []string{"7", "", "18446744073709551615", "12", "x"} {
+		raw := [][]byte{[]byte("a"), []byte(value)}
+		if value == "" {
+			raw[1] = nil
+		}
+		m.add(raw)
+	}
+	if !m.found || m.max != 18446744073709551615 {
+		t.Errorf("max = %d, found %v", m.max, m.found)
+	}
+
+	// A nil maxValue ignores rows, so callers need no check
+	var none *maxValue
+	none.add([][]byte{[]byte("1")})
+}
+
+func TestSetAutoIncrement(t *testing.T) {
+	tests := []struct {
+		dialect dialect
+		want    string
+	}{
+		{mysqlDialect{}, "ALTER TABLE `a``b` AUTO_INCREMENT=43;\n"},
+		{&standardDialect{postgres: true}, "SELECT setval(pg_get_serial_sequence('\"a`b\"', 'id'), 42);\n"},
+		{&standardDialect{}, ""},
+	}
+	for _, test := range tests {
+		if got := test.dialect.setAutoIncrement("a`b", "id", 42); got != test.want {
+			t.Errorf("setAutoIncrement = %q, want %q", got, test.want)
+		}
+	}
+	if got := (mysqlDialect{}).setAutoIncrement("t", "id", math.MaxUint64); got != "" {
+		t.Errorf("setAutoIncrement of the largest unsigned BIGINT = %q, want none", got)
+	}
+}
+
+func TestIntegerLimit(t *testing.T) {
+	tests := []struct {
+		dataType, columnType string
+		want                 uint64
+	}{
+		{"tinyint", "tinyint", math.MaxInt8},
+		{"tinyint", "tinyint unsigned", math.MaxUint8},
+		{"smallint", "smallint(6)", math.MaxInt16},
+		{"mediumint", "mediumint unsigned", 1<<24 - 1},
+		{"int", "int", math.MaxInt32},
+		{"INT", "INT UNSIGNED", math.MaxUint32},
+		{"bigint", "bigint", math.MaxInt64},
+		{"bigint", "bigint unsigned", math.MaxUint64},
+		{"decimal", "decimal(20,0)", math.MaxUint64},
+	}
+	for _, test := range tests {
+		if got := integerLimit(ColumnInfo{DataType: test.dataType, ColumnType: test.columnType}); got != test.want {
+			t.Errorf("integerLimit(%s) = %d, want %d", test.columnType, got, test.want)
+		}
+	}
+}
+
+func TestExportAutoIncrementAtLimit(t *testing.T) {
+	tests := []struct {
+		dialect, columnType, id string
+		want                    string
+	}{
+		{DialectMySQL, "bigint", "41", "ALTER TABLE `t` AUTO_INCREMENT=42;\n"},
+		// Loading the largest ID moves the counter to its end already
+		{DialectMySQL, "bigint", "9223372036854775807", ""},
+		{DialectMySQL, "bigint unsigned", "9223372036854775807", "ALTER TABLE `t` AUTO_INCREMENT=9223372036854775808;\n"},
+		{DialectMySQL, "bigint unsigned", "18446744073709551615", ""},
+		{DialectMySQL, "int unsigned", "4294967295", ""},
+		{DialectPostgres, "bigint", "9223372036854775807", "SELECT setval(pg_get_serial_sequence('\"t\"', 'id'), 9223372036854775807);\n"},
+	}
+	for _, test := range tests {
+		config := Config{Dialect: test.dialect, InsertMode: InsertModeInsert, AutoIncrement: AutoIncrementMaxExported}
+		info := &TableInfo{Name: "t", PrimaryKey: []string{"id"}, AutoIncrement: "id",
+			Columns: []ColumnInfo{{Name: "id", DataType: strings.Fields(test.columnType)[0], ColumnType: test.columnType}}}
+		e, _ := newFakeExporter(t, config, func(string) fakeResult {
+			return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{[]byte("1")}, {[]byte(test.id)}}}
+		})
+		dialect, err := newDialect(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.dialect, e.statementBytes = dialect, defaultMaxAllowedPacket
+
+		path := filepath.Join(t.TempDir(), "data.sql")
+		out, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.exportTableData(info, out); err != nil {
+			t.Fatal(err)
+		}
+		out.Close()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, after, _ := strings.Cut(string(content), "UNLOCK TABLES;\n")
+		if test.dialect != DialectMySQL {
+			_, after, _ = strings.Cut(string(content), ");\n")
+		}
+		if after != test.want {
+			t.Errorf("%s %s up to %s: counter = %q, want %q", test.dialect, test.columnType, test.id, after, test.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package exporter
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ChecksumsFileName is the name of the file holding the table checksums of an export
+const ChecksumsFileName = "checksums.json"
+
+// RowDigestsFileName is the name of the file holding the digest of every
+// exported row, one JSON object per line
+const RowDigestsFileName = "row_digests.jsonl"
+
+// TableChecksum holds the checksum of one exported table. The digests of
+// its rows are streamed to the row digests file while the table is read.
+type TableChecksum struct {
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	PrimaryKey []string `json:"primary_key,omitempty"`
+	Rows       int64    `json:"rows"`
+	Checksum   string   `json:"checksum"`
+
+	keyIndexes []int
+	sum        uint64
+	hash       hash.Hash
+	// prefix and current are reused for every row by digest
+	prefix  []byte
+	current []byte
+	digests *digestWriter
+	// linePrefix starts the lines of the table in the row digests file
+	linePrefix []byte
+}
+
+// RowChecksum is a line of the row digests file. Rows are identified by
+// their primary key values, rows of tables without a primary key only
+// carry the digest. Key holds the values as they were read from the
+// source, see appendKeyValue for their encoding in the file.
+type RowChecksum struct {
+	Table  string   `json:"table"`
+	Key    []string `json:"key,omitempty"`
+	Digest string   `json:"digest"`
+}
+
+// newTableChecksum starts collecting the checksums of a table. The row
+// digests are written to digests unless it is nil.
+func newTableChecksum(table string, columns, primaryKey []string, digests *digestWriter) *TableChecksum {
+	checksum := &TableChecksum{
+		Table:      table,
+		Columns:    columns,
+		PrimaryKey: primaryKey,
+		digests:    digests,
+	}
+	for _, key := range primaryKey {
+		for i, column := range columns {
+			if column == key {
+				checksum.keyIndexes = append(checksum.keyIndexes, i)
+			}
+		}
+	}
+	if digests != nil {
+		checksum.linePrefix = appendJSONString([]byte(`{"table":`), []byte(table))
+	}
+	checksum.finish()
+	return checksum
+}
+
+// add records the digest of a row scanned by rowScanner in the order of Columns
+func (c *TableChecksum) add(raw [][]byte) error {
+	digest := c.digest(raw)
+	c.addDigest(digest)
+	if c.digests == nil {
+		return nil
+	}
+	return c.digests.write(c, raw, digest)
+}
+
+// nullDigest stands for NULL in the input of row digests
+var nullDigest = []byte("N;")
+
+// digest returns the MD5 digest of a row, which is valid until the next
+// call. Every value is length prefixed so that NULL, empty strings and
+// values containing separators stay distinct.
+func (c *TableChecksum) digest(raw [][]byte) []byte {
+	if c.hash == nil {
+		c.hash = md5.New()
+	}
+	c.hash.Reset()
+	for _, value := range raw {
+		if value == nil {
+			c.hash.Write(nullDigest)
+			continue
+		}
+		c.prefix = append(strconv.AppendInt(c.prefix[:0], int64(len(value)), 10), ':')
+		c.hash.Write(c.prefix)
+		c.hash.Write(value)
+	}
+	c.current = c.hash.Sum(c.current[:0])
+	return c.current
+}
+
+// addDigest adds the digest of a row to the table checksum. The table
+// checksum is the sum of the row digests, so it does not depend on the order
+// in which rows are read and duplicate rows do not cancel out.
+func (c *TableChecksum) addDigest(digest []byte) {
+	c.sum += binary.BigEndian.Uint64(digest)
+	c.Rows++
+}
+
+// finish sets Checksum once all rows were added
+func (c *TableChecksum) finish() {
+	c.Checksum = c.formatSum()
+}
+
+// rowKey appends the primary key values of a row as a JSON array, which
+// identifies the row in the row digests file and in verify reports
+func (c *TableChecksum) rowKey(dst []byte, raw [][]byte) []byte {
+	dst = append(dst, '[')
+	for n, i := range c.keyIndexes {
+		if n > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendKeyValue(dst, raw[i])
+	}
+	return append(dst, ']')
+}
+
+// formatKey formats key values read from the row digests file like rowKey
+func formatKey(key []string) string {
+	dst := []byte{'['}
+	for n, value := range key {
+		if n > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendKeyValue(dst, []byte(value))
+	}
+	return string(append(dst, ']'))
+}
+
+// appendKeyValue appends a primary key value as JSON. Values that are not
+// valid UTF-8, such as those of BINARY or binary UUID keys, are written as
+// {"hex":"..."} so that they are read back byte for byte and still match
+// the row in the target.
+func appendKeyValue(dst, value []byte) []byte {
+	if utf8.Valid(value) {
+		return appendJSONString(dst, value)
+	}
+	dst = append(dst, `{"hex":"`...)
+	dst = hex.AppendEncode(dst, value)
+	return append(dst, `"}`...)
+}
+
+// formatSum formats the aggregated table checksum
+func (c *TableChecksum) formatSum() string {
+	return fmt.Sprintf("%016x", c.sum)
+}
+
+// digestWriter streams row digests to the row digests file, so that their
+// number is not limited by memory
+type digestWriter struct {
+	w    *bufio.Writer
+	line []byte
+}
+
+func newDigestWriter(w io.Writer) *digestWriter {
+	return &digestWriter{w: bufio.NewWriterSize(w, encoderBufferSize)}
+}
+
+// write writes the line of a row of the table of c
+func (d *digestWriter) write(c *TableChecksum, raw [][]byte, digest []byte) error {
+	line := append(d.line[:0], c.linePrefix...)
+	if len(c.keyIndexes) > 0 {
+		line = c.rowKey(append(line, `,"key":`...), raw)
+	}
+	line = append(line, `,"digest":"`...)
+	line = hex.AppendEncode(line, digest)
+	line = append(line, "\"}\n"...)
+	d.line = line
+	_, err := d.w.Write(line)
+	return err
+}
+
+func (d *digestWriter) flush() error {
+	return d.w.Flush()
+}
+
+// appendJSONString appends value as a JSON string. Bytes that are not valid
+// UTF-8 become U+FFFD like with encoding/json.
+func appendJSONString(dst, value []byte) []byte {
+	const hexDigits = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(value); {
+		c := value[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				dst = append(dst, '\\', c)
+			case c < 0x20:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			default:
+				dst = append(dst, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(value[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = utf8.AppendRune(dst, utf8.RuneError)
+		} else {
+			dst = append(dst, value[i:i+size]...)
+		}
+		i += size
+	}
+	return append(dst, '"')
+}
+
+// writeChecksums writes the table checksums of all exported tables
+func writeChecksums(path string, checksums []TableChecksum) error {
+	content, err := json.Marshal(checksums)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrWriteChecksums, err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf(msgs.ErrWriteChecksums, err)
+	}
+	return nil
+}
+
+// ReadChecksums loads the table checksums written by an export
+func ReadChecksums(path string) ([]TableChecksum, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadChecksums, path, err)
+	}
+	var checksums []TableChecksum
+	if err := json.Unmarshal(content, &checksums); err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadChecksums, path, err)
+	}
+	return checksums, nil
+}
+
+// UnmarshalJSON reads a line of the row digests file, decoding the key
+// values written by appendKeyValue
+func (r *RowChecksum) UnmarshalJSON(data []byte) error {
+	var line struct {
+		Table  string            `json:"table"`
+		Key    []json.RawMessage `json:"key"`
+		Digest string            `json:"digest"`
+	}
+	if err := json.Unmarshal(data, &line); err != nil {
+		return err
+	}
+	*r = RowChecksum{Table: line.Table, Digest: line.Digest}
+	for _, raw := range line.Key {
+		var value string
+		if len(raw) > 0 && raw[0] == '{' {
+			var binary struct {
+				Hex string `json:"hex"`
+			}
+			if err := json.Unmarshal(raw, &binary); err != nil {
+				return err
+			}
+			decoded, err := hex.DecodeString(binary.Hex)
+			if err != nil {
+				return err
+			}
+			value = string(decoded)
+		} else if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		r.Key = append(r.Key, value)
+	}
+	return nil
+}
+
+// digestReader reads the row digests file table by table, in the order
+// the tables were exported
+type digestReader struct {
+	path    string
+	scanner *bufio.Scanner
+	next    *RowChecksum
+}
+
+// maxDigestLine limits the length of a line of the row digests file
+const maxDigestLine = 64 << 20
+
+func newDigestReader(path string, r io.Reader) *digestReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxDigestLine)
+	return &digestReader{path: path, scanner: scanner}
+}
+
+// read returns the next row of table, or nil once its rows are exhausted
+func (d *digestReader) read(table string) (*RowChecksum, error) {
+	if d.next == nil {
+		if !d.scanner.Scan() {
+			if err := d.scanner.Err(); err != nil {
+				return nil, fmt.Errorf(msgs.ErrReadChecksums, d.path, err)
+			}
+			return nil, nil
+		}
+		d.next = &RowChecksum{}
+		if err := json.Unmarshal(d.scanner.Bytes(), d.next); err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadChecksums, d.path, err)
+		}
+	}
+	if d.next.Table != table {
+		return nil, nil
+	}
+	row := d.next
+	d.next = nil
+	return row, nil
+}
+
+// skip reads past the rows of table
+func (d *digestReader) skip(table string) error {
+	for {
+		row, err := d.read(table)
+		if row == nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"bytes"
+	"crypto/md5"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTableChecksum(t *testing.T) {
+	rows := [][][]byte{
+		{[]byte("1"), []byte("a,b")},
+		{[]byte("2"), nil},
+		{[]byte("3"), {}},
+	}
+	inputs := []string{"1:13:a,b", "1:2N;", "1:30:"}
+	var sum uint64
+	for _, input := range inputs {
+		digest := md5.Sum([]byte(input))
+		sum += binary.BigEndian.Uint64(digest[:])
+	}
+
+	var lines bytes.Buffer
+	digests := newDigestWriter(&lines)
+	c := newTableChecksum("t", []string{"id", "v"}, []string{"id"}, digests)
+	for _, raw := range rows {
+		if err := c.add(raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.finish()
+	if err := digests.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("%016x", sum); c.Checksum != want || c.Rows != 3 {
+		t.Errorf("checksum = %s of %d rows, want %s of 3", c.Checksum, c.Rows, want)
+	}
+	digest := md5.Sum([]byte(inputs[0]))
+	if want := fmt.Sprintf("{\"table\":\"t\",\"key\":[\"1\"],\"digest\":\"%x\"}\n", digest); !bytes.HasPrefix(lines.Bytes(), []byte(want)) {
+		t.Errorf("row digests = %q, want %q first", lines.String(), want)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { c.add(rows[0]) }); allocs != 0 {
+		t.Errorf("add allocates %v times per row, want 0", allocs)
+	}
+}
+
+func TestRowDigestsBinaryKeys(t *testing.T) {
+	rows := [][][]byte{
+		{{0x00, 0xff, 0x10}, []byte("binary")},
+		{[]byte("名前"), []byte("text")},
+		{[]byte("a\"b\\\n"), nil},
+		{[]byte("\xe5\x90"), []byte("truncated UTF-8")},
+	}
+	var lines bytes.Buffer
+	digests := newDigestWriter(&lines)
+	c := newTableChecksum("t", []string{"id", "v"}, []string{"id"}, digests)
+	for _, raw := range rows {
+		if err := c.add(raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.finish()
+	if err := digests.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"table":"t","key":[{"hex":"00ff10"}],"digest":`; !bytes.HasPrefix(lines.Bytes(), []byte(want)) {
+		t.Errorf("row digests = %q, want %q first", lines.String(), want)
+	}
+
+	// Every key is read back as it was exported
+	reader := newDigestReader(RowDigestsFileName, bytes.NewReader(lines.Bytes()))
+	for _, raw := range rows {
+		row, err := reader.read("t")
+		if err != nil || row == nil {
+			t.Fatalf("read = %v, %v", row, err)
+		}
+		if len(row.Key) != 1 || row.Key[0] != string(raw[0]) {
+			t.Errorf("key = %q, want %q", row.Key, raw[0])
+		}
+	}
+
+	// and looked up in the target with its original bytes
+	e, db := newFakeExporter(t, Config{}, func(string) fakeResult {
+		var values [][]driver.Value
+		for _, raw := range rows {
+			values = append(values, []driver.Value{raw[0], raw[1]})
+		}
+		return fakeResult{columns: []string{"id", "v"}, rows: values}
+	})
+	result := &TableVerification{}
+	reader = newDigestReader(RowDigestsFileName, bytes.NewReader(lines.Bytes()))
+	if err := e.verifyTable(*c, reader, result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.MissingRows) > 0 || len(result.ChangedRows) > 0 || result.ActualChecksum != c.Checksum {
+		t.Errorf("verify = %+v, want every row found unchanged", result)
+	}
+	want := []driver.Value{[]byte{0x00, 0xff, 0x10}, "名前", "a\"b\\\n", []byte("\xe5\x90")}
+	if args := db.sentArgs(); len(args) != 1 || !reflect.DeepEqual(args[0], want) {
+		t.Errorf("query arguments = %q, want %q", args, want)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { c.add(rows[0]) }); allocs != 0 {
+		t.Errorf("add of a binary key allocates %v times per row, want 0", allocs)
+	}
+}
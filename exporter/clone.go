@@ -0,0 +1,347 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// How Clone treats tables that already exist in the target
+const (
+	// ExistingDrop drops and recreates existing tables and views
+	ExistingDrop = "drop"
+	// ExistingTruncate keeps the structure of existing tables and replaces their rows
+	ExistingTruncate = "truncate"
+	// ExistingSkip leaves existing tables and views untouched, it is the default
+	ExistingSkip = "skip"
+)
+
+// maxPlaceholders is the limit of placeholders in one prepared statement
+const maxPlaceholders = 65535
+
+// paramOverhead is an upper bound of the bytes that a prepared statement
+// argument adds to its value: its type, a length prefix and its bit in the
+// NULL bitmap
+const paramOverhead = 12
+
+// CloneOptions controls how Clone writes into the target database
+type CloneOptions struct {
+	// Existing is one of ExistingDrop, ExistingTruncate or ExistingSkip
+	Existing string
+	// BatchSize is the number of rows inserted per statement. Batches are
+	// smaller where the rows would exceed Config.MaxStatementBytes or the
+	// target's max_allowed_packet.
+	BatchSize int
+}
+
+// Clone copies the schema and data of the source database straight into the
+// target database, using the same table discovery, schema extraction and row
+// reading as Execute
+func (e *Exporter) Clone(target *sql.DB, opts CloneOptions) error {
+	return e.CloneContext(context.Background(), target, opts)
+}
+
+// CloneContext is Clone stopping once ctx is canceled with a *CanceledError
+func (e *Exporter) CloneContext(ctx context.Context, target *sql.DB, opts CloneOptions) error {
+	defer e.bind(ctx)()
+	e.operation = OperationClone
+	return e.canceled(e.clone(target, opts))
+}
+
+func (e *Exporter) clone(target *sql.DB, opts CloneOptions) error {
+	switch opts.Existing {
+	case "":
+		opts.Existing = ExistingSkip
+	case ExistingDrop, ExistingTruncate, ExistingSkip:
+	default:
+		return fmt.Errorf(msgs.ErrInvalidExistingMode, opts.Existing)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	e.emit(Event{Type: EventStarted})
+
+	if e.config.SingleTransaction {
+		defer e.endSnapshot()
+		if err := e.startSnapshot(); err != nil {
+			return err
+		}
+	}
+
+	// Session settings such as FOREIGN_KEY_CHECKS need a dedicated connection
+	ctx := e.ctx
+	conn, err := target.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrConnectTarget, err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS=0"); err != nil {
+		return fmt.Errorf(msgs.ErrConnectTarget, err)
+	}
+	defer conn.ExecContext(context.Background(), "SET FOREIGN_KEY_CHECKS=1")
+
+	// Without a selected database the tables would be created nowhere
+	var database sql.NullString
+	if err := conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database); err != nil {
+		return fmt.Errorf(msgs.ErrConnectTarget, err)
+	}
+	if database.String == "" {
+		return fmt.Errorf(msgs.ErrTargetNoDatabase)
+	}
+	targetQ := boundQueryer{ctx: ctx, q: conn, log: e.log}
+	if err := e.checkCloneTarget(targetQ, database.String); err != nil {
+		return err
+	}
+	e.statementBytes = statementLimit(targetQ, e.config.MaxStatementBytes)
+
+	existing, err := targetTables(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	tables, err := e.loadMetadata()
+	if err != nil {
+		return err
+	}
+	found := Event{Type: EventTablesFound, Tables: len(tables)}
+	for _, table := range tables {
+		rows, bytes := table.exported(e.config)
+		found.EstimatedRows += rows
+		found.EstimatedBytes += bytes
+	}
+	e.emit(found)
+
+	var views []string
+	for _, table := range tables {
+		// Views may depend on any table, so they are created after all tables
+		if table.IsView {
+			views = append(views, table.Name)
+			continue
+		}
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+		e.table = table.Name
+		if err := e.cloneTable(conn, table, existing[table.Name], opts); err != nil {
+			return err
+		}
+	}
+	e.table = ""
+
+	for _, view := range views {
+		if existing[view] && opts.Existing == ExistingSkip {
+			e.emit(Event{Type: EventTableFinished, Table: view, IsView: true, Skipped: true})
+			continue
+		}
+		e.emit(Event{Type: EventTableStarted, Table: view, IsView: true})
+		create, err := e.showCreate(view, true)
+		if err != nil {
+			return err
+		}
+		create = rewriteDefiner(create, e.config.Definer, e.config.SQLSecurityInvoker)
+		for _, statement := range []string{"DROP VIEW IF EXISTS " + quoteIdent(view), create} {
+			if _, err := conn.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf(msgs.ErrCloneSchema, view, err)
+			}
+		}
+		e.emit(Event{Type: EventTableFinished, Table: view, IsView: true})
+	}
+
+	e.emit(Event{Type: EventFinished})
+	return nil
+}
+
+// checkCloneTarget refuses to clone a database into itself, which would
+// drop or truncate the source tables before they are read
+func (e *Exporter) checkCloneTarget(target queryer, database string) error {
+	source, err := databaseIdentity(e.q)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrCheckCloneTarget, err)
+	}
+	identity, err := databaseIdentity(target)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrCheckCloneTarget, err)
+	}
+	// Database names may be case insensitive on the server
+	if strings.EqualFold(source, identity) {
+		return fmt.Errorf(msgs.ErrCloneSameDatabase, database)
+	}
+	return nil
+}
+
+// databaseIdentity identifies the server and the selected database of a
+// connection. MariaDB has no server_uuid, so its host name, port and data
+// directory stand in for it.
+func databaseIdentity(q queryer) (string, error) {
+	var server, database sql.NullString
+	err := q.QueryRow("SELECT @@server_uuid, DATABASE()").Scan(&server, &database)
+	if err != nil {
+		err = q.QueryRow("SELECT CONCAT_WS(':', @@hostname, @@port, @@datadir), DATABASE()").Scan(&server, &database)
+	}
+	if err != nil {
+		return "", err
+	}
+	return server.String + "/" + database.String, nil
+}
+
+// targetTables returns the tables and views of the target database
+func targetTables(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()")
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrGetTables, err)
+	}
+	defer rows.Close()
+
+	tables := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadTableInfo, err)
+		}
+		tables[name] = true
+	}
+	return tables, rows.Err()
+}
+
+// cloneTable creates a table in the target according to the existing table
+// mode and copies its rows
+func (e *Exporter) cloneTable(conn *sql.Conn, info *TableInfo, exists bool, opts CloneOptions) error {
+	table, columns := info.Name, info.columnNames()
+	ctx := e.ctx
+	e.tableBytes = 0
+
+	var statements []string
+	switch {
+	case exists && opts.Existing == ExistingSkip:
+		e.emit(Event{Type: EventTableFinished, Table: table, Skipped: true})
+		return nil
+	case exists && opts.Existing == ExistingTruncate:
+		statements = []string{"TRUNCATE TABLE " + quoteIdent(table)}
+	default:
+		create, err := e.showCreate(table, false)
+		if err != nil {
+			return err
+		}
+		statements = []string{"DROP TABLE IF EXISTS " + quoteIdent(table), rewriteAutoIncrement(create, e.cloneAutoIncrement())}
+	}
+	rows, bytes := info.exported(e.config)
+	e.emit(Event{Type: EventTableStarted, Table: table, EstimatedRows: rows, EstimatedBytes: bytes})
+	for _, statement := range statements {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf(msgs.ErrCloneSchema, table, err)
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize*len(columns) > maxPlaceholders {
+		batchSize = maxPlaceholders / len(columns)
+	}
+	inserter := &batchInserter{ctx: ctx, conn: conn, table: table, columns: columns, batchSize: batchSize, maxBytes: e.statementBytes}
+	defer inserter.close()
+
+	rowCount, err := e.scanTable(info, func(s *rowScanner) error {
+		return inserter.add(s.values(), rowSize(s.raw)+len(s.raw)*paramOverhead)
+	})
+	if err != nil {
+		return err
+	}
+	if err := inserter.flush(); err != nil {
+		return err
+	}
+
+	e.emit(Event{Type: EventTableFinished, Table: table, Rows: int64(rowCount)})
+	return nil
+}
+
+// cloneAutoIncrement returns the auto-increment mode of cloned tables. MySQL
+// moves the counter past inserted IDs itself, so max-exported needs no
+// statement of its own.
+func (e *Exporter) cloneAutoIncrement() string {
+	if e.config.AutoIncrement == AutoIncrementMaxExported {
+		return AutoIncrementReset
+	}
+	return e.config.AutoIncrement
+}
+
+// batchInserter inserts rows with multi-row prepared INSERT statements. The
+// statement for a full batch is prepared once per table, batches that are
+// cut short by maxBytes are sent with statements of their own.
+type batchInserter struct {
+	ctx       context.Context
+	conn      *sql.Conn
+	table     string
+	columns   []string
+	batchSize int
+	// maxBytes limits the size of the arguments of one statement, a single
+	// larger row is inserted alone
+	maxBytes int
+
+	stmt    *sql.Stmt
+	pending []interface{}
+	rows    int
+	bytes   int
+}
+
+// add queues a row whose arguments take size bytes and inserts the batch
+// once it is full. The batch is inserted first when the row would make it
+// exceed maxBytes.
+func (b *batchInserter) add(values []interface{}, size int) error {
+	if b.rows > 0 && b.maxBytes > 0 && b.bytes+size > b.maxBytes {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+	b.pending = append(b.pending, values...)
+	b.rows++
+	b.bytes += size
+	if b.rows < b.batchSize {
+		return nil
+	}
+
+	if b.stmt == nil {
+		stmt, err := b.conn.PrepareContext(b.ctx, b.insertStatement(b.batchSize))
+		if err != nil {
+			return fmt.Errorf(msgs.ErrCloneInsert, b.table, err)
+		}
+		b.stmt = stmt
+	}
+	if _, err := b.stmt.ExecContext(b.ctx, b.pending...); err != nil {
+		return fmt.Errorf(msgs.ErrCloneInsert, b.table, err)
+	}
+	b.pending = b.pending[:0]
+	b.rows, b.bytes = 0, 0
+	return nil
+}
+
+// flush inserts the rows of an incomplete batch
+func (b *batchInserter) flush() error {
+	if b.rows == 0 {
+		return nil
+	}
+	if _, err := b.conn.ExecContext(b.ctx, b.insertStatement(b.rows), b.pending...); err != nil {
+		return fmt.Errorf(msgs.ErrCloneInsert, b.table, err)
+	}
+	b.pending = b.pending[:0]
+	b.rows, b.bytes = 0, 0
+	return nil
+}
+
+func (b *batchInserter) close() {
+	if b.stmt != nil {
+		b.stmt.Close()
+	}
+}
+
+// insertStatement builds an INSERT statement with placeholders for the given number of rows
+func (b *batchInserter) insertStatement(rows int) string {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(b.columns)), ", ") + ")"
+	values := strings.TrimSuffix(strings.Repeat(row+", ", rows), ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", quoteIdent(b.table), quoteIdents(b.columns), values)
+}
@@ -0,0 +1,165 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFakeTarget returns a clone target running its statements on a fakeDB
+func newFakeTarget(t *testing.T, answer func(query string) fakeResult) (*sql.DB, *fakeDB) {
+	f := &fakeDB{answer: answer}
+	db := sql.OpenDB(f)
+	t.Cleanup(func() { db.Close() })
+	return db, f
+}
+
+// statements returns the recorded statements that change the target
+func statements(f *fakeDB) []string {
+	var changes []string
+	for _, query := range f.sent() {
+		for _, prefix := range []string{"DROP ", "TRUNCATE ", "CREATE ", "INSERT "} {
+			if strings.HasPrefix(query, prefix) {
+				changes = append(changes, query)
+			}
+		}
+	}
+	return changes
+}
+
+func TestBatchInserter(t *testing.T) {
+	target, f := newFakeTarget(t, func(string) fakeResult { return fakeResult{} })
+	conn, err := target.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	b := &batchInserter{ctx: context.Background(), conn: conn, table: "t", columns: []string{"v"}, batchSize: 3, maxBytes: 100}
+	defer b.close()
+	// Three small rows fill a batch, a row over the limit is inserted alone
+	// and a row that would exceed the limit starts the next batch
+	for _, size := range []int{10, 10, 10, 150, 10, 60, 40} {
+		if err := b.add([]interface{}{int64(size)}, size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var batches [][]driver.Value
+	for i, query := range f.sent() {
+		if strings.HasPrefix(query, "INSERT ") {
+			batches = append(batches, f.sentArgs()[i])
+			if rows := strings.Count(query, "(?)"); rows != len(f.sentArgs()[i]) {
+				t.Errorf("%q has %d rows for %d values", query, rows, len(f.sentArgs()[i]))
+			}
+		}
+	}
+	want := [][]driver.Value{{int64(10), int64(10), int64(10)}, {int64(150)}, {int64(10), int64(60)}, {int64(40)}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+}
+
+// cloneSource answers the queries of a clone of the tables orders and users
+// from a server with the given server_uuid
+func cloneSource(uuid string) func(query string) fakeResult {
+	return func(query string) fakeResult {
+		switch {
+		case strings.Contains(query, "information_schema.TABLES"):
+			return fakeResult{columns: []string{"TABLE_NAME", "TABLE_TYPE", "TABLE_ROWS", "DATA_LENGTH"}, rows: [][]driver.Value{
+				{"orders", "BASE TABLE", int64(2), int64(16384)},
+				{"users", "BASE TABLE", int64(2), int64(16384)},
+			}}
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			return fakeResult{columns: []string{"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "IS_NULLABLE", "COLUMN_KEY", "EXTRA"}, rows: [][]driver.Value{
+				{"orders", "id", "int", "int", "NO", "PRI", ""},
+				{"users", "id", "int", "int", "NO", "PRI", ""},
+			}}
+		case strings.Contains(query, "information_schema.STATISTICS"):
+			return fakeResult{columns: []string{"TABLE_NAME", "COLUMN_NAME"}, rows: [][]driver.Value{{"orders", "id"}, {"users", "id"}}}
+		case strings.Contains(query, "@@server_uuid"):
+			return fakeResult{columns: []string{"uuid", "database"}, rows: [][]driver.Value{{uuid, "shop"}}}
+		case strings.HasPrefix(query, "SHOW CREATE TABLE"):
+			return fakeResult{columns: []string{"Table", "Create Table"}, rows: [][]driver.Value{{"t", "CREATE TABLE t"}}}
+		}
+		return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{"1"}, {"2"}}}
+	}
+}
+
+// cloneTarget answers the queries of a clone into the database shop of a
+// server with the given server_uuid that already holds the table orders
+func cloneTarget(uuid string) func(query string) fakeResult {
+	return func(query string) fakeResult {
+		switch {
+		case query == "SELECT DATABASE()":
+			return fakeResult{columns: []string{"DATABASE()"}, rows: [][]driver.Value{{"shop"}}}
+		case strings.Contains(query, "@@server_uuid"):
+			return fakeResult{columns: []string{"uuid", "database"}, rows: [][]driver.Value{{uuid, "shop"}}}
+		case strings.Contains(query, "@@max_allowed_packet"):
+			return fakeResult{columns: []string{"packet"}, rows: [][]driver.Value{{int64(1 << 20)}}}
+		case strings.Contains(query, "information_schema.TABLES"):
+			return fakeResult{columns: []string{"TABLE_NAME"}, rows: [][]driver.Value{{"orders"}}}
+		}
+		return fakeResult{}
+	}
+}
+
+func TestCloneExistingModes(t *testing.T) {
+	createUsers := []string{
+		"DROP TABLE IF EXISTS `users`",
+		"CREATE TABLE t",
+		"INSERT INTO `users` (`id`) VALUES (?), (?)",
+	}
+	tests := []struct {
+		existing string
+		want     []string
+	}{
+		{"", createUsers},
+		{ExistingSkip, createUsers},
+		{ExistingTruncate, append([]string{"TRUNCATE TABLE `orders`", "INSERT INTO `orders` (`id`) VALUES (?), (?)"}, createUsers...)},
+		{ExistingDrop, append([]string{"DROP TABLE IF EXISTS `orders`", "CREATE TABLE t", "INSERT INTO `orders` (`id`) VALUES (?), (?)"}, createUsers...)},
+	}
+	for _, test := range tests {
+		e, _ := newFakeExporter(t, Config{Database: "shop", Sample: SampleFirst}, cloneSource("source-uuid"))
+		target, f := newFakeTarget(t, cloneTarget("target-uuid"))
+		if err := e.Clone(target, CloneOptions{Existing: test.existing}); err != nil {
+			t.Fatalf("%q: %v", test.existing, err)
+		}
+		if got := statements(f); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: statements = %q, want %q", test.existing, got, test.want)
+		}
+		if e.statementBytes != 1<<20 {
+			t.Errorf("%q: statement limit = %d, want the target's max_allowed_packet", test.existing, e.statementBytes)
+		}
+	}
+
+	e, _ := newFakeExporter(t, Config{Database: "shop"}, cloneSource("source-uuid"))
+	target, _ := newFakeTarget(t, cloneTarget("target-uuid"))
+	if err := e.Clone(target, CloneOptions{Existing: "replace"}); err == nil {
+		t.Error("Clone with an invalid existing table mode succeeded")
+	}
+}
+
+func TestCloneSameDatabase(t *testing.T) {
+	e, _ := newFakeExporter(t, Config{Database: "shop", Sample: SampleFirst}, cloneSource("same-uuid"))
+	target, f := newFakeTarget(t, cloneTarget("same-uuid"))
+	if err := e.Clone(target, CloneOptions{Existing: ExistingDrop}); err == nil {
+		t.Fatal("Clone into the source database succeeded")
+	}
+	if got := statements(f); len(got) > 0 {
+		t.Errorf("statements = %q, want none", got)
+	}
+
+	// The same database name on another server is a different database
+	e, _ = newFakeExporter(t, Config{Database: "shop", Sample: SampleFirst}, cloneSource("source-uuid"))
+	target, _ = newFakeTarget(t, cloneTarget("target-uuid"))
+	if err := e.Clone(target, CloneOptions{Existing: ExistingDrop}); err != nil {
+		t.Errorf("Clone into another server = %v", err)
+	}
+}
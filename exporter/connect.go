@@ -0,0 +1,225 @@
+package exporter
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Connection pool limits set by Open
+const (
+	maxOpenConns    = 8
+	maxIdleConns    = 2
+	connMaxLifetime = 30 * time.Minute
+	connMaxIdleTime = time.Minute
+)
+
+// buildMySQLConfig merges the explicit connection settings into the optional base DSN
+func buildMySQLConfig(config Config) (*mysql.Config, error) {
+	cfg := mysql.NewConfig()
+	if config.DSN != "" {
+		parsed, err := mysql.ParseDSN(config.DSN)
+		if err != nil {
+			return nil, fmt.Errorf(msgs.ErrParseDSN, err)
+		}
+		cfg = parsed
+	}
+
+	if config.Socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = config.Socket
+	} else if config.Host != "" || config.Port != 0 {
+		host, port := config.Host, config.Port
+		if cfg.Net == "tcp" {
+			if h, p, err := splitAddr(cfg.Addr); err == nil {
+				if host == "" {
+					host = h
+				}
+				if port == 0 {
+					port = p
+				}
+			}
+		}
+		if port == 0 {
+			port = 3306
+		}
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	if config.User != "" {
+		cfg.User = config.User
+	}
+	if config.Password != "" {
+		cfg.Passwd = config.Password
+	}
+	if config.Database != "" {
+		cfg.DBName = config.Database
+	}
+
+	if config.SSLMode != "" || config.SSLCA != "" {
+		tlsConfig, err := buildTLSConfig(config, cfg)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsConfig
+		if tlsConfig == nil {
+			// The driver would enable TLS again from a tls= parameter of the base DSN
+			cfg.TLSConfig = "false"
+		}
+		cfg.AllowFallbackToPlaintext = strings.EqualFold(config.SSLMode, "PREFERRED")
+	}
+	if config.EnableCleartextPlugin {
+		cfg.AllowCleartextPasswords = true
+	}
+	if config.ServerPublicKeyPath != "" {
+		pubKey, err := loadPublicKey(config.ServerPublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		name := serverPubKeyName(pubKey)
+		mysql.RegisterServerPubKey(name, pubKey)
+		cfg.ServerPubKey = name
+	}
+
+	// The exporter relies on these settings regardless of the base DSN
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	if _, ok := cfg.Params["charset"]; !ok {
+		cfg.Params["charset"] = "utf8mb4"
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
+
+	if config.ConnectTimeout > 0 {
+		cfg.Timeout = config.ConnectTimeout
+	}
+	if config.ReadTimeout > 0 {
+		cfg.ReadTimeout = config.ReadTimeout
+	}
+	if config.WriteTimeout > 0 {
+		cfg.WriteTimeout = config.WriteTimeout
+	}
+
+	// A throttled export may stop reading a result for minutes, the server
+	// must not give up sending it after the default 60 seconds
+	if _, ok := cfg.Params["net_write_timeout"]; !ok && throttled(config) {
+		cfg.Params["net_write_timeout"] = "3600"
+	}
+
+	return cfg, nil
+}
+
+// buildTLSConfig creates the TLS configuration for the given ssl mode.
+// A nil result disables TLS.
+func buildTLSConfig(config Config, cfg *mysql.Config) (*tls.Config, error) {
+	mode := strings.ToUpper(config.SSLMode)
+	if mode == "" {
+		// Like the mysql client, a CA file alone implies verification
+		mode = "VERIFY_CA"
+	}
+
+	tlsConfig := &tls.Config{}
+	if config.SSLCA != "" {
+		pemData, err := os.ReadFile(config.SSLCA)
+		if err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadSSLCA, config.SSLCA, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf(msgs.ErrReadSSLCA, config.SSLCA, fmt.Errorf("no certificates found"))
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	switch mode {
+	case "DISABLED":
+		return nil, nil
+	case "PREFERRED", "REQUIRED":
+		tlsConfig.InsecureSkipVerify = true
+	case "VERIFY_CA":
+		// Verify the certificate chain but not the host name
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("server presented no certificate")
+			}
+			opts := x509.VerifyOptions{
+				Roots:         tlsConfig.RootCAs,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range state.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	case "VERIFY_IDENTITY":
+		if cfg.Net == "tcp" {
+			if host, _, err := net.SplitHostPort(cfg.Addr); err == nil {
+				tlsConfig.ServerName = host
+			}
+		}
+	default:
+		return nil, fmt.Errorf(msgs.ErrInvalidSSLMode, config.SSLMode)
+	}
+	return tlsConfig, nil
+}
+
+// serverPubKeyName returns the name under which a server public key is
+// registered with the driver. The registry is shared by the whole process, so
+// the name is derived from the key and exporters using different keys do not
+// overwrite each other's.
+func serverPubKeyName(key *rsa.PublicKey) string {
+	sum := sha256.Sum256(x509.MarshalPKCS1PublicKey(key))
+	return "mysql-exporter-" + hex.EncodeToString(sum[:8])
+}
+
+// loadPublicKey reads an RSA public key from a PEM file
+func loadPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadPublicKey, path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf(msgs.ErrReadPublicKey, path, fmt.Errorf("no PEM data found"))
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadPublicKey, path, err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf(msgs.ErrReadPublicKey, path, fmt.Errorf("not an RSA public key"))
+	}
+	return rsaKey, nil
+}
+
+// splitAddr splits a tcp address into host and port
+func splitAddr(addr string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, err
+	}
+	return host, port, nil
+}
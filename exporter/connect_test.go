@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestBuildMySQLConfigSSLMode(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		mode    string
+		wantTLS bool
+	}{
+		{"u:p@tcp(db:3306)/d?tls=true", "DISABLED", false},
+		{"u:p@tcp(db:3306)/d?tls=skip-verify", "disabled", false},
+		{"u:p@tcp(db:3306)/d?tls=true", "", true},
+		{"u:p@tcp(db:3306)/d", "REQUIRED", true},
+		{"u:p@tcp(db:3306)/d", "", false},
+	}
+	for _, test := range tests {
+		cfg, err := buildMySQLConfig(Config{DSN: test.dsn, SSLMode: test.mode})
+		if err != nil {
+			t.Fatalf("%s with %q: %v", test.dsn, test.mode, err)
+		}
+		if got := cfg.TLS != nil; got != test.wantTLS {
+			t.Errorf("%s with %q: TLS = %v, want %v", test.dsn, test.mode, got, test.wantTLS)
+		}
+		if test.wantTLS {
+			continue
+		}
+		// The driver enables TLS from the tls parameter when the
+		// configuration has none
+		parsed, err := mysql.ParseDSN(cfg.FormatDSN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parsed.TLS != nil; got != test.wantTLS {
+			t.Errorf("%s with %q: TLS after normalizing = %v, want %v", test.dsn, test.mode, got, test.wantTLS)
+		}
+	}
+}
+
+// writePublicKey writes a new RSA public key to a PEM file and returns its path
+func writePublicKey(t *testing.T, name string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildMySQLConfigServerPublicKeys(t *testing.T) {
+	first, second := writePublicKey(t, "first.pem"), writePublicKey(t, "second.pem")
+	a, err := buildMySQLConfig(Config{Host: "a", ServerPublicKeyPath: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := buildMySQLConfig(Config{Host: "b", ServerPublicKeyPath: second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ServerPubKey == b.ServerPubKey {
+		t.Errorf("both keys are registered as %q", a.ServerPubKey)
+	}
+	again, err := buildMySQLConfig(Config{Host: "c", ServerPublicKeyPath: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ServerPubKey != a.ServerPubKey {
+		t.Errorf("the same key is registered as %q and %q", a.ServerPubKey, again.ServerPubKey)
+	}
+}
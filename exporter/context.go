@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// CanceledError is returned when the context of an export, clone or verify
+// run is canceled or its deadline passes. Err is context.Canceled or
+// context.DeadlineExceeded, so errors.Is works with both.
+type CanceledError struct {
+	// Table is the table that was being processed, empty between tables
+	Table string
+	Err   error
+}
+
+func (c *CanceledError) Error() string {
+	if c.Table == "" {
+		return fmt.Sprintf(msgs.ErrCanceled, c.Err)
+	}
+	return fmt.Sprintf(msgs.ErrCanceledTable, c.Table, c.Err)
+}
+
+func (c *CanceledError) Unwrap() error {
+	return c.Err
+}
+
+// contextQueryer is implemented by *sql.DB and *sql.Conn
+type contextQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// boundQueryer runs queries with the context of the current run, so that
+// canceling the run aborts the query in flight. Queries failing with a
+// transient error are run again when retry is set.
+type boundQueryer struct {
+	ctx   context.Context
+	q     contextQueryer
+	log   *slog.Logger
+	retry func() *backoff
+}
+
+func (b boundQueryer) Query(query string, args ...any) (*sql.Rows, error) {
+	b.log.Debug(query, slog.Int("args", len(args)))
+	retry := b.backoff()
+	for {
+		rows, err := b.q.QueryContext(b.ctx, query, args...)
+		if err == nil || !retry.retry(err) {
+			return rows, err
+		}
+	}
+}
+
+func (b boundQueryer) QueryRow(query string, args ...any) *sql.Row {
+	b.log.Debug(query, slog.Int("args", len(args)))
+	retry := b.backoff()
+	for {
+		// Errors of the query itself are known before the row is scanned
+		row := b.q.QueryRowContext(b.ctx, query, args...)
+		if !retry.retry(row.Err()) {
+			return row
+		}
+	}
+}
+
+func (b boundQueryer) backoff() *backoff {
+	if b.retry == nil {
+		return nil
+	}
+	return b.retry()
+}
+
+// bind makes all further queries run with ctx and returns a function that
+// restores the background context
+func (e *Exporter) bind(ctx context.Context) func() {
+	e.ctx = ctx
+	e.table = ""
+	e.q = boundQueryer{ctx: ctx, q: e.db, log: e.log, retry: e.backoff}
+	return func() { e.bind(context.Background()) }
+}
+
+// canceled turns the error of a run into a CanceledError when its context
+// ended. The driver reports aborted queries with all kinds of errors.
+func (e *Exporter) canceled(err error) error {
+	if err == nil || e.ctx.Err() == nil {
+		return err
+	}
+	return &CanceledError{Table: e.table, Err: e.ctx.Err()}
+}
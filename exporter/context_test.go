@@ -0,0 +1,22 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOpenContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	_, _, err := OpenContext(ctx, Config{Host: "127.0.0.1", Port: 1, User: "root", Database: "db", Retries: 5})
+	var canceled *CanceledError
+	if !errors.As(err, &canceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("OpenContext = %v, want a CanceledError", err)
+	}
+	if elapsed := time.Since(start); elapsed >= retryDelay {
+		t.Errorf("OpenContext took %v, want no retries", elapsed)
+	}
+}
@@ -0,0 +1,235 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How the DEFINER clause of views and stored programs is written
+const (
+	// DefinerKeep keeps the definer of the source server
+	DefinerKeep = "keep"
+	// DefinerStrip removes the clause, the importing user becomes the definer
+	DefinerStrip = "strip"
+	// DefinerCurrentUser writes DEFINER=CURRENT_USER
+	DefinerCurrentUser = "current-user"
+)
+
+// validateDefiner checks the definer option, which is one of the modes
+// above or an account written as user@host
+func validateDefiner(definer string) error {
+	switch definer {
+	case "", DefinerKeep, DefinerStrip, DefinerCurrentUser:
+		return nil
+	}
+	if user, _ := splitAccount(definer); user == "" {
+		return fmt.Errorf(msgs.ErrInvalidDefiner, definer)
+	}
+	return nil
+}
+
+// splitAccount splits user@host, the parts may be quoted like in MySQL.
+// A missing host means any host.
+func splitAccount(account string) (string, string) {
+	user, host := account, "%"
+	if i := strings.LastIndex(account, "@"); i >= 0 {
+		user, host = account[:i], account[i+1:]
+	}
+	return unquoteAccountPart(user), unquoteAccountPart(host)
+}
+
+// unquoteAccountPart removes the backticks or quotes around a user or host name
+func unquoteAccountPart(part string) string {
+	if tokens := tokenize(part); len(tokens) == 1 && (tokens[0].kind == tokenIdent || tokens[0].kind == tokenString) {
+		return tokens[0].value()
+	}
+	return part
+}
+
+// rewriteDefiner rewrites the DEFINER clause of a CREATE VIEW, PROCEDURE,
+// FUNCTION, TRIGGER or EVENT statement and optionally forces SQL SECURITY
+// INVOKER. Only the statement header is changed, quoted text and the body are
+// left alone.
+func rewriteDefiner(create, definer string, invoker bool) string {
+	if (definer == "" || definer == DefinerKeep) && !invoker {
+		return create
+	}
+
+	tokens := tokenize(create)
+	var b strings.Builder
+	securitySeen := false
+	// body is the index of the body of a stored routine once its header is read
+	body := -1
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case i == body:
+			// Routines declare SQL SECURITY among the characteristics before the body
+			if invoker && !securitySeen {
+				b.WriteString("SQL SECURITY INVOKER\n")
+			}
+			b.WriteString(joinTokens(tokens[i:]))
+			return b.String()
+
+		case body < 0 && t.is("DEFINER") && definer != "" && definer != DefinerKeep:
+			eq := nextSignificant(tokens, i+1)
+			if eq >= len(tokens) || !tokens[eq].is("=") {
+				break
+			}
+			end := accountEnd(tokens, nextSignificant(tokens, eq+1))
+			switch definer {
+			case DefinerStrip:
+				// Drop the whitespace after the clause as well
+				for end < len(tokens) && tokens[end].kind == tokenSpace {
+					end++
+				}
+			case DefinerCurrentUser:
+				b.WriteString("DEFINER=CURRENT_USER")
+			default:
+				user, host := splitAccount(definer)
+				b.WriteString("DEFINER=" + quoteAccountPart(user) + "@" + quoteAccountPart(host))
+			}
+			i = end - 1
+			continue
+
+		case invoker && t.is("SQL"):
+			security := nextSignificant(tokens, i+1)
+			value := nextSignificant(tokens, security+1)
+			if value < len(tokens) && tokens[security].is("SECURITY") {
+				b.WriteString("SQL SECURITY INVOKER")
+				securitySeen = true
+				i = value
+				continue
+			}
+
+		case body < 0 && (t.is("VIEW") || t.is("TRIGGER") || t.is("EVENT")):
+			// Views declare SQL SECURITY before the VIEW keyword, triggers and
+			// events have none, so nothing after the keyword is rewritten
+			if t.is("VIEW") && invoker && !securitySeen {
+				b.WriteString("SQL SECURITY INVOKER ")
+			}
+			b.WriteString(joinTokens(tokens[i:]))
+			return b.String()
+
+		case body < 0 && (t.is("PROCEDURE") || t.is("FUNCTION")):
+			body = routineBody(tokens, i)
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// routineBody returns the index of the first token of the body of a stored
+// routine whose PROCEDURE or FUNCTION keyword is tokens[i]. The name, the
+// parameter list, the return type and the characteristics are skipped.
+func routineBody(tokens []token, i int) int {
+	next := func(i int) int { return min(nextSignificant(tokens, i+1), len(tokens)) }
+	is := func(i int, words ...string) bool {
+		for _, word := range words {
+			if i < len(tokens) && tokens[i].is(word) {
+				return true
+			}
+		}
+		return false
+	}
+	function := tokens[i].is("FUNCTION")
+
+	// The name may be qualified with the database
+	i = next(i)
+	for is(next(i), ".") {
+		i = next(next(i))
+	}
+	i = next(i)
+	if is(i, "(") {
+		i = nextSignificant(tokens, tokenGroupEnd(tokens, i))
+	}
+
+	if function && is(i, "RETURNS") {
+		i = next(next(i))
+	returnType:
+		for i < len(tokens) {
+			switch {
+			case is(i, "("):
+				i = nextSignificant(tokens, tokenGroupEnd(tokens, i))
+			case is(i, "UNSIGNED", "SIGNED", "ZEROFILL", "BINARY"):
+				i = next(i)
+			case is(i, "CHARSET", "COLLATE"):
+				i = next(next(i))
+			case is(i, "CHARACTER"):
+				i = next(next(next(i)))
+			default:
+				break returnType
+			}
+		}
+	}
+
+	for i < len(tokens) {
+		words := 0
+		switch {
+		case is(i, "DETERMINISTIC"):
+			words = 1
+		case is(i, "NOT", "COMMENT", "LANGUAGE", "CONTAINS", "NO"):
+			// NOT DETERMINISTIC, COMMENT 'text', LANGUAGE SQL, CONTAINS SQL, NO SQL
+			words = 2
+		case is(i, "READS", "MODIFIES", "SQL"):
+			// READS SQL DATA, MODIFIES SQL DATA, SQL SECURITY DEFINER
+			words = 3
+		default:
+			return i
+		}
+		for ; words > 0; words-- {
+			i = next(i)
+		}
+	}
+	return i
+}
+
+// tokenGroupEnd returns the index after the parenthesized group opening at tokens[i]
+func tokenGroupEnd(tokens []token, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch {
+		case tokens[i].is("("):
+			depth++
+		case tokens[i].is(")"):
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// nextSignificant returns the index of the next token that is not whitespace or a comment
+func nextSignificant(tokens []token, i int) int {
+	for i < len(tokens) && (tokens[i].kind == tokenSpace || tokens[i].kind == tokenComment) {
+		i++
+	}
+	return i
+}
+
+// accountEnd returns the index after the account starting at tokens[i]:
+// user@host with any quoting, or CURRENT_USER with optional parentheses
+func accountEnd(tokens []token, i int) int {
+	if i >= len(tokens) {
+		return i
+	}
+	end := i + 1
+	if tokens[i].is("CURRENT_USER") {
+		if end+1 < len(tokens) && tokens[end].is("(") && tokens[end+1].is(")") {
+			end += 2
+		}
+		return end
+	}
+	// The host part is a separate token unless the account is unquoted
+	if end < len(tokens) && tokens[end].is("@") && end+1 < len(tokens) {
+		end += 2
+	}
+	return end
+}
+
+// quoteAccountPart quotes the user or host name of an account
+func quoteAccountPart(name string) string {
+	return quoteIdent(name)
+}
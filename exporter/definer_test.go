@@ -0,0 +1,123 @@
+package exporter
+
+import "testing"
+
+func TestRewriteDefiner(t *testing.T) {
+	const view = "CREATE ALGORITHM=UNDEFINED DEFINER=`prod_admin`@`%` SQL SECURITY DEFINER VIEW `v` AS " +
+		"select 'DEFINER=`x`@`y` SQL SECURITY DEFINER' AS `c`"
+	tests := []struct {
+		name    string
+		create  string
+		definer string
+		invoker bool
+		want    string
+	}{
+		{
+			name:    "keep",
+			create:  view,
+			definer: DefinerKeep,
+			want:    view,
+		},
+		{
+			name:    "strip view",
+			create:  view,
+			definer: DefinerStrip,
+			want:    "CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v` AS select 'DEFINER=`x`@`y` SQL SECURITY DEFINER' AS `c`",
+		},
+		{
+			name:    "current user and invoker",
+			create:  view,
+			definer: DefinerCurrentUser,
+			invoker: true,
+			want: "CREATE ALGORITHM=UNDEFINED DEFINER=CURRENT_USER SQL SECURITY INVOKER VIEW `v` AS " +
+				"select 'DEFINER=`x`@`y` SQL SECURITY DEFINER' AS `c`",
+		},
+		{
+			name:    "account",
+			create:  view,
+			definer: "deploy@10.0.%",
+			want: "CREATE ALGORITHM=UNDEFINED DEFINER=`deploy`@`10.0.%` SQL SECURITY DEFINER VIEW `v` AS " +
+				"select 'DEFINER=`x`@`y` SQL SECURITY DEFINER' AS `c`",
+		},
+		{
+			name:    "quoted account with backtick and quote",
+			create:  "CREATE DEFINER=`o'b``rien`@`10.0.0.%` VIEW `v` AS select 1",
+			definer: "`a``b`@'localhost'",
+			want:    "CREATE DEFINER=`a``b`@`localhost` VIEW `v` AS select 1",
+		},
+		{
+			name:    "single quoted account",
+			create:  "CREATE DEFINER='app'@'localhost' VIEW `v` AS select 1",
+			definer: DefinerStrip,
+			want:    "CREATE VIEW `v` AS select 1",
+		},
+		{
+			name:    "unquoted account",
+			create:  "CREATE DEFINER=root@localhost VIEW `v` AS select 1",
+			definer: DefinerCurrentUser,
+			want:    "CREATE DEFINER=CURRENT_USER VIEW `v` AS select 1",
+		},
+		{
+			name:    "current user function",
+			create:  "CREATE DEFINER=CURRENT_USER() VIEW `v` AS select 1",
+			definer: DefinerStrip,
+			want:    "CREATE VIEW `v` AS select 1",
+		},
+		{
+			name:    "invoker without security clause",
+			create:  "CREATE VIEW `v` AS select 1",
+			invoker: true,
+			want:    "CREATE SQL SECURITY INVOKER VIEW `v` AS select 1",
+		},
+		{
+			name: "procedure body is left alone",
+			create: "CREATE DEFINER=`root`@`%` PROCEDURE `p`(IN `days` int)\n    SQL SECURITY DEFINER\n" +
+				"BEGIN\n  SELECT 'SQL SECURITY DEFINER', CURRENT_USER();\nEND",
+			definer: DefinerStrip,
+			invoker: true,
+			want: "CREATE PROCEDURE `p`(IN `days` int)\n    SQL SECURITY INVOKER\n" +
+				"BEGIN\n  SELECT 'SQL SECURITY DEFINER', CURRENT_USER();\nEND",
+		},
+		{
+			name: "function without security clause",
+			create: "CREATE DEFINER=`root`@`localhost` FUNCTION `db`.`f`(`x` int) RETURNS varchar(10) CHARSET utf8mb4\n" +
+				"    READS SQL DATA\n    DETERMINISTIC\n    COMMENT 'SQL SECURITY'\nRETURN (SELECT `name` FROM `t` WHERE `id` = `x`)",
+			definer: DefinerCurrentUser,
+			invoker: true,
+			want: "CREATE DEFINER=CURRENT_USER FUNCTION `db`.`f`(`x` int) RETURNS varchar(10) CHARSET utf8mb4\n" +
+				"    READS SQL DATA\n    DETERMINISTIC\n    COMMENT 'SQL SECURITY'\n" +
+				"SQL SECURITY INVOKER\nRETURN (SELECT `name` FROM `t` WHERE `id` = `x`)",
+		},
+		{
+			name:    "procedure with a single statement body",
+			create:  "CREATE DEFINER=`root`@`%` PROCEDURE `p`() UPDATE `t` SET `a` = 1",
+			invoker: true,
+			want:    "CREATE DEFINER=`root`@`%` PROCEDURE `p`() SQL SECURITY INVOKER\nUPDATE `t` SET `a` = 1",
+		},
+		{
+			name:    "trigger",
+			create:  "CREATE DEFINER=`root`@`%` TRIGGER `t` BEFORE INSERT ON `x` FOR EACH ROW SET NEW.`definer` = 'DEFINER=`a`@`b`'",
+			definer: DefinerStrip,
+			invoker: true,
+			want:    "CREATE TRIGGER `t` BEFORE INSERT ON `x` FOR EACH ROW SET NEW.`definer` = 'DEFINER=`a`@`b`'",
+		},
+	}
+	for _, test := range tests {
+		if got := rewriteDefiner(test.create, test.definer, test.invoker); got != test.want {
+			t.Errorf("%s: rewriteDefiner =\n%q\nwant\n%q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidateDefiner(t *testing.T) {
+	for _, definer := range []string{"", DefinerKeep, DefinerStrip, DefinerCurrentUser, "app@%", "app", "`a@b`@'h'"} {
+		if err := validateDefiner(definer); err != nil {
+			t.Errorf("validateDefiner(%q) = %v", definer, err)
+		}
+	}
+	for _, definer := range []string{"@host", "''@'h'"} {
+		if err := validateDefiner(definer); err == nil {
+			t.Errorf("validateDefiner(%q) succeeded", definer)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Dialects an export can be written in
+const (
+	// DialectMySQL writes the statements as MySQL returns them
+	DialectMySQL = "mysql"
+	// DialectPostgres translates the schema and data for PostgreSQL
+	DialectPostgres = "postgres"
+	// DialectSQLite translates the schema and data for SQLite
+	DialectSQLite = "sqlite"
+)
+
+// dialect renders the statements of schema.sql and data.sql for the database
+// the export is loaded into
+type dialect interface {
+	quoteIdent(name string) string
+
+	// The headers and footers are written around schema.sql and data.sql
+	schemaHeader() string
+	schemaFooter() string
+	dataHeader() string
+	dataFooter() string
+
+	dropTable(table string) string
+	dropView(view string) string
+	// createTable and createView translate a MySQL CREATE statement and
+	// return the statements together with warnings about what was lost
+	createTable(table, create string) (string, []string)
+	createView(view, create string) (string, []string)
+
+	// setAutoIncrement moves the counter of a table past the highest exported ID
+	setAutoIncrement(table, column string, max uint64) string
+
+	lockTable(table string) string
+	unlockTables() string
+	// appendLiteral appends a value scanned by rowScanner, nil is NULL
+	appendLiteral(dst, value []byte, kind valueKind) []byte
+
+	// insertInto and onConflict surround the values of an INSERT statement
+	// according to the insert mode, see InsertModeInsert
+	insertInto(mode string) string
+	onConflict(mode string, columns, primaryKey []string) string
+}
+
+// newDialect returns the dialect selected by the configuration, MySQL by default
+func newDialect(config Config) (dialect, error) {
+	switch name := strings.ToLower(config.Dialect); name {
+	case "", DialectMySQL:
+		return mysqlDialect{
+			rowAlias:      config.UpsertRowAlias,
+			ifNotExists:   config.IfNotExists,
+			autoIncrement: config.AutoIncrement,
+		}, nil
+	case DialectPostgres, "postgresql":
+		return &standardDialect{postgres: true, ifNotExists: config.IfNotExists}, nil
+	case DialectSQLite:
+		return &standardDialect{ifNotExists: config.IfNotExists}, nil
+	default:
+		return nil, fmt.Errorf(msgs.ErrInvalidDialect, config.Dialect)
+	}
+}
+
+// valueKind tells a dialect how to render the raw bytes of a column
+type valueKind int
+
+const (
+	kindText valueKind = iota
+	kindBinary
+	kindBit
+	kindTime
+)
+
+// columnKinds classifies the result columns by their MySQL type
+func columnKinds(rows *sql.Rows, count int) []valueKind {
+	kinds := make([]valueKind, count)
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return kinds
+	}
+	for i, t := range types {
+		switch t.DatabaseTypeName() {
+		case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "GEOMETRY":
+			kinds[i] = kindBinary
+		case "BIT":
+			kinds[i] = kindBit
+		case "DATE", "DATETIME", "TIMESTAMP":
+			kinds[i] = kindTime
+		}
+	}
+	return kinds
+}
+
+// mysqlDialect keeps the statements returned by the server
+type mysqlDialect struct {
+	// rowAlias uses the row alias of MySQL 8.0.19 instead of VALUES() in upserts
+	rowAlias bool
+	// ifNotExists adds IF NOT EXISTS to CREATE TABLE
+	ifNotExists bool
+	// autoIncrement is the mode applied to the AUTO_INCREMENT table option
+	autoIncrement string
+}
+
+func (mysqlDialect) quoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+func (mysqlDialect) schemaHeader() string { return "SET FOREIGN_KEY_CHECKS=0;\n\n" }
+func (mysqlDialect) schemaFooter() string { return "\nSET FOREIGN_KEY_CHECKS=1;\n" }
+func (mysqlDialect) dataHeader() string   { return "SET FOREIGN_KEY_CHECKS=0;\n\n" }
+func (mysqlDialect) dataFooter() string   { return "\nSET FOREIGN_KEY_CHECKS=1;\n" }
+
+func (d mysqlDialect) dropTable(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", d.quoteIdent(table))
+}
+
+func (d mysqlDialect) dropView(view string) string {
+	return fmt.Sprintf("DROP VIEW IF EXISTS %s;\n", d.quoteIdent(view))
+}
+
+func (d mysqlDialect) createTable(table, create string) (string, []string) {
+	create = rewriteAutoIncrement(create, d.autoIncrement)
+	if d.ifNotExists && hasPrefixFold(create, "CREATE TABLE ") {
+		create = "CREATE TABLE IF NOT EXISTS " + create[len("CREATE TABLE "):]
+	}
+	return create + ";\n", nil
+}
+
+func (mysqlDialect) createView(view, create string) (string, []string) {
+	return create + ";\n", nil
+}
+
+func (d mysqlDialect) setAutoIncrement(table, column string, max uint64) string {
+	// No counter is left above the largest unsigned BIGINT
+	if max == math.MaxUint64 {
+		return ""
+	}
+	return fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT=%d;\n", d.quoteIdent(table), max+1)
+}
+
+func (d mysqlDialect) lockTable(table string) string {
+	return fmt.Sprintf("LOCK TABLES %s WRITE;\n", d.quoteIdent(table))
+}
+
+func (mysqlDialect) unlockTables() string { return "UNLOCK TABLES;\n" }
+
+func (mysqlDialect) appendLiteral(dst, value []byte, kind valueKind) []byte {
+	if value == nil {
+		return append(dst, "NULL"...)
+	}
+	// 所有值都写成转义后的字符串，由MySQL转换为列的类型
+	dst = append(dst, '\'')
+	dst = appendEscaped(dst, value)
+	return append(dst, '\'')
+}
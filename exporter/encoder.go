@@ -0,0 +1,167 @@
+package exporter
+
+import (
+	"database/sql"
+	"io"
+)
+
+// encoderBufferSize is the size of the blocks a rowEncoder writes
+const encoderBufferSize = 256 << 10
+
+// canonicalTime is the format of date and time values in scanned rows
+const canonicalTime = "2006-01-02 15:04:05.999999"
+
+// rowScanner scans the rows of a result into buffers that are reused for
+// every row. Each value is nil for NULL or the bytes of its text form, with
+// dates and times in canonicalTime, so that rows read from different
+// servers and protocols compare equal. The bytes are only valid until the
+// next row is scanned.
+type rowScanner struct {
+	raw   [][]byte
+	kinds []valueKind
+
+	columns []sql.RawBytes
+	times   []sql.NullTime
+	text    [][]byte
+	dest    []interface{}
+}
+
+// newRowScanner prepares scanning count columns of rows
+func newRowScanner(rows *sql.Rows, count int) *rowScanner {
+	s := &rowScanner{
+		raw:     make([][]byte, count),
+		kinds:   columnKinds(rows, count),
+		columns: make([]sql.RawBytes, count),
+		times:   make([]sql.NullTime, count),
+		text:    make([][]byte, count),
+		dest:    make([]interface{}, count),
+	}
+	for i := range s.dest {
+		// The driver returns dates as time.Time, which does not scan into RawBytes
+		if s.kinds[i] == kindTime {
+			s.dest[i] = &s.times[i]
+		} else {
+			s.dest[i] = &s.columns[i]
+		}
+	}
+	return s
+}
+
+// scan reads the current row into raw
+func (s *rowScanner) scan(rows *sql.Rows) error {
+	if err := rows.Scan(s.dest...); err != nil {
+		return err
+	}
+	for i, kind := range s.kinds {
+		switch {
+		case kind != kindTime:
+			s.raw[i] = s.columns[i]
+		case s.times[i].Valid:
+			s.text[i] = s.times[i].Time.AppendFormat(s.text[i][:0], canonicalTime)
+			s.raw[i] = s.text[i]
+		default:
+			s.raw[i] = nil
+		}
+	}
+	return nil
+}
+
+// values returns a copy of the current row that stays valid, with dates
+// and times as time.Time
+func (s *rowScanner) values() []interface{} {
+	values := make([]interface{}, len(s.raw))
+	for i, value := range s.raw {
+		switch {
+		case value == nil:
+		case s.kinds[i] == kindTime:
+			values[i] = s.times[i].Time
+		default:
+			values[i] = append([]byte{}, value...)
+		}
+	}
+	return values
+}
+
+// rowEncoder renders rows as SQL value lists. Rows and the surrounding
+// statements are appended to a buffer that is written once it holds
+// encoderBufferSize bytes, and both buffers are reused for every row.
+type rowEncoder struct {
+	w       io.Writer
+	dialect dialect
+	buf     []byte
+	row     []byte
+}
+
+func newRowEncoder(w io.Writer, d dialect) *rowEncoder {
+	return &rowEncoder{w: w, dialect: d, buf: make([]byte, 0, 2*encoderBufferSize)}
+}
+
+// encode renders the current row of s as (value, ...). The result is only
+// valid until the next call.
+func (r *rowEncoder) encode(s *rowScanner) []byte {
+	b := append(r.row[:0], '(')
+	for i, value := range s.raw {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = r.dialect.appendLiteral(b, value, s.kinds[i])
+	}
+	r.row = append(b, ')')
+	return r.row
+}
+
+// write appends text to the buffer and writes the buffer once it is full
+func (r *rowEncoder) write(text ...[]byte) error {
+	r.add(text...)
+	if len(r.buf) < encoderBufferSize {
+		return nil
+	}
+	return r.flush()
+}
+
+// add appends text to the buffer without writing it
+func (r *rowEncoder) add(text ...[]byte) {
+	for _, t := range text {
+		r.buf = append(r.buf, t...)
+	}
+}
+
+// flush writes the buffered text
+func (r *rowEncoder) flush() error {
+	if len(r.buf) == 0 {
+		return nil
+	}
+	_, err := r.w.Write(r.buf)
+	r.buf = r.buf[:0]
+	return err
+}
+
+// appendEscaped appends a value escaped for a MySQL string literal. It
+// works on bytes, so binary values that are not valid UTF-8 stay intact.
+func appendEscaped(dst, value []byte) []byte {
+	for _, c := range value {
+		switch c {
+		case '\'':
+			dst = append(dst, '\\', '\'')
+		case '"':
+			dst = append(dst, '\\', '"')
+		case '\\':
+			dst = append(dst, '\\', '\\')
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		case '\b':
+			dst = append(dst, '\\', 'b')
+		case '\f':
+			dst = append(dst, '\\', 'f')
+		case 0:
+			dst = append(dst, '\\', '0')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,231 @@
+package exporter
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// benchTable is a table for the encoding benchmarks with one row of values
+type benchTable struct {
+	name string
+	info *TableInfo
+	row  []driver.Value
+}
+
+// benchTables returns a narrow table and a wide table with text, numbers,
+// dates and NULLs
+func benchTables() []benchTable {
+	narrow := benchTable{
+		name: "Narrow",
+		info: &TableInfo{Name: "users", PrimaryKey: []string{"id"}, Columns: []ColumnInfo{{Name: "id"}, {Name: "name"}, {Name: "created_at"}}},
+		row:  []driver.Value{[]byte("123456"), []byte("Alice O'Brien"), []byte("2024-05-17 08:30:00")},
+	}
+	wide := benchTable{name: "Wide", info: &TableInfo{Name: "events", PrimaryKey: []string{"c0"}}}
+	for i := 0; i < 40; i++ {
+		wide.info.Columns = append(wide.info.Columns, ColumnInfo{Name: fmt.Sprintf("c%d", i)})
+		switch i % 4 {
+		case 0:
+			wide.row = append(wide.row, []byte(fmt.Sprint(1000000+i)))
+		case 1:
+			wide.row = append(wide.row, []byte("a value with \"quotes\", commas\nand a line break"))
+		case 2:
+			wide.row = append(wide.row, []byte("19.99"))
+		default:
+			wide.row = append(wide.row, nil)
+		}
+	}
+	return []benchTable{narrow, wide}
+}
+
+// benchExporter returns an exporter reading the row of table b.N times
+func benchExporter(b *testing.B, table benchTable) *Exporter {
+	config := Config{InsertMode: InsertModeInsert, AutoIncrement: AutoIncrementReset, ProgressRows: defaultProgressRows}
+	e, _ := newFakeExporter(b, config, func(string) fakeResult {
+		return fakeResult{columns: table.info.columnNames(), rows: [][]driver.Value{table.row}, repeat: b.N}
+	})
+	dialect, err := newDialect(config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	e.dialect = dialect
+	e.statementBytes = defaultMaxAllowedPacket
+	return e
+}
+
+func TestAppendLiteral(t *testing.T) {
+	mysql, postgres, sqlite := mysqlDialect{}, &standardDialect{postgres: true}, &standardDialect{}
+	tests := []struct {
+		name  string
+		value []byte
+		kind  valueKind
+		// want are the literals in MySQL, PostgreSQL and SQLite
+		want [3]string
+	}{
+		{"null", nil, kindText, [3]string{"NULL", "NULL", "NULL"}},
+		{"empty", []byte{}, kindText, [3]string{"''", "''", "''"}},
+		{"quotes", []byte(`it's "q" \ x`), kindText,
+			[3]string{`'it\'s \"q\" \\ x'`, `'it''s "q" \ x'`, `'it''s "q" \ x'`}},
+		// PostgreSQL text cannot hold NUL bytes
+		{"control", []byte("a\nb\r\tc\x00d"), kindText,
+			[3]string{`'a\nb\r\tc\0d'`, "'a\nb\r\tcd'", "'a\nb\r\tc\x00d'"}},
+		{"utf8", []byte("名前"), kindText, [3]string{"'名前'", "'名前'", "'名前'"}},
+		{"binary", []byte{0x00, 0xff, '\'', '\n'}, kindBinary,
+			[3]string{`'\0` + "\xff" + `\'\n'`, `'\x00ff270a'`, `X'00ff270a'`}},
+		{"bit", []byte{0x01, 0x02}, kindBit, [3]string{"'\x01\x02'", "'258'", "'258'"}},
+		{"time", []byte("2024-05-17 08:30:00.5"), kindTime,
+			[3]string{"'2024-05-17 08:30:00.5'", "'2024-05-17 08:30:00.5'", "'2024-05-17 08:30:00.5'"}},
+	}
+	for _, test := range tests {
+		for i, d := range []dialect{mysql, postgres, sqlite} {
+			// The literal is appended to what the buffer holds
+			got := d.appendLiteral([]byte("x"), test.value, test.kind)
+			if string(got) != "x"+test.want[i] {
+				t.Errorf("%s in %T: appendLiteral = %q, want %q", test.name, d, got[1:], test.want[i])
+			}
+		}
+	}
+}
+
+func TestRowEncoderEncode(t *testing.T) {
+	created := time.Date(2024, 5, 17, 8, 30, 0, 500000000, time.UTC)
+	result := fakeResult{
+		columns: []string{"id", "name", "data", "created", "flags", "note"},
+		types:   []string{"INT", "VARCHAR", "BLOB", "DATETIME", "BIT", "TEXT"},
+		rows: [][]driver.Value{
+			{"1", "O'Brien\n", []byte{0x00, 0xff}, created, []byte{0x01}, nil},
+			{"2", "", []byte{}, nil, []byte{0x00}, "x"},
+		},
+	}
+	tests := []struct {
+		dialect dialect
+		want    []string
+	}{
+		{mysqlDialect{}, []string{
+			`('1', 'O\'Brien\n', '\0` + "\xff" + `', '2024-05-17 08:30:00.5', '` + "\x01" + `', NULL)`,
+			`('2', '', '', NULL, '\0', 'x')`,
+		}},
+		{&standardDialect{postgres: true}, []string{
+			"('1', 'O''Brien\n', '\\x00ff', '2024-05-17 08:30:00.5', '1', NULL)",
+			"('2', '', '\\x', NULL, '0', 'x')",
+		}},
+		{&standardDialect{}, []string{
+			"('1', 'O''Brien\n', X'00ff', '2024-05-17 08:30:00.5', '1', NULL)",
+			"('2', '', X'', NULL, '0', 'x')",
+		}},
+	}
+	for _, test := range tests {
+		e, _ := newFakeExporter(t, Config{}, func(string) fakeResult { return result })
+		rows, err := e.q.Query("SELECT * FROM t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newRowScanner(rows, len(result.columns))
+		encoder := newRowEncoder(nil, test.dialect)
+		var got []string
+		for rows.Next() {
+			if err := s.scan(rows); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, string(encoder.encode(s)))
+		}
+		rows.Close()
+		if len(got) != len(test.want) {
+			t.Fatalf("%T: encoded %q, want %q", test.dialect, got, test.want)
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%T: row %d = %q, want %q", test.dialect, i+1, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func BenchmarkEncodeScan(b *testing.B) {
+	for _, table := range benchTables() {
+		b.Run(table.name, func(b *testing.B) {
+			e := benchExporter(b, table)
+			b.ReportAllocs()
+			b.ResetTimer()
+			rows, err := e.q.Query("SELECT * FROM t")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer rows.Close()
+			s := newRowScanner(rows, len(table.row))
+			for rows.Next() {
+				if err := s.scan(rows); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeRow(b *testing.B) {
+	for _, table := range benchTables() {
+		b.Run(table.name, func(b *testing.B) {
+			s := &rowScanner{raw: make([][]byte, len(table.row)), kinds: make([]valueKind, len(table.row))}
+			for i, value := range table.row {
+				if value != nil {
+					s.raw[i] = value.([]byte)
+				}
+			}
+			encoder := newRowEncoder(nil, mysqlDialect{})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				encoder.encode(s)
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeChecksum(b *testing.B) {
+	for _, table := range benchTables() {
+		b.Run(table.name, func(b *testing.B) {
+			raw := make([][]byte, len(table.row))
+			for i, value := range table.row {
+				if value != nil {
+					raw[i] = value.([]byte)
+				}
+			}
+			devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer devNull.Close()
+			c := newTableChecksum(table.info.Name, table.info.columnNames(), table.info.PrimaryKey, newDigestWriter(devNull))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := c.add(raw); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncodeTable measures the write path of an export from the rows
+// read to the statements, checksums and row digests written
+func BenchmarkEncodeTable(b *testing.B) {
+	for _, table := range benchTables() {
+		b.Run(table.name, func(b *testing.B) {
+			e := benchExporter(b, table)
+			out, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer out.Close()
+			e.digests = newDigestWriter(out)
+			b.ReportAllocs()
+			b.ResetTimer()
+			if _, err := e.exportTableData(table.info, out); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
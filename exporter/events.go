@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Operations reported in Event.Operation
+const (
+	OperationExport = "export"
+	OperationClone  = "clone"
+	OperationVerify = "verify"
+)
+
+// EventType identifies what an Event reports
+type EventType int
+
+const (
+	// EventStarted is sent when a run starts
+	EventStarted EventType = iota
+	// EventTablesFound reports the number of tables and views in Tables and
+	// the estimated size of the export
+	EventTablesFound
+	// EventTableStarted is sent before a table or view is processed, with its estimated size
+	EventTableStarted
+	// EventRowsWritten reports the rows of the current table so far, every
+	// Config.ProgressRows rows
+	EventRowsWritten
+	// EventTableFinished reports the rows of a finished table, or that it was skipped
+	EventTableFinished
+	// EventWarning carries a localized warning in Message
+	EventWarning
+	// EventFileFinalized is sent for every file moved into the output directory
+	EventFileFinalized
+	// EventFinished is sent when a run completed successfully
+	EventFinished
+)
+
+// Event describes the progress of an export, clone or verify run
+type Event struct {
+	Type      EventType
+	Operation string
+	Database  string
+	Time      time.Time
+
+	// Table and IsView name the table of table events and table warnings
+	Table   string
+	IsView  bool
+	Skipped bool
+	Rows    int64
+	Tables  int
+	// Bytes counts the bytes written to data.sql for the table so far
+	Bytes int64
+
+	// EstimatedRows and EstimatedBytes are the rows and source data size
+	// expected from information_schema, which may be far off for InnoDB
+	EstimatedRows  int64
+	EstimatedBytes int64
+
+	// Path is the file of EventFileFinalized, Message the text of EventWarning
+	Path    string
+	Message string
+}
+
+// Observer receives the events of a run. Observe is called synchronously
+// from the goroutine running the exporter, so it should return quickly.
+type Observer interface {
+	Observe(event Event)
+}
+
+// defaultProgressRows is the number of rows between EventRowsWritten events
+const defaultProgressRows = 10000
+
+// emit sends an event to the observer of the configuration, if any
+func (e *Exporter) emit(event Event) {
+	if e.config.Observer == nil {
+		return
+	}
+	event.Operation = e.operation
+	event.Database = e.config.Database
+	event.Time = time.Now()
+	e.config.Observer.Observe(event)
+}
+
+// warn sends a warning about a table
+func (e *Exporter) warn(table, message string) {
+	e.emit(Event{Type: EventWarning, Table: table, Message: message})
+}
+
+// discardHandler drops all log records of an exporter without a Logger
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w *os.File
+	n *int64
+}
+
+func (c countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	*c.n += int64(n)
+	return n, err
+}
+
+func (c countingWriter) WriteString(s string) (int, error) {
+	n, err := c.w.WriteString(s)
+	*c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,125 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var eventNames = map[EventType]string{
+	EventStarted:       "started",
+	EventTablesFound:   "tables found",
+	EventTableStarted:  "table started",
+	EventRowsWritten:   "rows written",
+	EventTableFinished: "table finished",
+	EventWarning:       "warning",
+	EventFileFinalized: "file finalized",
+	EventFinished:      "finished",
+}
+
+// eventTrace renders the events of a run as one line each, leaving out
+// their times
+func eventTrace(events []Event) []string {
+	trace := make([]string, len(events))
+	for i, event := range events {
+		line := event.Operation + " " + eventNames[event.Type]
+		switch event.Type {
+		case EventTablesFound:
+			line += fmt.Sprintf(" %d", event.Tables)
+		case EventTableStarted:
+			line += " " + event.Table
+		case EventRowsWritten, EventTableFinished:
+			line += fmt.Sprintf(" %s %d", event.Table, event.Rows)
+			if event.Skipped {
+				line += " skipped"
+			}
+		case EventFileFinalized:
+			line += " " + filepath.Base(event.Path)
+		}
+		trace[i] = line
+	}
+	return trace
+}
+
+func TestExportEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	exportOutput(t, Config{Observer: recorder, ProgressRows: 1})
+	want := []string{
+		"export started",
+		"export tables found 2",
+		"export table started orders",
+		"export rows written orders 1",
+		"export rows written orders 2",
+		"export table finished orders 2",
+		"export table started users",
+		"export rows written users 1",
+		"export rows written users 2",
+		"export table finished users 2",
+		// The manifest is the last file, only then is the run finished
+		"export file finalized schema.sql",
+		"export file finalized data.sql",
+		"export file finalized " + ChecksumsFileName,
+		"export file finalized " + RowDigestsFileName,
+		"export file finalized " + ManifestFileName,
+		"export finished",
+	}
+	if got := eventTrace(recorder.events); !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, event := range recorder.events {
+		if event.Database != "shop" || event.Time.IsZero() {
+			t.Errorf("%s: database %q, time %v, want shop and a time", eventNames[event.Type], event.Database, event.Time)
+		}
+	}
+}
+
+func TestExportEventsFailed(t *testing.T) {
+	recorder := &eventRecorder{}
+	source := cloneSource("source-uuid")
+	config := Config{Database: "shop", Output: t.TempDir(), Observer: recorder, Sample: SampleFirst, InsertMode: InsertModeInsert}
+	e, _ := newFakeExporter(t, config, func(query string) fakeResult {
+		if strings.HasPrefix(query, "SHOW CREATE TABLE `users`") {
+			return fakeResult{err: errors.New("fakeDB: table dropped")}
+		}
+		return source(query)
+	})
+	e.dialect = mysqlDialect{}
+	if err := e.Execute(); err == nil {
+		t.Fatal("Execute succeeded")
+	}
+	// A failed run finalizes no file and does not finish
+	want := []string{
+		"export started",
+		"export tables found 2",
+		"export table started orders",
+		"export table finished orders 2",
+		"export table started users",
+	}
+	if got := eventTrace(recorder.events); !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCloneEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	e, _ := newFakeExporter(t, Config{Database: "shop", Sample: SampleFirst, Observer: recorder}, cloneSource("source-uuid"))
+	target, _ := newFakeTarget(t, cloneTarget("target-uuid"))
+	if err := e.Clone(target, CloneOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// orders exists in the target and is skipped without being started
+	want := []string{
+		"clone started",
+		"clone tables found 2",
+		"clone table finished orders 0 skipped",
+		"clone table started users",
+		"clone table finished users 2",
+		"clone finished",
+	}
+	if got := eventTrace(recorder.events); !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
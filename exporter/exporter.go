@@ -2,15 +2,18 @@ package exporter
 
 import (
 	"archive/zip"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/zhoucq/mysql-exporter/i18n"
 )
 
@@ -19,207 +22,560 @@ var msgs = i18n.GetCurrentMessages()
 
 // Config stores the exporter's configuration information
 type Config struct {
+	// DSN is an optional go-sql-driver DSN used as the base connection
+	// configuration. Host, Port, User, Password and Database override the
+	// corresponding DSN parts when they are set.
+	DSN string
+
 	Host     string
 	Port     int
 	User     string
 	Password string
+	// Socket is a Unix socket path used instead of Host and Port when set
+	Socket   string
 	Database string
 	MaxRows  int
 	Output   string
 	Compress bool
+
+	// Overwrite allows exporting into an output directory that is not empty,
+	// the files of a previous export are replaced
+	Overwrite bool
+	// KeepPartial keeps the staging directory of a failed export for debugging
+	KeepPartial bool
+
+	// Observer receives the progress of every run, nothing is printed without one
+	Observer Observer
+	// ProgressRows is the number of rows between EventRowsWritten events, 10000 by default
+	ProgressRows int
+	// Logger receives debug messages such as the executed queries, they are
+	// discarded without one
+	Logger *slog.Logger
+
+	// SSLMode is one of DISABLED, PREFERRED, REQUIRED, VERIFY_CA or
+	// VERIFY_IDENTITY, following the mysql client's --ssl-mode
+	SSLMode string
+	// SSLCA is a PEM file with the CA certificates used to verify the server
+	SSLCA string
+	// EnableCleartextPlugin allows the mysql_clear_password plugin, which is
+	// needed for token based authentication such as IAM database auth
+	EnableCleartextPlugin bool
+	// ServerPublicKeyPath is a PEM file with the server's RSA public key used
+	// by caching_sha2_password and sha256_password over unencrypted connections
+	ServerPublicKeyPath string
+
+	// ConnectTimeout, ReadTimeout and WriteTimeout bound establishing a
+	// connection and every network read and write, they override the DSN
+	// when set
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	// Retries is how often a connection attempt or query failing with a
+	// transient error, such as a dropped connection or a deadlock, is
+	// retried with growing delays. Table reads in primary key order continue
+	// after the last exported row. Nothing is retried while a snapshot
+	// transaction is open, since a new connection cannot see it.
+	Retries int
+
+	// SingleTransaction exports all tables from one consistent snapshot transaction
+	SingleTransaction bool
+	// SourceData records the binary log coordinates of the snapshot, see
+	// SourceDataActive and SourceDataCommented. It implies SingleTransaction.
+	SourceData int
+
+	// MaxStatementBytes limits the size of one INSERT statement so that it
+	// fits the target's max_allowed_packet. 0 uses the source's value for
+	// exports and the target's for Clone.
+	MaxStatementBytes int
+	// RowsPerStatement limits the rows of one multi-row INSERT statement,
+	// 1000 by default
+	RowsPerStatement int
+	// SingleRowInserts writes one INSERT statement per row instead of
+	// extended multi-row statements
+	SingleRowInserts bool
+
+	// InsertMode is one of InsertModeInsert, InsertModeIgnore,
+	// InsertModeReplace or InsertModeUpsert. It defaults to plain inserts.
+	InsertMode string
+	// UpsertRowAlias writes upserts with the row alias syntax of MySQL 8.0.19
+	// instead of the deprecated VALUES() function
+	UpsertRowAlias bool
+
+	// Sample is the sampling strategy choosing the exported rows of a table,
+	// see SampleFirst. MaxRows is the sample size.
+	Sample string
+	// SampleColumn is the column ordering SampleNewest, which defaults to the
+	// primary key, and the column whose values SampleStratified samples
+	SampleColumn string
+	// SamplePercent is the percentage of rows read by SamplePercent
+	SamplePercent float64
+
+	// NoData skips the table data, no data.sql is written
+	NoData bool
+	// NoCreateInfo skips the table structure, no schema.sql is written
+	NoCreateInfo bool
+	// NoRowDigests skips the row digests file, so that verify only compares
+	// row counts and table checksums of whole tables
+	NoRowDigests bool
+	// NoDrop leaves out the DROP statements before each CREATE statement
+	NoDrop bool
+	// IfNotExists creates tables and indexes only when they do not exist yet
+	IfNotExists bool
+
+	// AutoIncrement is AutoIncrementKeep, AutoIncrementReset,
+	// AutoIncrementStrip or AutoIncrementMaxExported. It defaults to reset.
+	AutoIncrement string
+
+	// MaxRowsPerSecond and MaxBytesPerSecond limit how fast rows are
+	// exported, 0 means no limit
+	MaxRowsPerSecond  int
+	MaxBytesPerSecond int64
+	// MaxLoad pauses the export while a global status variable of the source
+	// is over its limit, written as Threads_running=25[,Variable=limit...]
+	MaxLoad string
+	// CheckReplicaDSN is a replica of the source whose lag is watched, the
+	// export pauses while it is more than MaxLag behind
+	CheckReplicaDSN string
+	MaxLag          time.Duration
+	// MaxReplicaLag makes an export from a replica wait until it is at most
+	// this far behind its primary, before the export and between tables. The
+	// export fails when the lag is still too high after ReplicaLagTimeout.
+	MaxReplicaLag     time.Duration
+	ReplicaLagTimeout time.Duration
+	// StopSQLThread stops applying replicated changes while a replica source
+	// is exported, for a consistent result without a snapshot transaction
+	StopSQLThread bool
+
+	// Definer is DefinerKeep, DefinerStrip, DefinerCurrentUser or a user@host
+	// account written as the DEFINER of views
+	Definer string
+	// SQLSecurityInvoker makes views run with the privileges of the invoker
+	SQLSecurityInvoker bool
+
+	// Dialect is the database the export is written for, one of DialectMySQL,
+	// DialectPostgres or DialectSQLite. It defaults to MySQL.
+	Dialect string
 }
 
 // Exporter represents the database exporter
 type Exporter struct {
 	config Config
 	db     *sql.DB
+
+	// ctx is the context of the current run, q runs its queries on the pool
+	// or on the snapshot connection when one is open
+	ctx       context.Context
+	q         queryer
+	table     string
+	operation string
+	// tableBytes counts the bytes written for the current table
+	tableBytes  int64
+	throttler   *throttler
+	log         *slog.Logger
+	snapshot    *sql.Conn
+	replication *ReplicationInfo
+	// sqlThreadStopped is set while the export has stopped the SQL thread of
+	// a replica source
+	sqlThreadStopped bool
+	checksums        []TableChecksum
+	// digests streams the row digests of the export, nil when they are skipped
+	digests *digestWriter
+	dialect dialect
+	// statementBytes is the resolved MaxStatementBytes
+	statementBytes int
 }
 
 // New creates a new exporter instance
 func New(config Config) (*Exporter, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		config.User, config.Password, config.Host, config.Port, config.Database)
+	return NewContext(context.Background(), config)
+}
 
-	db, err := sql.Open("mysql", dsn)
+// NewContext creates a new exporter instance and stops connecting, including
+// the waits between retries, once ctx is canceled
+func NewContext(ctx context.Context, config Config) (*Exporter, error) {
+	if config.SourceData < SourceDataOff || config.SourceData > SourceDataCommented {
+		return nil, fmt.Errorf(msgs.ErrInvalidSourceData, config.SourceData)
+	}
+	if config.MaxStatementBytes < 0 {
+		return nil, fmt.Errorf(msgs.ErrInvalidStatementBytes, config.MaxStatementBytes)
+	}
+	if config.NoData && config.NoCreateInfo {
+		return nil, fmt.Errorf(msgs.ErrNothingToExport)
+	}
+	if config.AutoIncrement == "" {
+		config.AutoIncrement = AutoIncrementReset
+	}
+	if config.Logger == nil {
+		config.Logger = slog.New(discardHandler{})
+	}
+	if config.ProgressRows <= 0 {
+		config.ProgressRows = defaultProgressRows
+	}
+	if config.RowsPerStatement <= 0 {
+		config.RowsPerStatement = defaultRowsPerStatement
+	}
+	if !validAutoIncrement(config.AutoIncrement) {
+		return nil, fmt.Errorf(msgs.ErrInvalidAutoIncrement, config.AutoIncrement)
+	}
+	if err := validateDefiner(config.Definer); err != nil {
+		return nil, err
+	}
+	if err := validateSample(config); err != nil {
+		return nil, err
+	}
+	if config.InsertMode == "" {
+		config.InsertMode = InsertModeInsert
+	}
+	if !validInsertMode(config.InsertMode) {
+		return nil, fmt.Errorf(msgs.ErrInvalidInsertMode, config.InsertMode)
+	}
+	dialect, err := newDialect(config)
+	if err != nil {
+		return nil, err
+	}
+
+	db, database, err := OpenContext(ctx, config)
 	if err != nil {
-		return nil, fmt.Errorf(msgs.ErrConnectDB, err)
+		return nil, err
 	}
 
-	// Test the connection
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf(msgs.ErrPingDB, err)
+	// A database given only in the DSN is the one to work on
+	if config.Database == "" {
+		config.Database = database
+	}
+	if config.Database == "" {
+		db.Close()
+		return nil, fmt.Errorf(msgs.ErrNoDatabase)
 	}
 
-	return &Exporter{
-		config: config,
-		db:     db,
-	}, nil
+	throttler, err := newThrottler(ctx, config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	e := &Exporter{
+		config:    config,
+		db:        db,
+		throttler: throttler,
+		log:       config.Logger,
+		dialect:   dialect,
+	}
+	e.bind(context.Background())
+	return e, nil
+}
+
+// Close closes the connection pool of the exporter and the pool of the
+// replica watched by CheckReplicaDSN
+func (e *Exporter) Close() error {
+	e.throttler.close()
+	return e.db.Close()
+}
+
+// Open connects to the database described by config and returns the
+// connection pool together with the name of the selected database
+func Open(config Config) (*sql.DB, string, error) {
+	return OpenContext(context.Background(), config)
+}
+
+// OpenContext is Open that stops retrying once ctx is canceled
+func OpenContext(ctx context.Context, config Config) (*sql.DB, string, error) {
+	mysqlConfig, err := buildMySQLConfig(config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	connector, err := mysql.NewConnector(mysqlConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf(msgs.ErrConnectDB, err)
+	}
+	db := sql.OpenDB(connector)
+
+	// An export needs few connections at once: the snapshot or the streamed
+	// result, and pool queries such as the throttling checks. Idle connections
+	// are replaced before proxies or wait_timeout close them.
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+	db.SetConnMaxIdleTime(connMaxIdleTime)
+
+	// Test the connection, retrying while the server is unreachable
+	retry := &backoff{ctx: ctx, retries: config.Retries}
+	if config.Logger != nil {
+		retry.warn = func(message string) { config.Logger.Warn(message) }
+	}
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		if !retry.retry(err) {
+			db.Close()
+			if ctx.Err() != nil {
+				return nil, "", &CanceledError{Err: ctx.Err()}
+			}
+			return nil, "", fmt.Errorf(msgs.ErrPingDB, err)
+		}
+	}
+
+	return db, mysqlConfig.DBName, nil
 }
 
 // Execute performs the export operation
 func (e *Exporter) Execute() error {
-	fmt.Printf(msgs.ExportStart+"\n", e.config.Database)
+	return e.ExecuteContext(context.Background())
+}
 
-	// Ensure the output directory exists
-	if err := os.MkdirAll(e.config.Output, 0755); err != nil {
-		return fmt.Errorf(msgs.ErrCreateOutputDir, err)
-	}
+// ExecuteContext performs the export and stops once ctx is canceled. The
+// query in flight is aborted, the output files are closed and a
+// *CanceledError is returned.
+func (e *Exporter) ExecuteContext(ctx context.Context) error {
+	defer e.bind(ctx)()
+	e.operation = OperationExport
+	return e.canceled(e.execute())
+}
+
+func (e *Exporter) execute() error {
+	startedAt := time.Now()
+	e.checksums = nil
+	e.emit(Event{Type: EventStarted})
 
-	// Get all tables
-	tables, err := e.getTables()
+	// The files are written into a staging directory and only moved into the
+	// output directory once the export is complete
+	staging, err := e.prepareOutput()
 	if err != nil {
 		return err
 	}
+	if err := e.writeExport(staging, startedAt); err != nil {
+		e.discardOutput(staging)
+		return err
+	}
+	if err := e.publishOutput(staging); err != nil {
+		return err
+	}
 
-	fmt.Printf(msgs.ExportFoundTables+"\n", len(tables))
+	e.emit(Event{Type: EventFinished})
+	return nil
+}
 
-	// Create schema.sql file
-	schemaPath := filepath.Join(e.config.Output, "schema.sql")
-	schemaFile, err := os.Create(schemaPath)
+// writeExport writes all files of the export into dir
+func (e *Exporter) writeExport(dir string, startedAt time.Time) error {
+	// A replica source must have caught up before anything is read
+	if err := e.waitForReplica(); err != nil {
+		return err
+	}
+	if e.config.StopSQLThread {
+		defer e.startSQLThread()
+		if err := e.stopSQLThread(); err != nil {
+			return err
+		}
+	}
+
+	// Open the snapshot before reading any metadata so that everything is consistent
+	if e.config.SingleTransaction || e.config.SourceData != SourceDataOff {
+		defer e.endSnapshot()
+		if err := e.startSnapshot(); err != nil {
+			return err
+		}
+	}
+
+	e.statementBytes = statementLimit(e.q, e.config.MaxStatementBytes)
+
+	// Get all tables with their columns
+	tables, err := e.loadMetadata()
 	if err != nil {
-		return fmt.Errorf(msgs.ErrCreateSchemaFile, err)
+		return err
 	}
-	defer schemaFile.Close()
+	found := Event{Type: EventTablesFound, Tables: len(tables)}
+	for _, table := range tables {
+		rows, bytes := table.exported(e.config)
+		found.EstimatedRows += rows
+		found.EstimatedBytes += bytes
+	}
+	e.emit(found)
+	manifest := e.newManifest(startedAt)
+
+	// sqlFiles are the exported SQL files, outputFiles all files of the export
+	var sqlFiles []string
 
-	// Write schema file header
-	headerComment := fmt.Sprintf("-- MySQL导出 表结构导出\n"+
-		"-- 数据库: %s\n"+
-		"-- 导出时间: %s\n\n"+
-		"SET FOREIGN_KEY_CHECKS=0;\n\n",
-		e.config.Database, time.Now().Format("2006-01-02 15:04:05"))
-	if _, err := schemaFile.WriteString(headerComment); err != nil {
-		return fmt.Errorf(msgs.ErrWriteSchemaHeader, err)
+	// Create schema.sql file
+	var schemaFile *os.File
+	if !e.config.NoCreateInfo {
+		schemaPath := filepath.Join(dir, "schema.sql")
+		schemaFile, err = os.Create(schemaPath)
+		if err != nil {
+			return fmt.Errorf(msgs.ErrCreateSchemaFile, err)
+		}
+		defer schemaFile.Close()
+		sqlFiles = append(sqlFiles, schemaPath)
+
+		// Write schema file header
+		headerComment := fmt.Sprintf("-- MySQL导出 表结构导出\n"+
+			"-- 数据库: %s\n"+
+			"-- 导出时间: %s\n\n%s",
+			commentText(e.config.Database), time.Now().Format("2006-01-02 15:04:05"), e.dialect.schemaHeader())
+		if _, err := schemaFile.WriteString(headerComment); err != nil {
+			return fmt.Errorf(msgs.ErrWriteSchemaHeader, err)
+		}
 	}
 
 	// Create data.sql file
-	dataPath := filepath.Join(e.config.Output, "data.sql")
-	dataFile, err := os.Create(dataPath)
-	if err != nil {
-		return fmt.Errorf(msgs.ErrCreateDataFile, err)
+	var dataFile *os.File
+	if !e.config.NoData {
+		dataPath := filepath.Join(dir, "data.sql")
+		dataFile, err = os.Create(dataPath)
+		if err != nil {
+			return fmt.Errorf(msgs.ErrCreateDataFile, err)
+		}
+		defer dataFile.Close()
+		sqlFiles = append(sqlFiles, dataPath)
+
+		// Write data file header
+		dataHeaderComment := fmt.Sprintf("-- MySQL导出 数据导出\n"+
+			"-- 数据库: %s\n"+
+			"-- 每张表最多导出 %d 行数据\n"+
+			"-- 导出时间: %s\n\n%s",
+			commentText(e.config.Database), e.config.MaxRows, time.Now().Format("2006-01-02 15:04:05"), e.dialect.dataHeader())
+		if _, err := dataFile.WriteString(dataHeaderComment); err != nil {
+			return fmt.Errorf(msgs.ErrWriteDataHeader, err)
+		}
+	}
+
+	// The row digests are streamed to their file while the tables are read
+	var digestsPath string
+	if !e.config.NoData && !e.config.NoRowDigests {
+		digestsPath = filepath.Join(dir, RowDigestsFileName)
+		digestsFile, err := os.Create(digestsPath)
+		if err != nil {
+			return fmt.Errorf(msgs.ErrWriteChecksums, err)
+		}
+		defer digestsFile.Close()
+		e.digests = newDigestWriter(digestsFile)
+		defer func() { e.digests = nil }()
 	}
-	defer dataFile.Close()
 
-	// Write data file header
-	dataHeaderComment := fmt.Sprintf("-- MySQL导出 数据导出\n"+
-		"-- 数据库: %s\n"+
-		"-- 每张表最多导出 %d 行数据\n"+
-		"-- 导出时间: %s\n\n"+
-		"SET FOREIGN_KEY_CHECKS=0;\n\n",
-		e.config.Database, e.config.MaxRows, time.Now().Format("2006-01-02 15:04:05"))
-	if _, err := dataFile.WriteString(dataHeaderComment); err != nil {
-		return fmt.Errorf(msgs.ErrWriteDataHeader, err)
+	// The coordinates go into data.sql, or into schema.sql without data
+	if e.config.SourceData != SourceDataOff {
+		replicationFile := dataFile
+		if replicationFile == nil {
+			replicationFile = schemaFile
+		}
+		if err := e.writeReplicationInfo(replicationFile); err != nil {
+			return fmt.Errorf(msgs.ErrWriteReplicationInfo, err)
+		}
 	}
 
 	// Export structure and data for each table
 	for _, table := range tables {
-		fmt.Printf(msgs.ExportTableStart+"\n", table)
+		if err := e.ctx.Err(); err != nil {
+			return err
+		}
+		e.table = table.Name
+		if err := e.waitForReplica(); err != nil {
+			return err
+		}
+		rows, bytes := table.exported(e.config)
+		e.emit(Event{Type: EventTableStarted, Table: table.Name, EstimatedRows: rows, EstimatedBytes: bytes})
 
 		// Export table structure
-		if err := e.exportTableSchema(table, schemaFile); err != nil {
-			return err
+		if schemaFile != nil {
+			if err := e.exportTableSchema(table, schemaFile); err != nil {
+				return err
+			}
 		}
 
 		// Export table data
-		if err := e.exportTableData(table, dataFile); err != nil {
-			return err
+		rowCount := 0
+		if dataFile != nil {
+			rowCount, err = e.exportTableData(table, dataFile)
+			if err != nil {
+				return err
+			}
 		}
+		manifest.addTable(table, int64(rowCount))
 	}
+	e.table = ""
 
 	// Write file footer
-	footer := "\nSET FOREIGN_KEY_CHECKS=1;\n"
-	if _, err := schemaFile.WriteString(footer); err != nil {
-		return fmt.Errorf(msgs.ErrWriteSchemaFooter, err)
-	}
-	if _, err := dataFile.WriteString(footer); err != nil {
-		return fmt.Errorf(msgs.ErrWriteDataFooter, err)
+	if schemaFile != nil {
+		if _, err := schemaFile.WriteString(e.dialect.schemaFooter()); err != nil {
+			return fmt.Errorf(msgs.ErrWriteSchemaFooter, err)
+		}
 	}
+	outputFiles := append([]string{}, sqlFiles...)
+	if dataFile != nil {
+		if _, err := dataFile.WriteString(e.dialect.dataFooter()); err != nil {
+			return fmt.Errorf(msgs.ErrWriteDataFooter, err)
+		}
 
-	// If compression is needed, create a zip file
-	if e.config.Compress {
-		zipPath := filepath.Join(e.config.Output, "export.zip")
-		if err := e.createZipArchive(zipPath, schemaPath, dataPath); err != nil {
+		// Checksums are only meaningful when rows were exported
+		checksumsPath := filepath.Join(dir, ChecksumsFileName)
+		if err := writeChecksums(checksumsPath, e.checksums); err != nil {
 			return err
 		}
+		manifest.ChecksumsFile = ChecksumsFileName
+		outputFiles = append(outputFiles, checksumsPath)
 	}
-
-	fmt.Println(msgs.ExportComplete)
-	return nil
-}
-
-// TableInfo stores table information
-type TableInfo struct {
-	Name   string
-	IsView bool
-}
-
-// getTables gets all tables in the database
-func (e *Exporter) getTables() ([]string, error) {
-	// Use information_schema to distinguish between tables and views
-	query := fmt.Sprintf("SELECT TABLE_NAME, TABLE_TYPE FROM information_schema.TABLES WHERE TABLE_SCHEMA = '%s'", e.config.Database)
-	rows, err := e.db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf(msgs.ErrGetTables, err)
+	if e.digests != nil {
+		if err := e.digests.flush(); err != nil {
+			return fmt.Errorf(msgs.ErrWriteChecksums, err)
+		}
+		manifest.RowDigestsFile = RowDigestsFileName
+		outputFiles = append(outputFiles, digestsPath)
 	}
-	defer rows.Close()
 
-	var tables []string
-	for rows.Next() {
-		var tableName, tableType string
-		if err := rows.Scan(&tableName, &tableType); err != nil {
-			return nil, fmt.Errorf(msgs.ErrReadTableInfo, err)
+	// If compression is needed, create a zip file
+	if e.config.Compress {
+		zipPath := filepath.Join(dir, "export.zip")
+		if err := e.createZipArchive(zipPath, sqlFiles); err != nil {
+			return err
 		}
-		tables = append(tables, tableName)
+		outputFiles = append(outputFiles, zipPath)
 	}
 
-	return tables, nil
-}
-
-// isView checks if a table is a view
-func (e *Exporter) isView(tableName string) (bool, error) {
-	query := fmt.Sprintf("SELECT TABLE_TYPE FROM information_schema.TABLES WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'",
-		e.config.Database, tableName)
-	var tableType string
-	err := e.db.QueryRow(query).Scan(&tableType)
-	if err != nil {
-		return false, fmt.Errorf(msgs.ErrCheckTableType, err)
+	for _, path := range outputFiles {
+		if err := manifest.addFile(path); err != nil {
+			return err
+		}
 	}
-	return tableType == "VIEW", nil
+	manifest.FinishedAt = time.Now()
+	return e.writeManifest(dir, manifest)
 }
 
 // exportTableSchema exports the table structure
-func (e *Exporter) exportTableSchema(table string, file *os.File) error {
-	// Check if it's a view
-	isView, err := e.isView(table)
+func (e *Exporter) exportTableSchema(info *TableInfo, file *os.File) error {
+	table, isView := info.Name, info.IsView
+
+	// Get the CREATE statement for the table or view
+	tableSchema, err := e.showCreate(table, isView)
 	if err != nil {
 		return err
 	}
 
-	// Get the CREATE statement for the table or view
-	var tableSchema string
-	var query string
-
 	if isView {
-		query = fmt.Sprintf("SHOW CREATE VIEW `%s`", table)
-		var viewName, characterSet, collation string
-		if err := e.db.QueryRow(query).Scan(&viewName, &tableSchema, &characterSet, &collation); err != nil {
-			return fmt.Errorf(msgs.ErrGetViewCreateStmt, table, err)
-		}
 		// Write view structure to file
-		content := fmt.Sprintf(msgs.ViewStructure, table, table, tableSchema)
+		tableSchema = rewriteDefiner(tableSchema, e.config.Definer, e.config.SQLSecurityInvoker)
+		create, warnings := e.dialect.createView(table, tableSchema)
+		drop := e.dialect.dropView(table)
+		if e.config.NoDrop {
+			drop = ""
+		}
+		content := fmt.Sprintf(msgs.ViewStructure, commentText(e.dialect.quoteIdent(table))) +
+			e.dialectWarnings(table, warnings) + drop + create + "\n"
 		if _, err := file.WriteString(content); err != nil {
 			return fmt.Errorf(msgs.ErrWriteViewStructure, table, err)
 		}
 	} else {
-		query = fmt.Sprintf("SHOW CREATE TABLE `%s`", table)
-		var tableName string
-		if err := e.db.QueryRow(query).Scan(&tableName, &tableSchema); err != nil {
-			return fmt.Errorf(msgs.ErrGetTableCreateStmt, table, err)
-		}
-		// Reset auto-increment ID
-		tableSchema = resetAutoIncrement(tableSchema)
+		// Translate the statement, MySQL only gets the auto-increment ID reset
+		create, warnings := e.dialect.createTable(table, tableSchema)
 
 		// Write table structure to file
-		content := fmt.Sprintf(msgs.TableStructure, table, table, tableSchema)
+		drop := e.dialect.dropTable(table)
+		if e.config.NoDrop {
+			drop = ""
+		}
+		content := fmt.Sprintf(msgs.TableStructure, commentText(e.dialect.quoteIdent(table))) +
+			e.dialectWarnings(table, warnings) + drop + create + "\n"
 		if _, err := file.WriteString(content); err != nil {
 			return fmt.Errorf(msgs.ErrWriteTableStructure, table, err)
 		}
@@ -228,211 +584,219 @@ func (e *Exporter) exportTableSchema(table string, file *os.File) error {
 	return nil
 }
 
-// exportTableData exports table data
-func (e *Exporter) exportTableData(table string, file *os.File) error {
-	// Check if it's a view
-	isView, err := e.isView(table)
-	if err != nil {
-		return err
+// dialectWarnings reports the warnings of a dialect translation and renders
+// them as comments for the schema file
+func (e *Exporter) dialectWarnings(table string, warnings []string) string {
+	var b strings.Builder
+	for _, warning := range warnings {
+		e.warn(table, warning)
+		b.WriteString("-- " + commentText(warning) + "\n")
 	}
+	return b.String()
+}
+
+// showCreate returns the CREATE statement of a table or view
+func (e *Exporter) showCreate(table string, isView bool) (string, error) {
+	var name, create string
+	if isView {
+		query := "SHOW CREATE VIEW " + quoteIdent(table)
+		var characterSet, collation string
+		if err := e.q.QueryRow(query).Scan(&name, &create, &characterSet, &collation); err != nil {
+			return "", fmt.Errorf(msgs.ErrGetViewCreateStmt, table, err)
+		}
+		return create, nil
+	}
+
+	query := "SHOW CREATE TABLE " + quoteIdent(table)
+	if err := e.q.QueryRow(query).Scan(&name, &create); err != nil {
+		return "", fmt.Errorf(msgs.ErrGetTableCreateStmt, table, err)
+	}
+	return create, nil
+}
+
+// exportTableData exports table data and returns the number of exported rows
+func (e *Exporter) exportTableData(info *TableInfo, out *os.File) (int, error) {
+	table, isView := info.Name, info.IsView
+	columns, primaryKey := info.columnNames(), info.PrimaryKey
+
+	// The bytes written are counted for the progress events
+	e.tableBytes = 0
+	file := countingWriter{w: out, n: &e.tableBytes}
 
 	// Use different comments and processing methods based on whether it's a view
 	if isView {
 		// For views, only add comments, don't lock the table
-		comment := fmt.Sprintf(msgs.ViewData, table)
+		comment := fmt.Sprintf(msgs.ViewData, commentText(e.dialect.quoteIdent(table)))
 		if _, err := file.WriteString(comment); err != nil {
-			return fmt.Errorf(msgs.ErrWriteViewDataComment, table, err)
+			return 0, fmt.Errorf(msgs.ErrWriteViewDataComment, table, err)
 		}
 	} else {
 		// For regular tables, add comments and lock the table
-		comment := fmt.Sprintf(msgs.TableData, table, table)
+		comment := fmt.Sprintf(msgs.TableData, commentText(e.dialect.quoteIdent(table))) + e.dialect.lockTable(table)
 		if _, err := file.WriteString(comment); err != nil {
-			return fmt.Errorf(msgs.ErrWriteTableDataComment, table, err)
+			return 0, fmt.Errorf(msgs.ErrWriteTableDataComment, table, err)
 		}
 	}
 
-	// Get all columns of the table
-	columns, err := e.getTableColumns(table)
-	if err != nil {
-		return err
-	}
-
 	// If there are no columns, return directly
 	if len(columns) == 0 {
 		if !isView {
 			// Only regular tables need to be unlocked
-			endComment := fmt.Sprintf("UNLOCK TABLES;\n")
-			if _, err := file.WriteString(endComment); err != nil {
-				return fmt.Errorf(msgs.ErrWriteUnlockTables, table, err)
+			if _, err := file.WriteString(e.dialect.unlockTables()); err != nil {
+				return 0, fmt.Errorf(msgs.ErrWriteUnlockTables, table, err)
 			}
 		}
-		return nil
+		return 0, nil
 	}
 
-	// Get table data
-	query := fmt.Sprintf("SELECT * FROM `%s` LIMIT %d", table, e.config.MaxRows)
-	rows, err := e.db.Query(query)
-	if err != nil {
-		// 如果是视图查询失败，记录错误但继续执行
-		if isView {
-			fmt.Printf("  Warning: %v\n", fmt.Errorf(msgs.ErrReadViewData, table, err))
-			return nil
-		}
-		return fmt.Errorf(msgs.ErrQueryTableData, table, err)
+	// 准备列列表
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = e.dialect.quoteIdent(column)
 	}
-	defer rows.Close()
+	columnsList := strings.Join(quotedColumns, ", ")
+	insertInto := e.dialect.insertInto(e.config.InsertMode)
+	onConflict := e.dialect.onConflict(e.config.InsertMode, columns, primaryKey)
+	insertStart := []byte(fmt.Sprintf("%s %s (%s) VALUES ", insertInto, e.dialect.quoteIdent(table), columnsList))
+	statementEnd := []byte(onConflict + ";\n")
+	rowSeparator := []byte(",\n")
 
-	// 准备列列表
-	columnsList := "`" + strings.Join(columns, "`, `") + "`"
+	// 确定实体类型（表或视图）
+	entityType := msgs.EntityTable
+	if isView {
+		entityType = msgs.EntityView
+	}
 
-	// 遍历每一行数据
-	rowCount := 0
-	batchSize := 0
-	batchLimit := 1000 // 每批最多1000行
-
-	for rows.Next() {
-		// 创建一个动态大小的值切片
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-
-		// 扫描行数据
-		if err := rows.Scan(valuePtrs...); err != nil {
-			if isView {
-				// 如果是视图数据读取失败，记录警告并继续
-				fmt.Printf("  Warning: %v\n", fmt.Errorf(msgs.ErrReadViewData, table, err))
-				continue
+	// 视图数据不会被导入，所以只为表计算校验和
+	var checksum *TableChecksum
+	if !isView {
+		checksum = newTableChecksum(table, columns, primaryKey, e.digests)
+		defer func() {
+			checksum.finish()
+			e.checksums = append(e.checksums, *checksum)
+		}()
+	}
+
+	// 记录自增列导出的最大值。max-exported模式需要它，PostgreSQL的标识列
+	// 也不会因为插入显式的ID而前进
+	var maxID *maxValue
+	_, native := e.dialect.(mysqlDialect)
+	autoIncrement := info.AutoIncrement
+	if !isView && autoIncrement != "" && (e.config.AutoIncrement == AutoIncrementMaxExported || !native) {
+		for i, column := range columns {
+			if column == autoIncrement {
+				maxID = &maxValue{index: i, limit: integerLimit(info.Columns[i])}
 			}
-			return fmt.Errorf(msgs.ErrReadTableData, table, err)
-		}
-
-		// 处理每个值
-		valueStrings := make([]string, len(columns))
-		for i, v := range values {
-			if v == nil {
-				valueStrings[i] = "NULL"
-			} else {
-				switch value := v.(type) {
-				case []byte:
-					// 对字符串进行转义
-					valueStrings[i] = "'" + escapeString(string(value)) + "'"
-				case string:
-					valueStrings[i] = "'" + escapeString(value) + "'"
-				case time.Time:
-					valueStrings[i] = "'" + value.Format("2006-01-02 15:04:05") + "'"
-				default:
-					valueStrings[i] = fmt.Sprintf("%v", v)
-				}
+		}
+	}
+
+	// 遍历采样得到的每一行数据
+	batchSize := 0
+	batchLimit := e.config.RowsPerStatement
+	if e.config.SingleRowInserts {
+		batchLimit = 1
+	}
+	statementBytes := 0
+	var writeErr error
+
+	// 语句先写入缓冲区，缓冲区满时才写入文件
+	encoder := newRowEncoder(file, e.dialect)
+	writeRow := func(s *rowScanner) error {
+		if checksum != nil {
+			if err := checksum.add(s.raw); err != nil {
+				writeErr = fmt.Errorf(msgs.ErrWriteChecksums, err)
+				return writeErr
 			}
 		}
+		maxID.add(s.raw)
+
+		row := encoder.encode(s)
 
-		// 确定实体类型（表或视图）
-		var entityType string
-		if isView {
-			entityType = msgs.EntityView
-		} else {
-			entityType = msgs.EntityTable
+		// 如果当前批次已满，或者加入这一行后语句会超过字节限制，先结束当前INSERT语句
+		var err error
+		if batchSize > 0 && (batchSize >= batchLimit ||
+			statementBytes+len(rowSeparator)+len(row)+len(statementEnd) > e.statementBytes) {
+			err = encoder.write(statementEnd)
+			batchSize = 0 // 重置批次大小
 		}
 
-		// 如果是新批次的开始，写入完整的INSERT语句
-		if batchSize == 0 {
-			insertStmt := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)",
-				table, columnsList, strings.Join(valueStrings, ", "))
-			if _, err := file.WriteString(insertStmt); err != nil {
-				return fmt.Errorf(msgs.ErrWriteInsertStmt, entityType, table, err)
-			}
-		} else {
-			// 后续行，只写入值部分
-			insertValues := fmt.Sprintf(",\n(%s)", strings.Join(valueStrings, ", "))
-			if _, err := file.WriteString(insertValues); err != nil {
-				return fmt.Errorf(msgs.ErrWriteDataValues, entityType, table, err)
-			}
+		// 如果是新批次的开始，写入完整的INSERT语句，否则只写入值部分
+		if err == nil && batchSize == 0 {
+			err = encoder.write(insertStart, row)
+			statementBytes = len(insertStart) + len(row)
+		} else if err == nil {
+			err = encoder.write(rowSeparator, row)
+			statementBytes += len(rowSeparator) + len(row)
+		}
+		if err != nil {
+			writeErr = fmt.Errorf(msgs.ErrWriteDataValues, entityType, table, err)
+			return writeErr
 		}
 
-		rowCount++
 		batchSize++
+		return nil
+	}
 
-		// 如果当前批次已满或者是最后一行，结束当前INSERT语句并开始新批次
-		if batchSize >= batchLimit {
-			if _, err := file.WriteString(";\n"); err != nil {
-				return fmt.Errorf(msgs.ErrWriteInsertEnd, entityType, table, err)
-			}
-			batchSize = 0 // 重置批次大小
+	rowCount, err := e.scanTable(info, writeRow)
+	if err != nil {
+		// 如果是视图数据读取失败，记录警告并保留已读取的行
+		var throttled *throttleError
+		if !isView || writeErr != nil || errors.As(err, &throttled) {
+			return rowCount, err
 		}
+		e.warn(table, fmt.Sprintf(msgs.ErrReadViewData, table, err))
 	}
 
-	// 如果有未完成的批次，添加分号结束INSERT语句
+	// 如果有未完成的批次，添加分号结束INSERT语句，并写入缓冲区中剩余的内容
 	if batchSize > 0 {
-		if _, err := file.WriteString(";\n"); err != nil {
-			var entityType string
-			if isView {
-				entityType = msgs.EntityView
-			} else {
-				entityType = msgs.EntityTable
-			}
-			return fmt.Errorf(msgs.ErrWriteInsertEnd, entityType, table, err)
-		}
+		encoder.add(statementEnd)
+	}
+	if err := encoder.flush(); err != nil {
+		return rowCount, fmt.Errorf(msgs.ErrWriteDataValues, entityType, table, err)
 	}
 
 	// 只有普通表需要解锁
 	if !isView {
-		endComment := fmt.Sprintf("UNLOCK TABLES;\n")
-		if _, err := file.WriteString(endComment); err != nil {
-			return fmt.Errorf(msgs.ErrWriteUnlockTables, table, err)
+		if _, err := file.WriteString(e.dialect.unlockTables()); err != nil {
+			return rowCount, fmt.Errorf(msgs.ErrWriteUnlockTables, table, err)
 		}
 	}
 
-	var entityType string
-	if isView {
-		entityType = msgs.EntityView
-	} else {
-		entityType = msgs.EntityTable
+	// 将自增计数器设置为导出的最大ID之后。ID已达到列类型的最大值时，MySQL导入
+	// 这些行时已将计数器推到尽头，没有更大的值可设
+	if maxID != nil && maxID.found && !(native && maxID.exhausted()) {
+		if _, err := file.WriteString(e.dialect.setAutoIncrement(table, autoIncrement, maxID.max)); err != nil {
+			return rowCount, fmt.Errorf(msgs.ErrWriteAutoIncrement, table, err)
+		}
 	}
-	fmt.Printf(msgs.ExportTableRows+"\n", rowCount, entityType, table)
-	return nil
+
+	e.emit(Event{Type: EventTableFinished, Table: table, IsView: isView, Rows: int64(rowCount), Bytes: e.tableBytes})
+	return rowCount, nil
 }
 
-// getTableColumns 获取表或视图的所有列名
-func (e *Exporter) getTableColumns(table string) ([]string, error) {
-	// 检查是否为视图
-	isView, err := e.isView(table)
-	if err != nil {
-		return nil, err
-	}
+// defaultRowsPerStatement is the default of Config.RowsPerStatement
+const defaultRowsPerStatement = 1000
 
-	// 确定实体类型（表或视图）
-	entityType := msgs.EntityTable
-	if isView {
-		entityType = msgs.EntityView
-	}
+// defaultMaxAllowedPacket is used when the server's max_allowed_packet
+// cannot be read, it is the smallest default of supported servers
+const defaultMaxAllowedPacket = 4 << 20
 
-	// 查询表或视图的列信息
-	query := fmt.Sprintf("SHOW COLUMNS FROM `%s`", table)
-	rows, err := e.db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf(msgs.ErrGetTableColumns, entityType, table, err)
+// statementLimit returns the maximum size of an INSERT statement, which is
+// maxStatementBytes when it is set or the max_allowed_packet of the server q
+// is connected to
+func statementLimit(q queryer, maxStatementBytes int) int {
+	if maxStatementBytes > 0 {
+		return maxStatementBytes
 	}
-	defer rows.Close()
-
-	// 提取列名
-	var columns []string
-	for rows.Next() {
-		var field, typ, null, key, def, extra sql.NullString
-		if err := rows.Scan(&field, &typ, &null, &key, &def, &extra); err != nil {
-			return nil, fmt.Errorf(msgs.ErrReadTableColumns, entityType, table, err)
-		}
-		columns = append(columns, field.String)
+	var packet int
+	if err := q.QueryRow("SELECT @@max_allowed_packet").Scan(&packet); err != nil || packet <= 0 {
+		return defaultMaxAllowedPacket
 	}
-
-	return columns, nil
+	return packet
 }
 
 // createZipArchive 创建zip压缩文件
-func (e *Exporter) createZipArchive(zipPath, schemaPath, dataPath string) error {
-	fmt.Printf(msgs.ExportCreateZip+"\n", zipPath)
-
+func (e *Exporter) createZipArchive(zipPath string, paths []string) error {
 	// 创建zip文件
 	zipFile, err := os.Create(zipPath)
 	if err != nil {
@@ -444,14 +808,11 @@ func (e *Exporter) createZipArchive(zipPath, schemaPath, dataPath string) error
 	zipWriter := zip.NewWriter(zipFile)
 	defer zipWriter.Close()
 
-	// 添加schema.sql到zip
-	if err := addFileToZip(zipWriter, schemaPath, "schema.sql"); err != nil {
-		return err
-	}
-
-	// 添加data.sql到zip
-	if err := addFileToZip(zipWriter, dataPath, "data.sql"); err != nil {
-		return err
+	// 添加schema.sql和data.sql到zip
+	for _, path := range paths {
+		if err := addFileToZip(zipWriter, path, filepath.Base(path)); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -496,51 +857,7 @@ func addFileToZip(zipWriter *zip.Writer, filePath, zipPath string) error {
 	return nil
 }
 
-// resetAutoIncrement 重置CREATE TABLE语句中的AUTO_INCREMENT值
+// resetAutoIncrement 将CREATE TABLE语句中表选项的AUTO_INCREMENT值重置为1
 func resetAutoIncrement(createTableStmt string) string {
-	// 使用正则表达式查找并替换AUTO_INCREMENT=数字
-	// 这里使用简单的字符串替换方法
-	autoIncrIndex := strings.Index(createTableStmt, "AUTO_INCREMENT=")
-	if autoIncrIndex == -1 {
-		return createTableStmt // 没有找到AUTO_INCREMENT
-	}
-
-	// 找到AUTO_INCREMENT=后面的数字结束位置
-	endIndex := autoIncrIndex + len("AUTO_INCREMENT=")
-	for endIndex < len(createTableStmt) && (createTableStmt[endIndex] >= '0' && createTableStmt[endIndex] <= '9') {
-		endIndex++
-	}
-
-	// 替换AUTO_INCREMENT值为1
-	return createTableStmt[:autoIncrIndex] + "AUTO_INCREMENT=1" + createTableStmt[endIndex:]
-}
-
-// escapeString 转义SQL字符串中的特殊字符
-func escapeString(s string) string {
-	var result strings.Builder
-	for _, c := range s {
-		switch c {
-		case '\'':
-			result.WriteString("\\'")
-		case '"':
-			result.WriteString("\\\"")
-		case '\\':
-			result.WriteString("\\\\")
-		case '\n':
-			result.WriteString("\\n")
-		case '\r':
-			result.WriteString("\\r")
-		case '\t':
-			result.WriteString("\\t")
-		case '\b':
-			result.WriteString("\\b")
-		case '\f':
-			result.WriteString("\\f")
-		case '\x00':
-			result.WriteString("\\0")
-		default:
-			result.WriteRune(c)
-		}
-	}
-	return result.String()
+	return rewriteAutoIncrement(createTableStmt, AutoIncrementReset)
 }
@@ -0,0 +1,202 @@
+package exporter
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exportStatements exports the given number of rows of a one column table
+// and returns the INSERT statements written
+func exportStatements(t *testing.T, config Config, statementBytes, rows int) []string {
+	t.Helper()
+	info := &TableInfo{Name: "t", PrimaryKey: []string{"id"}, Columns: []ColumnInfo{{Name: "id"}}}
+	values := make([][]driver.Value, rows)
+	for i := range values {
+		values[i] = []driver.Value{[]byte(fmt.Sprint(10 + i))}
+	}
+	config.InsertMode, config.AutoIncrement = InsertModeInsert, AutoIncrementReset
+	e, _ := newFakeExporter(t, config, func(string) fakeResult {
+		return fakeResult{columns: info.columnNames(), rows: values}
+	})
+	dialect, err := newDialect(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.dialect = dialect
+	e.statementBytes = statementBytes
+
+	path := filepath.Join(t.TempDir(), "data.sql")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if _, err := e.exportTableData(info, out); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statements []string
+	for _, statement := range strings.SplitAfter(string(content), ";\n") {
+		if i := strings.Index(statement, "INSERT "); i >= 0 {
+			statements = append(statements, statement[i:])
+		}
+	}
+	return statements
+}
+
+// statementRows returns the number of rows of each statement
+func statementRows(statements []string) []int {
+	rows := make([]int, len(statements))
+	for i, statement := range statements {
+		rows[i] = strings.Count(statement, ",\n") + 1
+	}
+	return rows
+}
+
+func TestExportStatementLimits(t *testing.T) {
+	// A statement of two rows, each row takes the same number of bytes
+	two := exportStatements(t, Config{RowsPerStatement: 2}, defaultMaxAllowedPacket, 2)
+	if len(two) != 1 {
+		t.Fatalf("statements = %q, want one", two)
+	}
+	limit := len(two[0])
+
+	tests := []struct {
+		name           string
+		config         Config
+		statementBytes int
+		want           []int
+	}{
+		{"default", Config{}, defaultMaxAllowedPacket, []int{5}},
+		{"rows per statement", Config{RowsPerStatement: 2}, defaultMaxAllowedPacket, []int{2, 2, 1}},
+		{"rows at the byte limit", Config{}, limit, []int{2, 2, 1}},
+		{"one byte below the limit", Config{}, limit - 1, []int{1, 1, 1, 1, 1}},
+		{"row over the limit", Config{}, 10, []int{1, 1, 1, 1, 1}},
+		{"single row inserts", Config{SingleRowInserts: true}, defaultMaxAllowedPacket, []int{1, 1, 1, 1, 1}},
+	}
+	for _, test := range tests {
+		statements := exportStatements(t, test.config, test.statementBytes, 5)
+		if got := statementRows(statements); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: rows per statement = %v, want %v", test.name, got, test.want)
+		}
+		// Only a statement of a single row may exceed the limit
+		for _, statement := range statements {
+			if len(statement) > test.statementBytes && strings.Contains(statement, ",\n") {
+				t.Errorf("%s: %q exceeds %d bytes", test.name, statement, test.statementBytes)
+			}
+		}
+	}
+}
+
+// exportOutput runs an export of the tables orders and users and returns the
+// content of the files written into the output directory
+func exportOutput(t *testing.T, config Config) map[string]string {
+	t.Helper()
+	source := cloneSource("source-uuid")
+	config.Database, config.Output = "shop", t.TempDir()
+	config.Sample, config.InsertMode, config.AutoIncrement = SampleFirst, InsertModeInsert, AutoIncrementReset
+	e, _ := newFakeExporter(t, config, func(query string) fakeResult {
+		if strings.Contains(query, "@@max_allowed_packet") {
+			return fakeResult{columns: []string{"packet"}, rows: [][]driver.Value{{int64(1 << 20)}}}
+		}
+		if strings.HasPrefix(query, "SHOW CREATE TABLE") {
+			table := strings.Trim(strings.TrimPrefix(query, "SHOW CREATE TABLE "), "`")
+			create := "CREATE TABLE `" + table + "` (\n  `id` int NOT NULL,\n  PRIMARY KEY (`id`)\n)"
+			return fakeResult{columns: []string{"Table", "Create Table"}, rows: [][]driver.Value{{table, create}}}
+		}
+		return source(query)
+	})
+	dialect, err := newDialect(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.dialect = dialect
+	if err := e.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(config.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(config.Output, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(content)
+	}
+	return files
+}
+
+func TestExportSchemaOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		schema bool
+		data   bool
+		// contains and omits are statements expected in and missing from
+		// schema.sql
+		contains []string
+		omits    []string
+	}{
+		{"default", Config{}, true, true,
+			[]string{"DROP TABLE IF EXISTS `orders`;\nCREATE TABLE `orders`"}, []string{"IF NOT EXISTS"}},
+		{"no drop", Config{NoDrop: true}, true, true,
+			[]string{"CREATE TABLE `orders`"}, []string{"DROP TABLE"}},
+		{"if not exists", Config{IfNotExists: true}, true, true,
+			[]string{"DROP TABLE IF EXISTS `orders`;\nCREATE TABLE IF NOT EXISTS `orders`"}, nil},
+		{"no drop, if not exists", Config{NoDrop: true, IfNotExists: true}, true, true,
+			[]string{"CREATE TABLE IF NOT EXISTS `orders`", "CREATE TABLE IF NOT EXISTS `users`"}, []string{"DROP TABLE"}},
+		{"no data", Config{NoData: true}, true, false,
+			[]string{"DROP TABLE IF EXISTS `users`;\nCREATE TABLE `users`"}, nil},
+		{"no data, no drop, if not exists", Config{NoData: true, NoDrop: true, IfNotExists: true}, true, false,
+			[]string{"CREATE TABLE IF NOT EXISTS `users`"}, []string{"DROP TABLE"}},
+		{"no create info", Config{NoCreateInfo: true}, false, true, nil, nil},
+		// Without schema.sql the schema options have nothing to change
+		{"no create info, no drop, if not exists", Config{NoCreateInfo: true, NoDrop: true, IfNotExists: true}, false, true, nil, nil},
+		{"postgres, no drop, if not exists", Config{Dialect: DialectPostgres, NoDrop: true, IfNotExists: true}, true, true,
+			[]string{`CREATE TABLE IF NOT EXISTS "orders"`}, []string{"DROP TABLE"}},
+	}
+	for _, test := range tests {
+		files := exportOutput(t, test.config)
+		schema, hasSchema := files["schema.sql"]
+		data, hasData := files["data.sql"]
+		if hasSchema != test.schema || hasData != test.data {
+			t.Errorf("%s: schema.sql %v, data.sql %v, want %v, %v", test.name, hasSchema, hasData, test.schema, test.data)
+			continue
+		}
+		_, hasChecksums := files[ChecksumsFileName]
+		_, hasDigests := files[RowDigestsFileName]
+		if hasChecksums != test.data || hasDigests != test.data {
+			t.Errorf("%s: checksums %v, row digests %v, want %v", test.name, hasChecksums, hasDigests, test.data)
+		}
+		for _, statement := range test.contains {
+			if !strings.Contains(schema, statement) {
+				t.Errorf("%s: schema.sql lacks %q:\n%s", test.name, statement, schema)
+			}
+		}
+		for _, statement := range test.omits {
+			if strings.Contains(schema, statement) {
+				t.Errorf("%s: schema.sql contains %q:\n%s", test.name, statement, schema)
+			}
+		}
+		if test.data && strings.Count(data, "INSERT INTO") != 2 {
+			t.Errorf("%s: data.sql = %s, want the rows of both tables", test.name, data)
+		}
+	}
+
+	if _, err := New(Config{Database: "shop", NoData: true, NoCreateInfo: true}); err == nil {
+		t.Error("New with no data and no create info succeeded")
+	}
+}
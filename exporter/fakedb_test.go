@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// fakeResult is the answer of fakeDB to a query. The rows are returned
+// repeat times, once when repeat is 0. rowsErr fails the query after its
+// rows were read, like a connection dropped in the middle of a result.
+// types are the MySQL type names of the columns, if any.
+type fakeResult struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+	repeat  int
+	err     error
+	rowsErr error
+}
+
+// fakeDB is a database/sql driver that records the queries it is sent and
+// answers them with a function, so that the statements of an Exporter can
+// be tested without a server
+type fakeDB struct {
+	mu      sync.Mutex
+	queries []string
+	args    [][]driver.Value
+	answer  func(query string) fakeResult
+}
+
+// newFakeExporter returns an exporter running its queries on a fakeDB
+func newFakeExporter(t testing.TB, config Config, answer func(query string) fakeResult) (*Exporter, *fakeDB) {
+	f := &fakeDB{answer: answer}
+	db := sql.OpenDB(f)
+	t.Cleanup(func() { db.Close() })
+	if config.ProgressRows == 0 {
+		config.ProgressRows = 1000
+	}
+	if config.RowsPerStatement == 0 {
+		config.RowsPerStatement = defaultRowsPerStatement
+	}
+	e := &Exporter{config: config, db: db, log: slog.New(discardHandler{})}
+	e.bind(context.Background())
+	return e, f
+}
+
+// sent returns the queries recorded so far
+func (f *fakeDB) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.queries...)
+}
+
+// sentArgs returns the arguments of the queries recorded so far
+func (f *fakeDB) sentArgs() [][]driver.Value {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]driver.Value(nil), f.args...)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("fakeDB: begin") }
+
+// run records a query and returns its answer
+func (c fakeConn) run(query string, named []driver.NamedValue) fakeResult {
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+	c.db.mu.Lock()
+	c.db.queries = append(c.db.queries, query)
+	c.db.args = append(c.db.args, args)
+	c.db.mu.Unlock()
+	if c.db.answer == nil {
+		return fakeResult{err: errors.New("fakeDB: no answer")}
+	}
+	return c.db.answer(query)
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	result := c.run(query, named)
+	if result.err != nil {
+		return nil, result.err
+	}
+	return &fakeRows{columns: result.columns, rows: result.rows, repeat: max(result.repeat, 1), err: result.rowsErr, types: result.types}, nil
+}
+
+// ExecContext records statements, which only fail when their answer has an error
+func (c fakeConn) ExecContext(_ context.Context, query string, named []driver.NamedValue) (driver.Result, error) {
+	if err := c.run(query, named).err; err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+// fakeStmt is a prepared statement that is recorded every time it is run
+type fakeStmt struct {
+	conn  fakeConn
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("fakeDB: exec without context")
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("fakeDB: query without context")
+}
+
+func (s fakeStmt) ExecContext(ctx context.Context, named []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, named)
+}
+
+func (s fakeStmt) QueryContext(ctx context.Context, named []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, named)
+}
+
+type fakeRows struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+	repeat  int
+	next    int
+	err     error
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string {
+	if i < len(r.types) {
+		return r.types[i]
+	}
+	return ""
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next == len(r.rows) {
+		r.repeat--
+		r.next = 0
+	}
+	if r.repeat == 0 || len(r.rows) == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
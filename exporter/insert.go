@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How rows that already exist in the target are treated by the INSERT statements
+const (
+	// InsertModeInsert writes plain INSERT statements that fail on duplicate keys
+	InsertModeInsert = "insert"
+	// InsertModeIgnore skips rows whose key already exists
+	InsertModeIgnore = "ignore"
+	// InsertModeReplace replaces rows whose key already exists
+	InsertModeReplace = "replace"
+	// InsertModeUpsert updates the non-key columns of rows whose key already exists
+	InsertModeUpsert = "upsert"
+)
+
+// validInsertMode reports whether mode is one of the insert modes
+func validInsertMode(mode string) bool {
+	switch mode {
+	case InsertModeInsert, InsertModeIgnore, InsertModeReplace, InsertModeUpsert:
+		return true
+	}
+	return false
+}
+
+// updateColumns returns the columns that are updated on a duplicate key,
+// which are all columns that are not part of the primary key
+func updateColumns(columns, primaryKey []string) []string {
+	var update []string
+	for _, column := range columns {
+		if !contains(primaryKey, column) {
+			update = append(update, column)
+		}
+	}
+	return update
+}
+
+// mysqlRowAlias names the new row in the 8.0.19 ON DUPLICATE KEY UPDATE syntax
+const mysqlRowAlias = "new"
+
+func (d mysqlDialect) insertInto(mode string) string {
+	switch mode {
+	case InsertModeIgnore:
+		return "INSERT IGNORE INTO"
+	case InsertModeReplace:
+		return "REPLACE INTO"
+	default:
+		return "INSERT INTO"
+	}
+}
+
+func (d mysqlDialect) onConflict(mode string, columns, primaryKey []string) string {
+	if mode != InsertModeUpsert {
+		return ""
+	}
+	update := updateColumns(columns, primaryKey)
+	if len(update) == 0 {
+		// Every column is part of the key, a no-op assignment keeps duplicates from failing
+		update = columns[:1]
+	}
+
+	assignments := make([]string, len(update))
+	for i, column := range update {
+		if d.rowAlias {
+			assignments[i] = fmt.Sprintf("%s=%s.%s", d.quoteIdent(column), mysqlRowAlias, d.quoteIdent(column))
+		} else {
+			assignments[i] = fmt.Sprintf("%s=VALUES(%s)", d.quoteIdent(column), d.quoteIdent(column))
+		}
+	}
+	alias := ""
+	if d.rowAlias {
+		alias = " AS " + mysqlRowAlias
+	}
+	return alias + "\nON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+func (d *standardDialect) insertInto(mode string) string {
+	if d.postgres {
+		return "INSERT INTO"
+	}
+	switch mode {
+	case InsertModeIgnore:
+		return "INSERT OR IGNORE INTO"
+	case InsertModeReplace:
+		return "INSERT OR REPLACE INTO"
+	default:
+		return "INSERT INTO"
+	}
+}
+
+// onConflict uses ON CONFLICT, which needs the primary key as the conflict
+// target to update rows. PostgreSQL has no REPLACE, updating every non-key
+// column of the existing row has the same result.
+func (d *standardDialect) onConflict(mode string, columns, primaryKey []string) string {
+	switch {
+	case mode == InsertModeInsert || mode == "":
+		return ""
+	case mode == InsertModeReplace && !d.postgres:
+		return ""
+	case mode == InsertModeIgnore:
+		if d.postgres {
+			return "\nON CONFLICT DO NOTHING"
+		}
+		return ""
+	}
+
+	update := updateColumns(columns, primaryKey)
+	if len(primaryKey) == 0 || len(update) == 0 {
+		return "\nON CONFLICT DO NOTHING"
+	}
+	key := make([]string, len(primaryKey))
+	for i, column := range primaryKey {
+		key[i] = d.quoteIdent(column)
+	}
+	assignments := make([]string, len(update))
+	for i, column := range update {
+		assignments[i] = fmt.Sprintf("%s = excluded.%s", d.quoteIdent(column), d.quoteIdent(column))
+	}
+	return fmt.Sprintf("\nON CONFLICT (%s) DO UPDATE SET %s", strings.Join(key, ", "), strings.Join(assignments, ", "))
+}
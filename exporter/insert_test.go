@@ -0,0 +1,55 @@
+package exporter
+
+import "testing"
+
+func TestInsertModes(t *testing.T) {
+	columns := []string{"id", "name"}
+	key := []string{"id"}
+	tests := []struct {
+		dialect    string
+		rowAlias   bool
+		mode       string
+		primaryKey []string
+		insertInto string
+		onConflict string
+	}{
+		{DialectMySQL, false, InsertModeInsert, key, "INSERT INTO", ""},
+		{DialectMySQL, false, InsertModeIgnore, key, "INSERT IGNORE INTO", ""},
+		{DialectMySQL, false, InsertModeReplace, key, "REPLACE INTO", ""},
+		{DialectMySQL, false, InsertModeUpsert, key, "INSERT INTO", "\nON DUPLICATE KEY UPDATE `name`=VALUES(`name`)"},
+		{DialectMySQL, true, InsertModeUpsert, key, "INSERT INTO", " AS new\nON DUPLICATE KEY UPDATE `name`=new.`name`"},
+		// Without a primary key unique keys decide, so every column is updated
+		{DialectMySQL, false, InsertModeUpsert, nil, "INSERT INTO", "\nON DUPLICATE KEY UPDATE `id`=VALUES(`id`), `name`=VALUES(`name`)"},
+		{DialectMySQL, false, InsertModeReplace, nil, "REPLACE INTO", ""},
+		{DialectMySQL, false, InsertModeUpsert, columns, "INSERT INTO", "\nON DUPLICATE KEY UPDATE `id`=VALUES(`id`)"},
+
+		{DialectPostgres, false, InsertModeInsert, key, "INSERT INTO", ""},
+		{DialectPostgres, false, InsertModeIgnore, key, "INSERT INTO", "\nON CONFLICT DO NOTHING"},
+		{DialectPostgres, false, InsertModeReplace, key, "INSERT INTO", "\nON CONFLICT (\"id\") DO UPDATE SET \"name\" = excluded.\"name\""},
+		{DialectPostgres, false, InsertModeUpsert, key, "INSERT INTO", "\nON CONFLICT (\"id\") DO UPDATE SET \"name\" = excluded.\"name\""},
+		{DialectPostgres, false, InsertModeIgnore, nil, "INSERT INTO", "\nON CONFLICT DO NOTHING"},
+		{DialectPostgres, false, InsertModeReplace, nil, "INSERT INTO", "\nON CONFLICT DO NOTHING"},
+		{DialectPostgres, false, InsertModeUpsert, nil, "INSERT INTO", "\nON CONFLICT DO NOTHING"},
+		{DialectPostgres, false, InsertModeUpsert, columns, "INSERT INTO", "\nON CONFLICT DO NOTHING"},
+
+		{DialectSQLite, false, InsertModeInsert, key, "INSERT INTO", ""},
+		{DialectSQLite, false, InsertModeIgnore, key, "INSERT OR IGNORE INTO", ""},
+		{DialectSQLite, false, InsertModeReplace, key, "INSERT OR REPLACE INTO", ""},
+		{DialectSQLite, false, InsertModeUpsert, key, "INSERT INTO", "\nON CONFLICT (\"id\") DO UPDATE SET \"name\" = excluded.\"name\""},
+		{DialectSQLite, false, InsertModeIgnore, nil, "INSERT OR IGNORE INTO", ""},
+		{DialectSQLite, false, InsertModeReplace, nil, "INSERT OR REPLACE INTO", ""},
+		{DialectSQLite, false, InsertModeUpsert, nil, "INSERT INTO", "\nON CONFLICT DO NOTHING"},
+	}
+	for _, test := range tests {
+		d, err := newDialect(Config{Dialect: test.dialect, UpsertRowAlias: test.rowAlias})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.insertInto(test.mode); got != test.insertInto {
+			t.Errorf("%s %s key %q: insertInto = %q, want %q", test.dialect, test.mode, test.primaryKey, got, test.insertInto)
+		}
+		if got := d.onConflict(test.mode, columns, test.primaryKey); got != test.onConflict {
+			t.Errorf("%s %s key %q: onConflict = %q, want %q", test.dialect, test.mode, test.primaryKey, got, test.onConflict)
+		}
+	}
+}
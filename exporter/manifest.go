@@ -0,0 +1,177 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestFileName is the name of the machine-readable manifest written next to the export
+const ManifestFileName = "manifest.json"
+
+// ManifestVersion is the version of the manifest format
+const ManifestVersion = 1
+
+// Manifest describes an export for downstream automation
+type Manifest struct {
+	Version       int              `json:"version"`
+	ServerVersion string           `json:"server_version"`
+	Database      string           `json:"database"`
+	Options       ManifestOptions  `json:"options"`
+	StartedAt     time.Time        `json:"started_at"`
+	FinishedAt    time.Time        `json:"finished_at"`
+	Replication   *ReplicationInfo `json:"replication,omitempty"`
+
+	Tables             []ManifestTable `json:"tables"`
+	ExportedTotalRows  int64           `json:"exported_total_rows"`
+	EstimatedTotalRows int64           `json:"estimated_total_rows"`
+
+	Files []ManifestFile `json:"files"`
+	// ChecksumsFile names the file with the table checksums used by Verify
+	ChecksumsFile string `json:"checksums_file,omitempty"`
+	// RowDigestsFile names the file with the row digests used by Verify,
+	// empty when the export was made without them
+	RowDigestsFile string `json:"row_digests_file,omitempty"`
+}
+
+// ManifestOptions records the options the export was made with. Credentials are never included.
+type ManifestOptions struct {
+	Host              string  `json:"host,omitempty"`
+	Port              int     `json:"port,omitempty"`
+	Socket            string  `json:"socket,omitempty"`
+	User              string  `json:"user,omitempty"`
+	MaxRows           int     `json:"max_rows"`
+	Compress          bool    `json:"compress"`
+	SingleTransaction bool    `json:"single_transaction"`
+	SourceData        int     `json:"source_data"`
+	Dialect           string  `json:"dialect,omitempty"`
+	MaxStatementBytes int     `json:"max_statement_bytes"`
+	RowsPerStatement  int     `json:"rows_per_statement"`
+	SingleRowInserts  bool    `json:"single_row_inserts,omitempty"`
+	InsertMode        string  `json:"insert_mode"`
+	Sample            string  `json:"sample,omitempty"`
+	SampleColumn      string  `json:"sample_column,omitempty"`
+	SamplePercent     float64 `json:"sample_percent,omitempty"`
+	AutoIncrement     string  `json:"auto_increment"`
+	NoData            bool    `json:"no_data,omitempty"`
+	NoCreateInfo      bool    `json:"no_create_info,omitempty"`
+	NoRowDigests      bool    `json:"no_row_digests,omitempty"`
+}
+
+// ManifestTable describes one exported table or view
+type ManifestTable struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	ExportedRows  int64  `json:"exported_rows"`
+	EstimatedRows int64  `json:"estimated_rows"`
+}
+
+// ManifestFile describes one output file
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ReadManifest loads a manifest written by an export
+func ReadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadManifest, path, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadManifest, path, err)
+	}
+	return &manifest, nil
+}
+
+// newManifest starts the manifest of an export
+func (e *Exporter) newManifest(startedAt time.Time) *Manifest {
+	return &Manifest{
+		Version:       ManifestVersion,
+		ServerVersion: e.serverVersion(),
+		Database:      e.config.Database,
+		Options: ManifestOptions{
+			Host:              e.config.Host,
+			Port:              e.config.Port,
+			Socket:            e.config.Socket,
+			User:              e.config.User,
+			MaxRows:           e.config.MaxRows,
+			Compress:          e.config.Compress,
+			SingleTransaction: e.config.SingleTransaction,
+			SourceData:        e.config.SourceData,
+			Dialect:           e.config.Dialect,
+			MaxStatementBytes: e.statementBytes,
+			RowsPerStatement:  e.config.RowsPerStatement,
+			SingleRowInserts:  e.config.SingleRowInserts,
+			InsertMode:        e.config.InsertMode,
+			Sample:            e.config.Sample,
+			SampleColumn:      e.config.SampleColumn,
+			SamplePercent:     e.config.SamplePercent,
+			AutoIncrement:     e.config.AutoIncrement,
+			NoData:            e.config.NoData,
+			NoCreateInfo:      e.config.NoCreateInfo,
+			NoRowDigests:      e.config.NoRowDigests,
+		},
+		StartedAt:   startedAt,
+		Replication: e.replication,
+		Tables:      []ManifestTable{},
+		Files:       []ManifestFile{},
+	}
+}
+
+// addTable records an exported table in the manifest
+func (m *Manifest) addTable(table *TableInfo, exportedRows int64) {
+	tableType := "table"
+	if table.IsView {
+		tableType = "view"
+	}
+	m.Tables = append(m.Tables, ManifestTable{
+		Name:          table.Name,
+		Type:          tableType,
+		ExportedRows:  exportedRows,
+		EstimatedRows: table.EstimatedRows,
+	})
+	m.ExportedTotalRows += exportedRows
+	m.EstimatedTotalRows += table.EstimatedRows
+}
+
+// addFile records the size and checksum of an output file in the manifest
+func (m *Manifest) addFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrOpenFile, path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrChecksumFile, path, err)
+	}
+	m.Files = append(m.Files, ManifestFile{
+		Name:   filepath.Base(path),
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	})
+	return nil
+}
+
+// writeManifest writes the manifest into the directory of the export
+func (e *Exporter) writeManifest(dir string, manifest *Manifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf(msgs.ErrWriteManifest, err)
+	}
+	content = append(content, '\n')
+	if err := os.WriteFile(filepath.Join(dir, ManifestFileName), content, 0644); err != nil {
+		return fmt.Errorf(msgs.ErrWriteManifest, err)
+	}
+	return nil
+}
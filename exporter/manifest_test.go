@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestAddFile(t *testing.T) {
+	dir := t.TempDir()
+	files := []struct {
+		name    string
+		content string
+		want    ManifestFile
+	}{
+		{"empty.sql", "", ManifestFile{"empty.sql", 0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}},
+		{"schema.sql", "hello\n", ManifestFile{"schema.sql", 6, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"}},
+	}
+	m := &Manifest{}
+	for _, file := range files {
+		path := filepath.Join(dir, file.name)
+		if err := os.WriteFile(path, []byte(file.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.addFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []ManifestFile{files[0].want, files[1].want}
+	if !reflect.DeepEqual(m.Files, want) {
+		t.Errorf("files = %+v, want %+v", m.Files, want)
+	}
+	if err := m.addFile(filepath.Join(dir, "missing.sql")); err == nil {
+		t.Error("addFile of a missing file succeeded")
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	config := Config{Host: "db", Port: 3306, User: "u", Password: "secret", Database: "shop", MaxRows: 10, InsertMode: InsertModeInsert}
+	e, _ := newFakeExporter(t, config, func(string) fakeResult {
+		return fakeResult{columns: []string{"VERSION()"}, rows: [][]driver.Value{{"8.0.36"}}}
+	})
+	started := time.Date(2024, 5, 17, 8, 30, 0, 0, time.UTC)
+	m := e.newManifest(started)
+	m.FinishedAt = started.Add(time.Minute)
+	m.addTable(&TableInfo{Name: "orders", EstimatedRows: 100}, 10)
+	m.addTable(&TableInfo{Name: "recent", IsView: true}, 0)
+
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data.sql")
+	if err := os.WriteFile(data, []byte("INSERT INTO `orders` VALUES (1);\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.addFile(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.writeManifest(dir, m); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "secret") {
+		t.Errorf("manifest contains the password: %s", content)
+	}
+	read, err := ReadManifest(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, m) {
+		t.Errorf("ReadManifest = %+v, want %+v", read, m)
+	}
+	if read.ServerVersion != "8.0.36" || read.ExportedTotalRows != 10 || read.EstimatedTotalRows != 100 {
+		t.Errorf("manifest = %s, %d rows of %d, want 8.0.36, 10 of 100", read.ServerVersion, read.ExportedTotalRows, read.EstimatedTotalRows)
+	}
+	if want := []string{"table", "view"}; read.Tables[0].Type != want[0] || read.Tables[1].Type != want[1] {
+		t.Errorf("tables = %+v, want a table and a view", read.Tables)
+	}
+	if len(read.Files) != 1 || read.Files[0].Size != 33 {
+		t.Errorf("files = %+v, want data.sql of 33 bytes", read.Files)
+	}
+}
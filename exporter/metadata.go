@@ -0,0 +1,149 @@
+package exporter
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// TableInfo describes a table or view of the database, as read from
+// information_schema when a run starts
+type TableInfo struct {
+	Name   string
+	IsView bool
+	// Columns are the columns in table order
+	Columns []ColumnInfo
+	// PrimaryKey names the primary key columns in index order, empty without one
+	PrimaryKey []string
+	// AutoIncrement names the auto-increment column, empty without one
+	AutoIncrement string
+	// EstimatedRows and DataLength are the TABLE_ROWS and DATA_LENGTH
+	// statistics, 0 for views. InnoDB row counts are estimates.
+	EstimatedRows int64
+	DataLength    int64
+}
+
+// ColumnInfo describes a column of a table or view
+type ColumnInfo struct {
+	Name string
+	// DataType is the type name such as varchar, ColumnType the full type
+	// such as varchar(255)
+	DataType   string
+	ColumnType string
+	Nullable   bool
+	// Key is PRI, UNI or MUL for indexed columns, like in SHOW COLUMNS
+	Key string
+}
+
+// columnNames returns the names of the columns in table order
+func (t *TableInfo) columnNames() []string {
+	names := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// exported estimates the rows and source bytes an export reads from the
+// table, taking the row limit and percent sampling into account
+func (t *TableInfo) exported(config Config) (int64, int64) {
+	rows := t.EstimatedRows
+	if config.Sample == SamplePercent {
+		rows = int64(float64(rows) * config.SamplePercent / 100)
+	}
+	if config.MaxRows > 0 && rows > int64(config.MaxRows) {
+		rows = int64(config.MaxRows)
+	}
+	if t.EstimatedRows <= 0 {
+		return rows, 0
+	}
+	return rows, int64(float64(t.DataLength) * float64(rows) / float64(t.EstimatedRows))
+}
+
+// loadMetadata reads all tables and views of the database together with
+// their columns, primary keys and statistics. Three bulk queries replace the
+// per table queries, which matters with thousands of tables over a slow link.
+func (e *Exporter) loadMetadata() ([]*TableInfo, error) {
+	rows, err := e.q.Query("SELECT TABLE_NAME, TABLE_TYPE, TABLE_ROWS, DATA_LENGTH FROM information_schema.TABLES "+
+		"WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME", e.config.Database)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrGetTables, err)
+	}
+	defer rows.Close()
+
+	var tables []*TableInfo
+	byName := map[string]*TableInfo{}
+	for rows.Next() {
+		var name, tableType string
+		var tableRows, dataLength sql.NullInt64
+		if err := rows.Scan(&name, &tableType, &tableRows, &dataLength); err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadTableInfo, err)
+		}
+		table := &TableInfo{
+			Name:          name,
+			IsView:        tableType == "VIEW",
+			EstimatedRows: tableRows.Int64,
+			DataLength:    dataLength.Int64,
+		}
+		tables = append(tables, table)
+		byName[name] = table
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadTableInfo, err)
+	}
+	rows.Close()
+
+	rows, err = e.q.Query("SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, EXTRA "+
+		"FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME, ORDINAL_POSITION", e.config.Database)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrGetColumns, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, extra string
+		var column ColumnInfo
+		var nullable string
+		if err := rows.Scan(&tableName, &column.Name, &column.DataType, &column.ColumnType, &nullable, &column.Key, &extra); err != nil {
+			return nil, fmt.Errorf(msgs.ErrGetColumns, err)
+		}
+		table, ok := byName[tableName]
+		if !ok {
+			// Created after the tables were listed
+			continue
+		}
+		column.Nullable = nullable == "YES"
+		table.Columns = append(table.Columns, column)
+		if strings.Contains(strings.ToLower(extra), "auto_increment") {
+			table.AutoIncrement = column.Name
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(msgs.ErrGetColumns, err)
+	}
+	rows.Close()
+
+	// Primary key columns come in index order from STATISTICS. COLUMN_KEY
+	// would list them in table order, and marks the first unique index of a
+	// table without a primary key as PRI too.
+	rows, err = e.q.Query("SELECT TABLE_NAME, COLUMN_NAME FROM information_schema.STATISTICS "+
+		"WHERE TABLE_SCHEMA = ? AND INDEX_NAME = 'PRIMARY' ORDER BY TABLE_NAME, SEQ_IN_INDEX", e.config.Database)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrGetPrimaryKeys, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, column string
+		if err := rows.Scan(&tableName, &column); err != nil {
+			return nil, fmt.Errorf(msgs.ErrGetPrimaryKeys, err)
+		}
+		if table, ok := byName[tableName]; ok {
+			table.PrimaryKey = append(table.PrimaryKey, column)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(msgs.ErrGetPrimaryKeys, err)
+	}
+	return tables, nil
+}
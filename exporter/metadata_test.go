@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadMetadataPrimaryKeyOrder(t *testing.T) {
+	e, _ := newFakeExporter(t, Config{Database: "shop"}, func(query string) fakeResult {
+		switch {
+		case strings.Contains(query, "information_schema.TABLES"):
+			return fakeResult{columns: []string{"TABLE_NAME", "TABLE_TYPE", "TABLE_ROWS", "DATA_LENGTH"}, rows: [][]driver.Value{
+				{"order_items", "BASE TABLE", int64(10), int64(16384)},
+				{"tokens", "BASE TABLE", int64(5), int64(16384)},
+			}}
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			return fakeResult{columns: []string{"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "IS_NULLABLE", "COLUMN_KEY", "EXTRA"}, rows: [][]driver.Value{
+				{"order_items", "line", "int", "int", "NO", "PRI", ""},
+				{"order_items", "order_id", "int", "int", "NO", "PRI", ""},
+				{"order_items", "sku", "varchar", "varchar(20)", "YES", "", ""},
+				// A unique NOT NULL column is shown as PRI without a primary key
+				{"tokens", "token", "char", "char(32)", "NO", "PRI", ""},
+			}}
+		case strings.Contains(query, "information_schema.STATISTICS"):
+			return fakeResult{columns: []string{"TABLE_NAME", "COLUMN_NAME"}, rows: [][]driver.Value{
+				{"order_items", "order_id"},
+				{"order_items", "line"},
+			}}
+		}
+		return fakeResult{}
+	})
+
+	tables, err := e.loadMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("%d tables, want 2", len(tables))
+	}
+	if want := []string{"order_id", "line"}; !reflect.DeepEqual(tables[0].PrimaryKey, want) {
+		t.Errorf("primary key = %q, want %q", tables[0].PrimaryKey, want)
+	}
+	if want := []string{"line", "order_id", "sku"}; !reflect.DeepEqual(tables[0].columnNames(), want) {
+		t.Errorf("columns = %q, want %q", tables[0].columnNames(), want)
+	}
+	if tables[1].PrimaryKey != nil {
+		t.Errorf("primary key of tokens = %q, want none", tables[1].PrimaryKey)
+	}
+}
@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// stagingPrefix starts the names of the staging directories created inside
+// the output directory
+const stagingPrefix = ".mysql-exporter-"
+
+// exportFiles are the files an export may write, in the order they are moved
+// into the output directory. The manifest comes last, so an output directory
+// with a manifest always holds a complete export.
+var exportFiles = []string{"schema.sql", "data.sql", ChecksumsFileName, RowDigestsFileName, "export.zip", ManifestFileName}
+
+// prepareOutput creates the output directory and a staging directory inside
+// it. An output directory that holds anything but staging directories is
+// only used when Overwrite is set.
+func (e *Exporter) prepareOutput() (string, error) {
+	if err := os.MkdirAll(e.config.Output, 0755); err != nil {
+		return "", fmt.Errorf(msgs.ErrCreateOutputDir, err)
+	}
+	entries, err := os.ReadDir(e.config.Output)
+	if err != nil {
+		return "", fmt.Errorf(msgs.ErrCreateOutputDir, err)
+	}
+	if !e.config.Overwrite {
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), stagingPrefix) {
+				return "", fmt.Errorf(msgs.ErrOutputNotEmpty, e.config.Output)
+			}
+		}
+	}
+
+	// The staging directory is on the same file system, so the files can be renamed into place
+	staging, err := os.MkdirTemp(e.config.Output, stagingPrefix)
+	if err != nil {
+		return "", fmt.Errorf(msgs.ErrCreateOutputDir, err)
+	}
+	return staging, nil
+}
+
+// previousDir is the directory inside the staging directory that holds the
+// files of the previous export while the new files are moved into place
+const previousDir = "previous"
+
+// rename moves a file, tests replace it to make a move fail
+var rename = os.Rename
+
+// publishOutput flushes the files of the staging directory to disk and
+// renames them into the output directory. The files of a previous export are
+// moved aside first and only removed once every new file is in place, a
+// failed rename moves them back. A failed export is discarded like one that
+// failed while writing.
+func (e *Exporter) publishOutput(staging string) error {
+	// Every file reaches the disk before the output directory changes
+	var staged []string
+	for _, name := range exportFiles {
+		source := filepath.Join(staging, name)
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			continue
+		}
+		if err := syncFile(source); err != nil {
+			e.discardOutput(staging)
+			return fmt.Errorf(msgs.ErrPublishOutput, source, err)
+		}
+		staged = append(staged, name)
+	}
+
+	// The old manifest goes first, so the directory does not claim to hold a
+	// complete export while its files are replaced. Files of a previous export
+	// that this one does not write would be mistaken for its own, so they are
+	// moved aside too.
+	previous := filepath.Join(staging, previousDir)
+	if err := os.Mkdir(previous, 0755); err != nil {
+		e.discardOutput(staging)
+		return fmt.Errorf(msgs.ErrPublishOutput, previous, err)
+	}
+	var moved []string
+	for _, name := range append([]string{ManifestFileName}, exportFiles...) {
+		if slices.Contains(moved, name) {
+			continue
+		}
+		path := filepath.Join(e.config.Output, name)
+		if err := rename(path, filepath.Join(previous, name)); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return e.restoreOutput(staging, moved, nil, fmt.Errorf(msgs.ErrPublishOutput, path, err))
+		}
+		moved = append(moved, name)
+	}
+
+	var published []string
+	for _, name := range staged {
+		source := filepath.Join(staging, name)
+		if err := rename(source, filepath.Join(e.config.Output, name)); err != nil {
+			return e.restoreOutput(staging, moved, published, fmt.Errorf(msgs.ErrPublishOutput, source, err))
+		}
+		published = append(published, name)
+	}
+	syncDir(e.config.Output)
+
+	for _, name := range published {
+		e.emit(Event{Type: EventFileFinalized, Path: filepath.Join(e.config.Output, name)})
+	}
+	return os.RemoveAll(staging)
+}
+
+// restoreOutput undoes a failed publishOutput: the new files go back into the
+// staging directory and the files of the previous export back into the
+// output directory, its manifest last. The staging directory is only kept
+// when a previous file could not be moved back.
+func (e *Exporter) restoreOutput(staging string, moved, published []string, err error) error {
+	for _, name := range published {
+		rename(filepath.Join(e.config.Output, name), filepath.Join(staging, name))
+	}
+	previous := filepath.Join(staging, previousDir)
+	for i := len(moved) - 1; i >= 0; i-- {
+		path := filepath.Join(e.config.Output, moved[i])
+		if restoreErr := rename(filepath.Join(previous, moved[i]), path); restoreErr != nil {
+			return errors.Join(err, fmt.Errorf(msgs.ErrRestoreOutput, previous, restoreErr))
+		}
+	}
+	syncDir(e.config.Output)
+	e.discardOutput(staging)
+	return err
+}
+
+// discardOutput removes the staging directory of a failed export, or keeps
+// it for debugging when KeepPartial is set
+func (e *Exporter) discardOutput(staging string) {
+	if e.config.KeepPartial {
+		e.emit(Event{Type: EventWarning, Path: staging, Message: fmt.Sprintf(msgs.ExportPartialKept, staging)})
+		return
+	}
+	os.RemoveAll(staging)
+}
+
+// syncFile flushes a written file to disk
+func syncFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
+
+// syncDir flushes the entries of a directory to disk. Not every platform
+// can sync directories, which only weakens the guarantee after a crash.
+func syncDir(path string) {
+	if dir, err := os.Open(path); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+}
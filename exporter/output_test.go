@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// outputContent returns the files of a directory with their content,
+// directories are listed with an empty content
+func outputContent(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			files[entry.Name()+"/"] = ""
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(content)
+	}
+	return files
+}
+
+// stagePublish creates an output directory holding a previous export and a
+// staging directory with the files of a new one
+func stagePublish(t *testing.T, config Config) (*Exporter, string, map[string]string) {
+	t.Helper()
+	config.Output, config.Overwrite = t.TempDir(), true
+	old := map[string]string{"schema.sql": "old schema", "data.sql": "old data", "export.zip": "old zip", ManifestFileName: "old manifest"}
+	for name, content := range old {
+		writeTestFile(t, config.Output, name, content)
+	}
+	recorder := &eventRecorder{}
+	config.Observer = recorder
+	e, _ := newFakeExporter(t, config, nil)
+	staging, err := e.prepareOutput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"schema.sql": "new schema", "data.sql": "new data", ManifestFileName: "new manifest"} {
+		writeTestFile(t, staging, name, content)
+	}
+	return e, staging, old
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPublishOutput(t *testing.T) {
+	e, staging, _ := stagePublish(t, Config{})
+	if err := e.publishOutput(staging); err != nil {
+		t.Fatal(err)
+	}
+	// The zip of the previous export is not mistaken for one of this export
+	want := map[string]string{"schema.sql": "new schema", "data.sql": "new data", ManifestFileName: "new manifest"}
+	if got := outputContent(t, e.config.Output); !reflect.DeepEqual(got, want) {
+		t.Errorf("output = %v, want %v", got, want)
+	}
+
+	var paths []string
+	for _, event := range e.config.Observer.(*eventRecorder).events {
+		if event.Type == EventFileFinalized {
+			paths = append(paths, filepath.Base(event.Path))
+		}
+	}
+	if want := []string{"schema.sql", "data.sql", ManifestFileName}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("files finalized = %v, want %v with the manifest last", paths, want)
+	}
+}
+
+func TestPublishOutputRollsBack(t *testing.T) {
+	tests := []struct {
+		name string
+		// fail is the rename that fails, counted from 1. The old manifest,
+		// schema.sql, data.sql, the missing checksums and row digests and
+		// export.zip are moved aside, then the three new files are moved in.
+		fail int
+	}{
+		{"moving the old manifest aside", 1},
+		{"moving an old file aside", 3},
+		{"moving the first new file in", 7},
+		{"moving the new manifest in", 9},
+	}
+	for _, test := range tests {
+		for _, keep := range []bool{false, true} {
+			e, staging, old := stagePublish(t, Config{KeepPartial: keep})
+			renames := 0
+			rename = func(from, to string) error {
+				renames++
+				if renames == test.fail {
+					return errors.New("rename failed")
+				}
+				return os.Rename(from, to)
+			}
+			err := e.publishOutput(staging)
+			rename = os.Rename
+			if err == nil {
+				t.Fatalf("%s: publishOutput succeeded", test.name)
+			}
+
+			// The previous export is untouched, the failed one is
+			// discarded or kept like any failed export
+			want := map[string]string{}
+			for name, content := range old {
+				want[name] = content
+			}
+			if keep {
+				want[filepath.Base(staging)+"/"] = ""
+			}
+			if got := outputContent(t, e.config.Output); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s, keep %v: output = %v, want %v", test.name, keep, got, want)
+			}
+			if !keep {
+				continue
+			}
+			kept := outputContent(t, staging)
+			if kept["data.sql"] != "new data" || kept[ManifestFileName] != "new manifest" {
+				t.Errorf("%s: staging = %v, want the new files", test.name, kept)
+			}
+		}
+	}
+}
+
+func TestPublishOutputRestoreFails(t *testing.T) {
+	e, staging, _ := stagePublish(t, Config{})
+	renames := 0
+	rename = func(from, to string) error {
+		renames++
+		// The new schema.sql fails, then moving the old files back
+		if renames >= 7 {
+			return errors.New("rename failed")
+		}
+		return os.Rename(from, to)
+	}
+	defer func() { rename = os.Rename }()
+	if err := e.publishOutput(staging); err == nil {
+		t.Fatal("publishOutput succeeded")
+	}
+	// The files of the previous export that were moved aside are kept
+	previous := outputContent(t, filepath.Join(staging, previousDir))
+	if previous[ManifestFileName] != "old manifest" || previous["data.sql"] != "old data" {
+		t.Errorf("previous = %v, want the old files", previous)
+	}
+}
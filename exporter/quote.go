@@ -0,0 +1,24 @@
+package exporter
+
+import "strings"
+
+// quoteIdent quotes a MySQL identifier. Backticks inside the name are
+// doubled, so any table or column name is safe to put into a statement.
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quoteIdents quotes a list of identifiers and joins them with commas
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// commentText makes text safe to write after "--". A line break in a table
+// name would otherwise end the comment and turn the rest into a statement.
+func commentText(text string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(text)
+}
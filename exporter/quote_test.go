@@ -0,0 +1,196 @@
+package exporter
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hostileNames are identifiers that break statements unless they are quoted
+var hostileNames = []string{
+	"a`b",
+	"`",
+	"it's",
+	`say "hi"`,
+	"two\nlines",
+	"x -- y",
+	"x # y",
+	"x /* y",
+	"x; DROP TABLE y",
+	"名前",
+}
+
+func TestQuoteIdent(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"orders", "`orders`"},
+		{"a`b", "`a``b`"},
+		{"``", "``````"},
+		{"it's", "`it's`"},
+		{"two\nlines", "`two\nlines`"},
+		{"", "``"},
+	}
+	for _, test := range tests {
+		if got := quoteIdent(test.name); got != test.want {
+			t.Errorf("quoteIdent(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestQuoteIdents(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"id"}, "`id`"},
+		{[]string{"tenant", "a`b", "c, d"}, "`tenant`, `a``b`, `c, d`"},
+	}
+	for _, test := range tests {
+		if got := quoteIdents(test.names); got != test.want {
+			t.Errorf("quoteIdents(%q) = %q, want %q", test.names, got, test.want)
+		}
+	}
+}
+
+func TestCommentText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "plain"},
+		{"two\nlines", "two lines"},
+		{"windows\r\nline", "windows line"},
+		{"old\rmac", "old mac"},
+		{"a\n\nb", "a  b"},
+		{"-- already a comment", "-- already a comment"},
+	}
+	for _, test := range tests {
+		if got := commentText(test.text); got != test.want {
+			t.Errorf("commentText(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+// checkIdents fails when the identifiers of query are not want, or when any
+// part of it would be read as a comment or a string
+func checkIdents(t *testing.T, query string, want []string) {
+	t.Helper()
+	var idents []string
+	for _, token := range tokenize(query) {
+		switch token.kind {
+		case tokenIdent:
+			idents = append(idents, token.value())
+		case tokenComment, tokenString:
+			t.Errorf("query %q contains %q", query, token.text)
+		}
+	}
+	if !reflect.DeepEqual(idents, want) {
+		t.Errorf("identifiers of %q = %q, want %q", query, idents, want)
+	}
+}
+
+func TestShowCreateQuotesNames(t *testing.T) {
+	for _, name := range hostileNames {
+		e, db := newFakeExporter(t, Config{}, func(query string) fakeResult {
+			if strings.HasPrefix(query, "SHOW CREATE VIEW") {
+				return fakeResult{columns: []string{"View", "Create View", "character_set_client", "collation_connection"},
+					rows: [][]driver.Value{{name, "CREATE VIEW", "utf8mb4", "utf8mb4_general_ci"}}}
+			}
+			return fakeResult{columns: []string{"Table", "Create Table"}, rows: [][]driver.Value{{name, "CREATE TABLE"}}}
+		})
+		for _, isView := range []bool{false, true} {
+			if _, err := e.showCreate(name, isView); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, query := range db.sent() {
+			checkIdents(t, query, []string{name})
+		}
+	}
+}
+
+func TestResumeAfterQuotesNames(t *testing.T) {
+	for _, name := range hostileNames {
+		last := [][]byte{[]byte("7"), []byte("x")}
+		q := resumeAfter(name, []string{name, "v"}, []int{0, 1}, last, 5)
+		checkIdents(t, q.query, []string{name, name, "v", name, "v"})
+		if want := []interface{}{[]byte("7"), []byte("x")}; !reflect.DeepEqual(q.args, want) {
+			t.Errorf("args = %q, want %q", q.args, want)
+		}
+		if !strings.HasSuffix(q.query, " LIMIT 5") {
+			t.Errorf("query %q does not end with the limit", q.query)
+		}
+	}
+}
+
+func TestNewSamplerQuotesNames(t *testing.T) {
+	for _, name := range hostileNames {
+		info := &TableInfo{Name: name, Columns: []ColumnInfo{{Name: name, DataType: "int"}, {Name: "v"}}, PrimaryKey: []string{name}, EstimatedRows: 100}
+		tests := []struct {
+			sample string
+			// want are the identifiers of the queries run to set up the
+			// sampler, followed by those of its first query
+			want [][]string
+		}{
+			{SampleFirst, [][]string{{name, name}}},
+			{SampleNewest, [][]string{{name, name}}},
+			{SamplePercent, [][]string{{name, "sample"}}},
+			// The fake MIN and MAX fail, so the keys are not probed
+			{SampleRandom, [][]string{{name, name, name}, {name, "sample"}}},
+			{SampleStratified, [][]string{{name, name, name}, {name}}},
+		}
+		for _, test := range tests {
+			config := Config{MaxRows: 10, Retries: 1, Sample: test.sample, SampleColumn: name, SamplePercent: 50}
+			e, db := newFakeExporter(t, config, func(query string) fakeResult {
+				if strings.Contains(query, "GROUP BY") {
+					return fakeResult{columns: []string{"value", "rows"}, rows: [][]driver.Value{{[]byte("a"), []byte("3")}}}
+				}
+				return fakeResult{err: errors.New("fakeDB: unknown column")}
+			})
+			s, err := e.newSampler(info)
+			if err != nil {
+				t.Fatalf("%s: %v", test.sample, err)
+			}
+			q, ok, err := s.next(0)
+			if err != nil || !ok {
+				t.Fatalf("%s: next = %v, %v", test.sample, ok, err)
+			}
+			queries := append(db.sent(), q.query)
+			if len(queries) != len(test.want) {
+				t.Errorf("%s: queries = %q, want %d", test.sample, queries, len(test.want))
+				continue
+			}
+			for i, query := range queries {
+				checkIdents(t, query, test.want[i])
+			}
+		}
+	}
+}
+
+func TestVerifyTableQuotesNames(t *testing.T) {
+	for _, name := range hostileNames {
+		e, db := newFakeExporter(t, Config{}, func(query string) fakeResult {
+			return fakeResult{columns: []string{name, "v"}}
+		})
+		expected := TableChecksum{Table: name, Columns: []string{name, "v"}, PrimaryKey: []string{name}}
+		if err := e.verifyTable(expected, nil, &TableVerification{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, query := range db.sent() {
+			checkIdents(t, query, []string{name, "v", name})
+		}
+
+		// Key lookups of composite keys
+		c := &TableChecksum{Table: name, Columns: []string{name, "v"}, PrimaryKey: []string{name, "v"}}
+		query := verifyQuery(c, 2)
+		checkIdents(t, query, []string{name, "v", name, name, "v"})
+		if !strings.HasSuffix(query, " IN ((?, ?), (?, ?))") {
+			t.Errorf("query %q does not look up two keys", query)
+		}
+	}
+}
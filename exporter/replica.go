@@ -0,0 +1,140 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// replicaStatus reads SHOW REPLICA STATUS, or SHOW SLAVE STATUS before MySQL
+// 8.0.22, as a map from column to value. It returns false when the server is
+// not a replica.
+func replicaStatus(ctx context.Context, q contextQueryer) (map[string]sql.NullString, bool, error) {
+	rows, err := q.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		rows, err = q.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf(msgs.ErrReplicaStatus, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, false, fmt.Errorf(msgs.ErrReplicaStatus, err)
+	}
+	if !rows.Next() {
+		return nil, false, rows.Err()
+	}
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, false, fmt.Errorf(msgs.ErrReplicaStatus, err)
+	}
+
+	status := make(map[string]sql.NullString, len(columns))
+	for i, column := range columns {
+		status[column] = values[i]
+	}
+	return status, true, nil
+}
+
+// replicaLag returns how far a replica is behind its source. The lag is -1
+// when it is unknown because the replication threads are stopped.
+func replicaLag(ctx context.Context, q contextQueryer) (time.Duration, error) {
+	status, ok, err := replicaStatus(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, errors.New(msgs.ErrNotReplica)
+	}
+	return statusLag(status), nil
+}
+
+// statusLag reads Seconds_Behind_Source, called Seconds_Behind_Master by
+// older servers, from a replica status
+func statusLag(status map[string]sql.NullString) time.Duration {
+	value, ok := status["Seconds_Behind_Source"]
+	if !ok {
+		value = status["Seconds_Behind_Master"]
+	}
+	seconds, err := strconv.ParseInt(value.String, 10, 64)
+	if !value.Valid || err != nil {
+		return -1
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// waitForReplica waits until a replica source is at most MaxReplicaLag
+// behind its primary. It fails when the lag is still too high after
+// ReplicaLagTimeout, and does nothing while the SQL thread is stopped by the
+// export itself.
+func (e *Exporter) waitForReplica() error {
+	if e.config.MaxReplicaLag <= 0 || e.sqlThreadStopped {
+		return nil
+	}
+	var waitStart time.Time
+	warned := false
+	for {
+		lag, err := replicaLag(e.ctx, e.db)
+		if err != nil {
+			return err
+		}
+		if lag >= 0 && lag <= e.config.MaxReplicaLag {
+			return nil
+		}
+
+		reason := msgs.ThrottleReplicaStopped
+		if lag >= 0 {
+			reason = fmt.Sprintf(msgs.ThrottleReplicaLag, lag, e.config.MaxReplicaLag)
+		}
+		if waitStart.IsZero() {
+			waitStart = time.Now()
+		}
+		if time.Since(waitStart) >= e.config.ReplicaLagTimeout {
+			return fmt.Errorf(msgs.ErrReplicaLag, e.config.ReplicaLagTimeout, reason)
+		}
+		if !warned {
+			e.warn(e.table, fmt.Sprintf(msgs.ReplicaLagWaiting, reason))
+			warned = true
+		}
+		if err := sleep(e.ctx, throttleCheckInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// stopSQLThread stops applying changes on a replica source, so that the
+// tables do not change while they are read without a snapshot transaction
+func (e *Exporter) stopSQLThread() error {
+	if _, err := e.db.ExecContext(e.ctx, "STOP REPLICA SQL_THREAD"); err != nil {
+		if _, err := e.db.ExecContext(e.ctx, "STOP SLAVE SQL_THREAD"); err != nil {
+			return fmt.Errorf(msgs.ErrStopSQLThread, err)
+		}
+	}
+	e.sqlThreadStopped = true
+	return nil
+}
+
+// startSQLThread restarts the SQL thread stopped by stopSQLThread. It also
+// runs when the export was canceled, and only warns when it fails so that
+// the result of the export is not lost.
+func (e *Exporter) startSQLThread() {
+	if !e.sqlThreadStopped {
+		return
+	}
+	ctx := context.Background()
+	if _, err := e.db.ExecContext(ctx, "START REPLICA SQL_THREAD"); err != nil {
+		if _, err := e.db.ExecContext(ctx, "START SLAVE SQL_THREAD"); err != nil {
+			e.warn("", fmt.Sprintf(msgs.StartSQLThreadFailed, err))
+		}
+	}
+	e.sqlThreadStopped = false
+}
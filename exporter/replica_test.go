@@ -0,0 +1,187 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusLag(t *testing.T) {
+	tests := []struct {
+		name   string
+		status map[string]sql.NullString
+		want   time.Duration
+	}{
+		{"source", map[string]sql.NullString{"Seconds_Behind_Source": {String: "12", Valid: true}}, 12 * time.Second},
+		{"master", map[string]sql.NullString{"Seconds_Behind_Master": {String: "0", Valid: true}}, 0},
+		// The lag is NULL while the replication threads are stopped
+		{"stopped", map[string]sql.NullString{"Seconds_Behind_Source": {}}, -1},
+		{"missing", map[string]sql.NullString{}, -1},
+	}
+	for _, test := range tests {
+		if got := statusLag(test.status); got != test.want {
+			t.Errorf("%s: statusLag = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// replicaAnswer answers SHOW REPLICA STATUS with the given lags in turn, a
+// nil lag is NULL. Servers before 8.0.22 only know SHOW SLAVE STATUS.
+func replicaAnswer(legacy bool, lags ...driver.Value) func(query string) fakeResult {
+	column, statement := "Seconds_Behind_Source", "SHOW REPLICA STATUS"
+	if legacy {
+		column, statement = "Seconds_Behind_Master", "SHOW SLAVE STATUS"
+	}
+	checks := 0
+	return func(query string) fakeResult {
+		if query != statement {
+			return fakeResult{err: errors.New("fakeDB: syntax error")}
+		}
+		lag := lags[min(checks, len(lags)-1)]
+		checks++
+		return fakeResult{columns: []string{"Replica_IO_State", column}, rows: [][]driver.Value{{"Waiting", lag}}}
+	}
+}
+
+func TestReplicaLag(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		e, _ := newFakeExporter(t, Config{}, replicaAnswer(legacy, "7"))
+		if lag, err := replicaLag(e.ctx, e.db); err != nil || lag != 7*time.Second {
+			t.Errorf("legacy %v: replicaLag = %v, %v, want 7s", legacy, lag, err)
+		}
+	}
+
+	// A server that is no replica has an empty status
+	e, _ := newFakeExporter(t, Config{}, func(string) fakeResult {
+		return fakeResult{columns: []string{"Seconds_Behind_Source"}}
+	})
+	if _, err := replicaLag(e.ctx, e.db); err == nil {
+		t.Error("replicaLag of a server that is no replica succeeded")
+	}
+}
+
+func TestWaitForReplica(t *testing.T) {
+	tests := []struct {
+		name    string
+		lags    []driver.Value
+		timeout time.Duration
+		wantErr bool
+		// warnings is the number of warnings sent while waiting
+		warnings int
+	}{
+		{"current", []driver.Value{"1"}, 0, false, 0},
+		{"catches up", []driver.Value{"30", "0"}, time.Minute, false, 1},
+		{"too far behind", []driver.Value{"30"}, 0, true, 0},
+		{"stopped", []driver.Value{nil}, 0, true, 0},
+	}
+	for _, test := range tests {
+		recorder := &eventRecorder{}
+		config := Config{MaxReplicaLag: 5 * time.Second, ReplicaLagTimeout: test.timeout, Observer: recorder}
+		e, _ := newFakeExporter(t, config, replicaAnswer(false, test.lags...))
+		err := e.waitForReplica()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: waitForReplica = %v, want error %v", test.name, err, test.wantErr)
+		}
+		if len(recorder.events) != test.warnings {
+			t.Errorf("%s: %d warnings, want %d", test.name, len(recorder.events), test.warnings)
+		}
+	}
+
+	// Without a limit, and while the SQL thread is stopped, nothing is checked
+	e, f := newFakeExporter(t, Config{}, replicaAnswer(false, "30"))
+	if err := e.waitForReplica(); err != nil || len(f.sent()) > 0 {
+		t.Errorf("waitForReplica without a limit = %v after %q, want no query", err, f.sent())
+	}
+	e, f = newFakeExporter(t, Config{MaxReplicaLag: time.Second}, replicaAnswer(false, "30"))
+	e.sqlThreadStopped = true
+	if err := e.waitForReplica(); err != nil || len(f.sent()) > 0 {
+		t.Errorf("waitForReplica with the SQL thread stopped = %v after %q, want no query", err, f.sent())
+	}
+}
+
+func TestWaitForReplicaCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	answer := replicaAnswer(false, "30")
+	e, _ := newFakeExporter(t, Config{MaxReplicaLag: time.Second, ReplicaLagTimeout: time.Hour}, func(query string) fakeResult {
+		// The export is canceled while the replica is behind
+		cancel()
+		return answer(query)
+	})
+	defer e.bind(ctx)()
+
+	done := make(chan error, 1)
+	go func() { done <- e.waitForReplica() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("waitForReplica = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitForReplica kept waiting after the cancellation")
+	}
+}
+
+func TestSQLThread(t *testing.T) {
+	tests := []struct {
+		name string
+		// fail are the statements the server does not know
+		fail     string
+		want     []string
+		warnings int
+	}{
+		{"replica", "SLAVE", []string{"STOP REPLICA SQL_THREAD", "START REPLICA SQL_THREAD"}, 0},
+		{"before 8.0.22", "REPLICA", []string{
+			"STOP REPLICA SQL_THREAD", "STOP SLAVE SQL_THREAD",
+			"START REPLICA SQL_THREAD", "START SLAVE SQL_THREAD"}, 0},
+	}
+	for _, test := range tests {
+		recorder := &eventRecorder{}
+		e, f := newFakeExporter(t, Config{Observer: recorder}, func(query string) fakeResult {
+			if strings.Contains(query, test.fail) {
+				return fakeResult{err: errors.New("fakeDB: syntax error")}
+			}
+			return fakeResult{}
+		})
+		if err := e.stopSQLThread(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !e.sqlThreadStopped {
+			t.Errorf("%s: SQL thread not recorded as stopped", test.name)
+		}
+		e.startSQLThread()
+		// Starting again does nothing
+		e.startSQLThread()
+		if got := f.sent(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: statements = %q, want %q", test.name, got, test.want)
+		}
+		if len(recorder.events) != test.warnings {
+			t.Errorf("%s: %d warnings, want %d", test.name, len(recorder.events), test.warnings)
+		}
+	}
+
+	// Failing to restart only warns, failing to stop is an error
+	recorder := &eventRecorder{}
+	e, _ := newFakeExporter(t, Config{Observer: recorder}, func(query string) fakeResult {
+		if strings.HasPrefix(query, "START") {
+			return fakeResult{err: errors.New("fakeDB: access denied")}
+		}
+		return fakeResult{}
+	})
+	if err := e.stopSQLThread(); err != nil {
+		t.Fatal(err)
+	}
+	e.startSQLThread()
+	if len(recorder.events) != 1 || recorder.events[0].Type != EventWarning || e.sqlThreadStopped {
+		t.Errorf("events = %v, stopped %v, want one warning", recorder.events, e.sqlThreadStopped)
+	}
+	e, _ = newFakeExporter(t, Config{}, func(string) fakeResult { return fakeResult{err: errors.New("fakeDB: access denied")} })
+	if err := e.stopSQLThread(); err == nil || e.sqlThreadStopped {
+		t.Errorf("stopSQLThread = %v, want an error", err)
+	}
+}
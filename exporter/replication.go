@@ -0,0 +1,236 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Source data modes, following mysqldump's --source-data
+const (
+	// SourceDataOff does not record the binary log coordinates
+	SourceDataOff = 0
+	// SourceDataActive writes active replication statements
+	SourceDataActive = 1
+	// SourceDataCommented writes the replication statements as comments
+	SourceDataCommented = 2
+)
+
+// ReplicationInfo holds the binary log coordinates of the exported snapshot
+type ReplicationInfo struct {
+	LogFile      string `json:"log_file,omitempty"`
+	LogPosition  uint64 `json:"log_position,omitempty"`
+	GTIDExecuted string `json:"gtid_executed,omitempty"`
+}
+
+// queryer runs queries either through the connection pool or on the snapshot connection
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// startSnapshot opens a consistent snapshot transaction on a dedicated
+// connection and routes all further queries through it. When the binary log
+// coordinates are requested, they are read under a global read lock so that
+// they match the snapshot exactly.
+func (e *Exporter) startSnapshot() error {
+	ctx := e.ctx
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrStartSnapshot, err)
+	}
+
+	statements := []string{
+		"SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT",
+	}
+	locked := false
+	if e.config.SourceData != SourceDataOff {
+		// Flush without the lock first so that the locked flush is fast
+		statements = append([]string{"FLUSH LOCAL TABLES", "FLUSH TABLES WITH READ LOCK"}, statements...)
+	}
+	// The global read lock blocks all writes on the source. When the snapshot
+	// cannot be set up it is released and the connection is thrown away, so
+	// that a pooled connection never keeps holding it.
+	fail := func(err error) error {
+		if locked {
+			conn.ExecContext(context.Background(), "UNLOCK TABLES")
+		}
+		discardConn(conn)
+		e.snapshot = nil
+		e.q = boundQueryer{ctx: ctx, q: e.db, log: e.log, retry: e.backoff}
+		return err
+	}
+	for _, statement := range statements {
+		// A canceled lock statement may still have taken the lock
+		locked = locked || statement == "FLUSH TABLES WITH READ LOCK"
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fail(fmt.Errorf(msgs.ErrStartSnapshot, err))
+		}
+	}
+	e.snapshot = conn
+	e.q = boundQueryer{ctx: ctx, q: conn, log: e.log}
+
+	if e.config.SourceData != SourceDataOff {
+		info, err := e.readReplicationInfo()
+		if err != nil {
+			return fail(err)
+		}
+		e.replication = info
+		if _, err := conn.ExecContext(ctx, "UNLOCK TABLES"); err != nil {
+			return fail(fmt.Errorf(msgs.ErrStartSnapshot, err))
+		}
+	}
+	return nil
+}
+
+// endSnapshot finishes the snapshot transaction and releases its connection
+func (e *Exporter) endSnapshot() {
+	if e.snapshot == nil {
+		return
+	}
+	// The transaction is finished even when the run was canceled
+	if _, err := e.snapshot.ExecContext(context.Background(), "COMMIT"); err != nil {
+		discardConn(e.snapshot)
+	} else {
+		e.snapshot.Close()
+	}
+	e.snapshot = nil
+	e.q = boundQueryer{ctx: e.ctx, q: e.db, log: e.log, retry: e.backoff}
+}
+
+// discardConn closes conn instead of returning it to the pool, so that no
+// lock or transaction it may still hold outlives the run
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
+
+// readReplicationInfo reads the binary log position and the executed GTID set
+func (e *Exporter) readReplicationInfo() (*ReplicationInfo, error) {
+	// SHOW MASTER STATUS was replaced by SHOW BINARY LOG STATUS in MySQL 8.2
+	rows, err := e.q.Query("SHOW BINARY LOG STATUS")
+	if err != nil {
+		rows, err = e.q.Query("SHOW MASTER STATUS")
+		if err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadBinlogStatus, err)
+		}
+	}
+	defer rows.Close()
+
+	info := &ReplicationInfo{}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadBinlogStatus, err)
+	}
+	if rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadBinlogStatus, err)
+		}
+		for i, column := range columns {
+			switch column {
+			case "File":
+				info.LogFile = values[i].String
+			case "Position":
+				info.LogPosition, _ = strconv.ParseUint(values[i].String, 10, 64)
+			case "Executed_Gtid_Set":
+				info.GTIDExecuted = values[i].String
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadBinlogStatus, err)
+	}
+	rows.Close()
+
+	// The status output omits the GTID set on older servers; MariaDB has no
+	// gtid_executed at all, in which case only the file position is recorded
+	if info.GTIDExecuted == "" {
+		var gtidExecuted sql.NullString
+		if err := e.q.QueryRow("SELECT @@GLOBAL.gtid_executed").Scan(&gtidExecuted); err == nil {
+			info.GTIDExecuted = gtidExecuted.String
+		}
+	}
+	// The set is reported with line breaks between server UUIDs, which would
+	// end the comment of a commented statement in the export
+	info.GTIDExecuted = strings.ReplaceAll(info.GTIDExecuted, "\n", "")
+
+	return info, nil
+}
+
+// serverVersion returns VERSION() of the source server, or "" when it cannot be read
+func (e *Exporter) serverVersion() string {
+	var version string
+	if err := e.q.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		return ""
+	}
+	return version
+}
+
+// parseVersion returns the major, minor and patch version of a VERSION() string
+func parseVersion(version string) (int, int, int) {
+	var parts [3]int
+	for i, part := range strings.SplitN(version, ".", 3) {
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		parts[i], _ = strconv.Atoi(part[:end])
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// changeSourceStatement returns the statement that points a replica at a
+// binary log position of a server with the given VERSION(). CHANGE
+// REPLICATION SOURCE TO replaced CHANGE MASTER TO in MySQL 8.0.23, MariaDB
+// only knows CHANGE MASTER TO although its versions are 10.x and later.
+func changeSourceStatement(version, file string, pos uint64) string {
+	major, minor, patch := parseVersion(version)
+	mysql8023 := major > 8 || (major == 8 && (minor > 0 || patch >= 23))
+	if mysql8023 && !strings.Contains(version, "MariaDB") {
+		return fmt.Sprintf("CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='%s', SOURCE_LOG_POS=%d;", file, pos)
+	}
+	return fmt.Sprintf("CHANGE MASTER TO MASTER_LOG_FILE='%s', MASTER_LOG_POS=%d;", file, pos)
+}
+
+// writeReplicationInfo writes the snapshot coordinates as comments and as
+// statements that set up a replica from the export
+func (e *Exporter) writeReplicationInfo(w io.Writer) error {
+	info := e.replication
+	if info == nil || (info.LogFile == "" && info.GTIDExecuted == "") {
+		_, err := fmt.Fprintf(w, "%s\n\n", msgs.ReplicationInfoMissing)
+		return err
+	}
+
+	// The statements only make sense when the export is loaded into MySQL
+	prefix := ""
+	_, native := e.dialect.(mysqlDialect)
+	if e.config.SourceData == SourceDataCommented || !native {
+		prefix = "-- "
+	}
+
+	var b strings.Builder
+	if info.LogFile != "" {
+		fmt.Fprintf(&b, msgs.ReplicationInfoPosition+"\n", info.LogFile, info.LogPosition)
+	}
+	if info.GTIDExecuted != "" {
+		fmt.Fprintf(&b, msgs.ReplicationInfoGTID+"\n", info.GTIDExecuted)
+		fmt.Fprintf(&b, "%sSET @@GLOBAL.GTID_PURGED='%s';\n", prefix, info.GTIDExecuted)
+	}
+	if info.LogFile != "" {
+		fmt.Fprintf(&b, "%s%s\n", prefix, changeSourceStatement(e.serverVersion(), info.LogFile, info.LogPosition))
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
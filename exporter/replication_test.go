@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChangeSourceStatement(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"5.7.44-log", "CHANGE MASTER TO"},
+		{"8.0.22", "CHANGE MASTER TO"},
+		{"8.0.23", "CHANGE REPLICATION SOURCE TO"},
+		{"8.0.36-0ubuntu0.22.04.1", "CHANGE REPLICATION SOURCE TO"},
+		{"8.4.0", "CHANGE REPLICATION SOURCE TO"},
+		{"9.1.0-commercial", "CHANGE REPLICATION SOURCE TO"},
+		{"10.11.6-MariaDB-0+deb12u1-log", "CHANGE MASTER TO"},
+		{"11.4.2-MariaDB", "CHANGE MASTER TO"},
+		{"5.5.5-10.6.16-MariaDB", "CHANGE MASTER TO"},
+		{"", "CHANGE MASTER TO"},
+	}
+	for _, test := range tests {
+		got := changeSourceStatement(test.version, "binlog.000042", 157)
+		if !strings.HasPrefix(got, test.want+" ") {
+			t.Errorf("changeSourceStatement(%q) = %q, want %s", test.version, got, test.want)
+		}
+	}
+
+	want := "CHANGE MASTER TO MASTER_LOG_FILE='binlog.000042', MASTER_LOG_POS=157;"
+	if got := changeSourceStatement("10.11.6-MariaDB", "binlog.000042", 157); got != want {
+		t.Errorf("changeSourceStatement = %q, want %q", got, want)
+	}
+	want = "CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='binlog.000042', SOURCE_LOG_POS=157;"
+	if got := changeSourceStatement("8.0.23", "binlog.000042", 157); got != want {
+		t.Errorf("changeSourceStatement = %q, want %q", got, want)
+	}
+}
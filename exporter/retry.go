@@ -0,0 +1,121 @@
+package exporter
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Delays between the attempts of a failed operation, doubling from
+// retryDelay up to maxRetryDelay
+const (
+	retryDelay    = 500 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
+)
+
+// backoff paces the attempts of an operation that failed with a transient error
+type backoff struct {
+	ctx     context.Context
+	retries int
+	attempt int
+	delay   time.Duration
+	warn    func(string)
+}
+
+// retry reports whether the operation should run again after err. It waits
+// before returning true, and returns false for errors that are not
+// transient, when no retries are left or when the context ended.
+func (b *backoff) retry(err error) bool {
+	if b == nil || b.attempt >= b.retries || b.ctx.Err() != nil || !transient(err) {
+		return false
+	}
+	b.attempt++
+	if b.delay == 0 {
+		b.delay = retryDelay
+	} else {
+		b.delay = min(2*b.delay, maxRetryDelay)
+	}
+	if b.warn != nil {
+		b.warn(fmt.Sprintf(msgs.RetryAfterError, b.delay, b.attempt, b.retries, err))
+	}
+	return sleep(b.ctx, b.delay) == nil
+}
+
+// backoff starts the retries of an operation of the current run. A lost
+// snapshot connection cannot be replaced, so nothing is retried while a
+// snapshot is open.
+func (e *Exporter) backoff() *backoff {
+	if e.snapshot != nil {
+		return nil
+	}
+	return &backoff{ctx: e.ctx, retries: e.config.Retries, warn: func(message string) {
+		e.warn(e.table, message)
+	}}
+}
+
+// transient reports whether err is a dropped connection, a timeout or a
+// server error that may not happen again, such as a deadlock
+func transient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1040, // too many connections
+			1053, // server shutdown in progress
+			1205, // lock wait timeout
+			1213: // deadlock
+			return true
+		}
+	}
+	return false
+}
+
+// resumeAfter returns the query reading the rows of a table that follow
+// the row last in primary key order. keys are the positions of the primary
+// key columns in last, limit is the number of rows still to read, 0 for all.
+func resumeAfter(table string, primaryKey []string, keys []int, last [][]byte, limit int) sampleQuery {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = last[key]
+	}
+	order := quoteIdents(primaryKey)
+	return sampleQuery{
+		query: "SELECT * FROM " + quoteIdent(table) + " WHERE (" + order + ") > (" + placeholders + ")" +
+			" ORDER BY " + order + limitClause(limit),
+		args:   args,
+		resume: keyResume(table, primaryKey, keys, limit),
+	}
+}
+
+// keyResume returns the resume function of a query reading a table in
+// primary key order with a row limit, 0 for none
+func keyResume(table string, primaryKey []string, keys []int, limit int) func([][]byte, int) (sampleQuery, bool) {
+	return func(last [][]byte, read int) (sampleQuery, bool) {
+		if limit > 0 && read >= limit {
+			return sampleQuery{}, false
+		}
+		rest := 0
+		if limit > 0 {
+			rest = limit - read
+		}
+		return resumeAfter(table, primaryKey, keys, last, rest), true
+	}
+}
@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{driver.ErrBadConn, true},
+		{mysql.ErrInvalidConn, true},
+		{fmt.Errorf("reading rows: %w", io.ErrUnexpectedEOF), true},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset")}, true},
+		{&mysql.MySQLError{Number: 1213, Message: "Deadlock found"}, true},
+		{&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, true},
+		{&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}, false},
+		{errors.New("syntax error"), false},
+	}
+	for _, test := range tests {
+		if got := transient(test.err); got != test.want {
+			t.Errorf("transient(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestBackoffStops(t *testing.T) {
+	// No retries are left, or the error will happen again
+	b := &backoff{ctx: context.Background(), retries: 0}
+	if b.retry(io.ErrUnexpectedEOF) {
+		t.Error("retry without retries left = true")
+	}
+	b = &backoff{ctx: context.Background(), retries: 3}
+	if b.retry(errors.New("syntax error")) || b.attempt != 0 {
+		t.Error("retry of an error that is not transient = true")
+	}
+	// A snapshot run has no backoff
+	var none *backoff
+	if none.retry(io.ErrUnexpectedEOF) {
+		t.Error("retry of a nil backoff = true")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b = &backoff{ctx: ctx, retries: 3}
+	if b.retry(io.ErrUnexpectedEOF) {
+		t.Error("retry of a canceled run = true")
+	}
+}
@@ -0,0 +1,520 @@
+package exporter
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sampling strategies selecting which rows of a table are exported
+const (
+	// SampleFirst reads the first rows in clustered index order
+	SampleFirst = "first"
+	// SampleNewest reads the rows with the highest primary key or SampleColumn value
+	SampleNewest = "newest"
+	// SampleRandom reads uniformly random rows by probing primary key ranges
+	SampleRandom = "random"
+	// SamplePercent reads about SamplePercent percent of the rows
+	SamplePercent = "percent"
+	// SampleStratified reads the same number of rows for each value of SampleColumn
+	SampleStratified = "stratified"
+)
+
+// maxProbeRounds bounds the key probing of random samples on sparse keys
+const maxProbeRounds = 50
+
+// maxProbeKeys is the largest number of keys probed by one query
+const maxProbeKeys = 10000
+
+// validateSample checks the sampling options of a configuration
+func validateSample(config Config) error {
+	switch config.Sample {
+	case "", SampleFirst, SampleNewest, SampleRandom:
+	case SamplePercent:
+		if config.SamplePercent <= 0 || config.SamplePercent > 100 {
+			return fmt.Errorf(msgs.ErrInvalidSamplePercent, config.SamplePercent)
+		}
+	case SampleStratified:
+		if config.SampleColumn == "" {
+			return fmt.Errorf(msgs.ErrSampleColumnRequired, config.Sample)
+		}
+	default:
+		return fmt.Errorf(msgs.ErrInvalidSample, config.Sample)
+	}
+	return nil
+}
+
+// sampleQuery is one query reading part of the sample of a table. A query
+// with resume can be continued after the connection dropped, resume is given
+// the last row read and the number of rows read and reports false when the
+// query was complete. keep, when set, is given every row read and decides
+// whether it belongs to the sample.
+type sampleQuery struct {
+	query  string
+	args   []interface{}
+	resume func(last [][]byte, read int) (sampleQuery, bool)
+	keep   func(raw [][]byte) bool
+}
+
+// sampler produces the queries reading the sample of a table. next is given
+// the number of rows read so far and reports false when the sample is complete.
+type sampler interface {
+	next(rowsRead int) (sampleQuery, bool, error)
+}
+
+// staticSampler runs a fixed list of queries
+type staticSampler struct {
+	queries []sampleQuery
+}
+
+func (s *staticSampler) next(rowsRead int) (sampleQuery, bool, error) {
+	if len(s.queries) == 0 {
+		return sampleQuery{}, false, nil
+	}
+	q := s.queries[0]
+	s.queries = s.queries[1:]
+	return q, true, nil
+}
+
+// scanTable reads the sampled rows of a table and calls fn with the scanner
+// holding every row, throttled to the configured limits. It returns the
+// number of rows read.
+func (e *Exporter) scanTable(info *TableInfo, fn func(s *rowScanner) error) (int, error) {
+	table, isView, count := info.Name, info.IsView, len(info.Columns)
+	s, err := e.newSampler(info)
+	if err != nil {
+		return 0, err
+	}
+	e.throttler.reset()
+
+	rowCount := 0
+	for {
+		q, ok, err := s.next(rowCount)
+		if err != nil || !ok {
+			return rowCount, err
+		}
+		if err := e.ctx.Err(); err != nil {
+			return rowCount, err
+		}
+
+		// A query that dropped its connection after some rows is resumed
+		// from the last row, when its order allows it
+		retry := e.backoff()
+		for {
+			last, read, err := e.readQuery(table, isView, q, count, &rowCount, fn)
+			if err == nil {
+				break
+			}
+			if read == 0 || q.resume == nil || !retry.retry(err) {
+				return rowCount, err
+			}
+			if q, ok = q.resume(last, read); !ok {
+				break
+			}
+		}
+	}
+}
+
+// readQuery runs one query of scanTable and calls fn with every row. It
+// returns a copy of the last row of a resumable query and the number of rows
+// it read, and counts them in rowCount.
+func (e *Exporter) readQuery(table string, isView bool, q sampleQuery, count int, rowCount *int,
+	fn func(s *rowScanner) error) ([][]byte, int, error) {
+	rows, err := e.q.Query(q.query, q.args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf(msgs.ErrQueryTableData, table, err)
+	}
+	defer rows.Close()
+
+	var last [][]byte
+	if q.resume != nil {
+		last = make([][]byte, count)
+	}
+	read := 0
+	s := newRowScanner(rows, count)
+	for rows.Next() {
+		if err := s.scan(rows); err != nil {
+			return last, read, fmt.Errorf(msgs.ErrReadTableData, table, err)
+		}
+		if q.keep != nil && !q.keep(s.raw) {
+			continue
+		}
+		if err := fn(s); err != nil {
+			return last, read, err
+		}
+		// The scanned bytes are reused by the next row
+		for i := range last {
+			last[i] = append(last[i][:0], s.raw[i]...)
+		}
+		read++
+		*rowCount++
+		if *rowCount%e.config.ProgressRows == 0 {
+			e.emit(Event{Type: EventRowsWritten, Table: table, IsView: isView, Rows: int64(*rowCount), Bytes: e.tableBytes})
+		}
+		if err := e.throttle(rowSize(s.raw)); err != nil {
+			return last, read, &throttleError{err: err}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return last, read, fmt.Errorf(msgs.ErrReadTableData, table, err)
+	}
+	return last, read, nil
+}
+
+// rowSize returns the number of bytes of the values of a row
+func rowSize(raw [][]byte) int {
+	size := 0
+	for _, value := range raw {
+		size += len(value)
+	}
+	return size
+}
+
+// newSampler chooses the queries for the configured sampling strategy.
+// Views and tables that lack what a strategy needs are read from the start.
+func (e *Exporter) newSampler(info *TableInfo) (sampler, error) {
+	table, isView := info.Name, info.IsView
+	columns, primaryKey := info.columnNames(), info.PrimaryKey
+	limit := e.config.MaxRows
+	selectAll := "SELECT * FROM " + quoteIdent(table)
+	first := &staticSampler{queries: []sampleQuery{{query: selectAll + limitClause(limit)}}}
+	if e.config.Retries > 0 && !isView && len(primaryKey) > 0 {
+		// Rows come in no defined order without ORDER BY. Ordering by the
+		// primary key lets a dropped read continue after the last row.
+		keys := make([]int, len(primaryKey))
+		for i, column := range primaryKey {
+			keys[i] = slices.Index(columns, column)
+		}
+		first.queries[0] = sampleQuery{
+			query:  selectAll + " ORDER BY " + quoteIdents(primaryKey) + limitClause(limit),
+			resume: keyResume(table, primaryKey, keys, limit),
+		}
+	}
+
+	// Without a row limit every strategy but percent reads the whole table
+	strategy := e.config.Sample
+	if isView || strategy == "" || strategy == SampleFirst || (limit <= 0 && strategy != SamplePercent) {
+		return first, nil
+	}
+
+	switch strategy {
+	case SampleNewest:
+		order := primaryKey
+		if e.config.SampleColumn != "" && contains(columns, e.config.SampleColumn) {
+			order = []string{e.config.SampleColumn}
+		}
+		if len(order) == 0 {
+			e.warn(table, fmt.Sprintf(msgs.SampleFallback, table, strategy))
+			return first, nil
+		}
+		keys := make([]string, len(order))
+		for i, column := range order {
+			keys[i] = quoteIdent(column) + " DESC"
+		}
+		query := selectAll + " ORDER BY " + strings.Join(keys, ", ") + limitClause(limit)
+		return &staticSampler{queries: []sampleQuery{{query: query}}}, nil
+
+	case SamplePercent:
+		// A row limit lowers the fraction to what the limit needs, so that
+		// randomScan does not sort much more than the limit
+		fraction := e.config.SamplePercent / 100
+		if limit > 0 {
+			fraction = min(fraction, sampleFraction(info, limit))
+		}
+		return &staticSampler{queries: []sampleQuery{randomScan(table, fraction, limit, "")}}, nil
+
+	case SampleRandom:
+		if len(primaryKey) == 1 {
+			s, ok, err := e.newRandomSampler(info, primaryKey[0], limit)
+			if err != nil || ok {
+				return s, err
+			}
+		}
+		// Keys that cannot be probed are sampled in one scan, which avoids
+		// sorting the table like ORDER BY RAND() would
+		return &staticSampler{queries: []sampleQuery{randomScan(table, sampleFraction(info, limit), limit, "")}}, nil
+
+	case SampleStratified:
+		column := e.config.SampleColumn
+		if !contains(columns, column) {
+			e.warn(table, fmt.Sprintf(msgs.SampleFallback, table, strategy))
+			return first, nil
+		}
+		return e.newStratifiedSampler(table, column, slices.Index(columns, column), limit)
+	}
+	return first, nil
+}
+
+// limitClause returns the LIMIT clause for a row limit, 0 means no limit
+func limitClause(limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" LIMIT %d", limit)
+}
+
+// sampleOversample is how many more rows than needed randomScan reads
+// for a row limit, so that inexact statistics rarely leave the sample short
+const sampleOversample = 2
+
+// sampleFraction estimates the fraction of rows needed for a sample of
+// limit rows from the table statistics, oversampled by sampleOversample
+func sampleFraction(table *TableInfo, limit int) float64 {
+	if table.EstimatedRows <= int64(limit) {
+		return 1
+	}
+	return min(1, sampleOversample*float64(limit)/float64(table.EstimatedRows))
+}
+
+// randomScan reads each row with the given probability in one scan of the
+// table, optionally restricted by a condition. A plain LIMIT would stop the
+// scan early and only keep rows from the start of the table, so with a row
+// limit the rows found are shuffled and cut to the limit instead. This sorts
+// the oversampled rows, but not the whole table.
+func randomScan(table string, fraction float64, limit int, condition string, args ...interface{}) sampleQuery {
+	query := "SELECT * FROM " + quoteIdent(table) + " WHERE RAND() < ?" + condition
+	if limit > 0 {
+		query = "SELECT * FROM (" + query + ") AS `sample` ORDER BY RAND()" + limitClause(limit)
+	}
+	return sampleQuery{query: query, args: append([]interface{}{fraction}, args...)}
+}
+
+// stratum is one distinct value of the column of a stratified sample with
+// the number of rows it has and the number of rows still to sample
+type stratum struct {
+	rows, quota int64
+}
+
+// integerType reports whether a column of the given DATA_TYPE holds integers
+func integerType(dataType string) bool {
+	switch strings.ToLower(dataType) {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return true
+	}
+	return false
+}
+
+// newStratifiedSampler reads up to limit rows split evenly over the distinct
+// values of a column. The rows of each value are counted first, then one
+// scan of the table keeps a uniformly random selection of each value's rows.
+// With more values than rows, the values sampled are chosen at random.
+func (e *Exporter) newStratifiedSampler(table, column string, index, limit int) (sampler, error) {
+	// Values are compared byte for byte, which keeps values that a case
+	// insensitive collation groups together apart. The values of a group
+	// are identical, MIN picks one without the ANY_VALUE of MySQL 8.0.
+	query := fmt.Sprintf("SELECT MIN(%s), COUNT(*) FROM %s GROUP BY CAST(%s AS BINARY)",
+		quoteIdent(column), quoteIdent(table), quoteIdent(column))
+	rows, err := e.q.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrQueryTableData, table, err)
+	}
+	defer rows.Close()
+
+	strata := map[string]*stratum{}
+	var keys []string
+	s := newRowScanner(rows, 2)
+	for rows.Next() {
+		if err := s.scan(rows); err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadTableData, table, err)
+		}
+		count, err := strconv.ParseInt(string(s.raw[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadTableData, table, err)
+		}
+		key := stratumKey(s.raw[0])
+		strata[key] = &stratum{rows: count}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(msgs.ErrReadTableData, table, err)
+	}
+	if len(keys) == 0 {
+		return &staticSampler{}, nil
+	}
+	allocateStrata(strata, keys, int64(limit))
+
+	keep := func(raw [][]byte) bool {
+		st := strata[stratumKey(raw[index])]
+		if st == nil || st.quota <= 0 {
+			return false
+		}
+		// Selection sampling keeps each row with the probability that
+		// leaves a uniform choice of quota rows out of the rest
+		keep := st.rows <= st.quota || rand.Int64N(st.rows) < st.quota
+		st.rows--
+		if keep {
+			st.quota--
+		}
+		return keep
+	}
+	query = "SELECT * FROM " + quoteIdent(table)
+	return &staticSampler{queries: []sampleQuery{{query: query, keep: keep}}}, nil
+}
+
+// stratumKey identifies the value of a column in a stratified sample,
+// keeping NULL apart from the empty string
+func stratumKey(raw []byte) string {
+	if raw == nil {
+		return ""
+	}
+	return "=" + string(raw)
+}
+
+// allocateStrata spreads limit rows over the strata. Strata with fewer rows
+// than their share pass the rest on to the larger ones. With fewer rows than
+// strata, limit random strata get one row each.
+func allocateStrata(strata map[string]*stratum, keys []string, limit int64) {
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	if int64(len(keys)) >= limit {
+		for _, key := range keys[:limit] {
+			strata[key].quota = 1
+		}
+		return
+	}
+	slices.SortStableFunc(keys, func(a, b string) int { return cmp.Compare(strata[a].rows, strata[b].rows) })
+	remaining := limit
+	for i, key := range keys {
+		st := strata[key]
+		st.quota = min(st.rows, remaining/int64(len(keys)-i))
+		remaining -= st.quota
+	}
+}
+
+// randomSampler draws random values between the smallest and largest
+// integer primary key and reads the rows that exist for them. The number of
+// keys drawn per round adapts to how densely the key range is populated.
+// Keys too sparse to find the rows within maxProbeRounds fall back to
+// randomScan, which leaves out the rows already read.
+type randomSampler struct {
+	e        *Exporter
+	info     *TableInfo
+	key      string
+	keyIndex int
+	min, max int64
+	// size is the number of values from min to max, 0 stands for 2^64
+	size  uint64
+	limit int
+
+	drawn    map[int64]bool
+	hits     []interface{}
+	probed   int
+	rounds   int
+	scanning bool
+}
+
+// newRandomSampler returns false when the primary key is not an integer
+func (e *Exporter) newRandomSampler(info *TableInfo, key string, limit int) (sampler, bool, error) {
+	keyIndex := slices.Index(info.columnNames(), key)
+	// A string key of digits would scan into an integer too, but its
+	// values are not ordered like numbers
+	if keyIndex < 0 || !integerType(info.Columns[keyIndex].DataType) {
+		return nil, false, nil
+	}
+	s := &randomSampler{e: e, info: info, key: key, keyIndex: keyIndex, limit: limit, drawn: map[int64]bool{}}
+	query := fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s", quoteIdent(key), quoteIdent(key), quoteIdent(info.Name))
+	var min, max *int64
+	if err := e.q.QueryRow(query).Scan(&min, &max); err != nil {
+		// Unsigned keys beyond the int64 range are sampled differently
+		return nil, false, nil
+	}
+	if min == nil || max == nil {
+		return &staticSampler{}, true, nil
+	}
+	if *min > *max {
+		return nil, false, nil
+	}
+	// The difference of two int64 keys may not fit an int64
+	s.min, s.max = *min, *max
+	s.size = uint64(s.max) - uint64(s.min) + 1
+	return s, true, nil
+}
+
+// span returns the number of values from min to max as a float
+func (s *randomSampler) span() float64 {
+	if s.size == 0 {
+		return math.Pow(2, 64)
+	}
+	return float64(s.size)
+}
+
+func (s *randomSampler) next(rowsRead int) (sampleQuery, bool, error) {
+	remaining := s.limit - rowsRead
+	if remaining <= 0 || s.scanning || s.rounds >= maxProbeRounds || (s.size != 0 && uint64(len(s.drawn)) >= s.size) {
+		return sampleQuery{}, false, nil
+	}
+
+	// The share of existing keys is observed in the previous rounds, and
+	// estimated from the table statistics before the first one
+	density := 1.0
+	if s.probed > 0 {
+		density = float64(rowsRead) / float64(s.probed)
+	} else if s.info.EstimatedRows > 0 {
+		density = min(1, float64(s.info.EstimatedRows)/s.span())
+	}
+	// The keys already read are left out of the scan by an IN list, which
+	// must stay short
+	budget := float64(maxProbeKeys * (maxProbeRounds - s.rounds))
+	if density*budget < float64(remaining) && len(s.hits) <= maxProbeKeys {
+		return s.scan(remaining), true, nil
+	}
+
+	count := maxProbeKeys
+	if needed := float64(remaining)/density + 1; needed < maxProbeKeys {
+		count = int(needed)
+	}
+	if s.size != 0 && uint64(count) > s.size-uint64(len(s.drawn)) {
+		count = int(s.size - uint64(len(s.drawn)))
+	}
+
+	args := make([]interface{}, 0, count)
+	for len(args) < count {
+		var offset uint64
+		if s.size == 0 {
+			offset = rand.Uint64()
+		} else {
+			offset = rand.Uint64N(s.size)
+		}
+		key := int64(uint64(s.min) + offset)
+		// Dense draws fall back to the next free key so that the loop ends
+		for s.drawn[key] {
+			if key == s.max {
+				key = s.min
+			} else {
+				key++
+			}
+		}
+		s.drawn[key] = true
+		args = append(args, key)
+	}
+	s.probed += count
+	s.rounds++
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s IN (%s) LIMIT %d", quoteIdent(s.info.Name), quoteIdent(s.key), placeholders, remaining)
+	return sampleQuery{query: query, args: args, keep: s.hit}, true, nil
+}
+
+// hit records the key of a row read by probing
+func (s *randomSampler) hit(raw [][]byte) bool {
+	if key, err := strconv.ParseInt(string(raw[s.keyIndex]), 10, 64); err == nil {
+		s.hits = append(s.hits, key)
+	}
+	return true
+}
+
+// scan switches to randomScan for the remaining rows, leaving out the rows
+// read so far by key
+func (s *randomSampler) scan(remaining int) sampleQuery {
+	s.scanning = true
+	s.e.warn(s.info.Name, fmt.Sprintf(msgs.SampleSparseKeys, s.info.Name, s.key))
+	condition := ""
+	if len(s.hits) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(s.hits)), ", ")
+		condition = fmt.Sprintf(" AND %s NOT IN (%s)", quoteIdent(s.key), placeholders)
+	}
+	return randomScan(s.info.Name, sampleFraction(s.info, remaining), remaining, condition, s.hits...)
+}
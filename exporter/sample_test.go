@@ -0,0 +1,393 @@
+package exporter
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eventRecorder is an Observer keeping every event
+type eventRecorder struct {
+	events []Event
+}
+
+func (r *eventRecorder) Observe(event Event) {
+	r.events = append(r.events, event)
+}
+
+func TestRandomScan(t *testing.T) {
+	tests := []struct {
+		limit     int
+		condition string
+		want      string
+	}{
+		{0, "", "SELECT * FROM `t` WHERE RAND() < ?"},
+		{10, "", "SELECT * FROM (SELECT * FROM `t` WHERE RAND() < ?) AS `sample` ORDER BY RAND() LIMIT 10"},
+		{10, " AND `id` NOT IN (?)",
+			"SELECT * FROM (SELECT * FROM `t` WHERE RAND() < ? AND `id` NOT IN (?)) AS `sample` ORDER BY RAND() LIMIT 10"},
+	}
+	for _, test := range tests {
+		q := randomScan("t", 0.5, test.limit, test.condition, int64(7))
+		if q.query != test.want {
+			t.Errorf("randomScan(%d, %q) = %q, want %q", test.limit, test.condition, q.query, test.want)
+		}
+		if want := []interface{}{0.5, int64(7)}; !reflect.DeepEqual(q.args, want) {
+			t.Errorf("args = %v, want %v", q.args, want)
+		}
+	}
+}
+
+func TestSampleFraction(t *testing.T) {
+	tests := []struct {
+		rows  int64
+		limit int
+		want  float64
+	}{
+		{0, 10, 1},
+		{10, 10, 1},
+		{15, 10, 1},
+		{1000, 10, 0.02},
+	}
+	for _, test := range tests {
+		if got := sampleFraction(&TableInfo{EstimatedRows: test.rows}, test.limit); got != test.want {
+			t.Errorf("sampleFraction(%d, %d) = %g, want %g", test.rows, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestAllocateStrata(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  map[string]int64
+		limit int64
+		want  map[string]int64
+	}{
+		{"even", map[string]int64{"a": 100, "b": 100}, 10, map[string]int64{"a": 5, "b": 5}},
+		{"small strata pass their share on", map[string]int64{"a": 1, "b": 2, "c": 100}, 12, map[string]int64{"a": 1, "b": 2, "c": 9}},
+		{"everything fits", map[string]int64{"a": 3, "b": 4}, 100, map[string]int64{"a": 3, "b": 4}},
+		{"remainder", map[string]int64{"a": 100, "b": 100, "c": 100}, 10, nil},
+	}
+	for _, test := range tests {
+		strata := map[string]*stratum{}
+		var keys []string
+		for key, rows := range test.rows {
+			strata[key] = &stratum{rows: rows}
+			keys = append(keys, key)
+		}
+		allocateStrata(strata, keys, test.limit)
+		total := int64(0)
+		got := map[string]int64{}
+		for key, st := range strata {
+			got[key] = st.quota
+			total += st.quota
+		}
+		if test.want != nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: quotas = %v, want %v", test.name, got, test.want)
+		}
+		if test.want == nil && total != test.limit {
+			t.Errorf("%s: quotas = %v, want %d rows", test.name, got, test.limit)
+		}
+	}
+}
+
+func TestAllocateStrataMoreValuesThanRows(t *testing.T) {
+	strata := map[string]*stratum{}
+	var keys []string
+	for _, key := range []string{"", "=a", "=b", "=c", "=d"} {
+		strata[key] = &stratum{rows: 10}
+		keys = append(keys, key)
+	}
+	allocateStrata(strata, keys, 3)
+	count := 0
+	for _, st := range strata {
+		if st.quota > 1 {
+			t.Errorf("quota = %d, want at most 1", st.quota)
+		}
+		count += int(st.quota)
+	}
+	if count != 3 {
+		t.Errorf("%d strata sampled, want 3", count)
+	}
+}
+
+func TestRandomSamplerFullKeyRange(t *testing.T) {
+	info := &TableInfo{Name: "t", Columns: []ColumnInfo{{Name: "id"}}}
+	s := &randomSampler{info: info, key: "id", min: math.MinInt64, max: math.MaxInt64, limit: 5, drawn: map[int64]bool{}}
+	s.size = uint64(s.max) - uint64(s.min) + 1
+	if s.size != 0 {
+		t.Fatalf("size = %d, want 0 for the whole int64 range", s.size)
+	}
+	q, ok, err := s.next(0)
+	if err != nil || !ok {
+		t.Fatalf("next = %v, %v", ok, err)
+	}
+	if len(q.args) != 6 {
+		t.Errorf("%d keys probed, want 6", len(q.args))
+	}
+}
+
+func TestRandomSamplerDenseKeys(t *testing.T) {
+	info := &TableInfo{Name: "t", Columns: []ColumnInfo{{Name: "id"}}, EstimatedRows: 3}
+	s := &randomSampler{info: info, key: "id", min: 1, max: 3, size: 3, limit: 10, drawn: map[int64]bool{}}
+	q, _, _ := s.next(0)
+	keys := map[interface{}]bool{}
+	for _, key := range q.args {
+		keys[key] = true
+	}
+	if want := map[interface{}]bool{int64(1): true, int64(2): true, int64(3): true}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, want every key once", q.args)
+	}
+	if _, ok, _ := s.next(3); ok {
+		t.Error("next after every key was drawn = true, want false")
+	}
+}
+
+func TestRandomSamplerSparseKeys(t *testing.T) {
+	recorder := &eventRecorder{}
+	e := &Exporter{config: Config{Observer: recorder}}
+	info := &TableInfo{Name: "t", Columns: []ColumnInfo{{Name: "id"}, {Name: "v"}}, EstimatedRows: 1000}
+
+	// The statistics show that probing cannot find the rows
+	s := &randomSampler{e: e, info: info, key: "id", min: 1, max: 1 << 60, size: 1 << 60, limit: 100, drawn: map[int64]bool{}}
+	q, ok, _ := s.next(0)
+	if want := randomScan("t", 0.2, 100, ""); !ok || q.query != want.query || !reflect.DeepEqual(q.args, want.args) {
+		t.Errorf("next = %q %v, want %q %v", q.query, q.args, want.query, want.args)
+	}
+	if _, ok, _ := s.next(0); ok {
+		t.Error("next after the scan = true, want false")
+	}
+
+	// Probing finds fewer rows than the statistics promised
+	s = &randomSampler{e: e, info: info, key: "id", min: 1, max: 1000, size: 1000, limit: 100, drawn: map[int64]bool{}}
+	q, _, _ = s.next(0)
+	if q.keep == nil || strings.HasPrefix(q.query, "SELECT * FROM (") {
+		t.Fatalf("first query = %q, want a key lookup", q.query)
+	}
+	q.keep([][]byte{[]byte("42"), []byte("x")})
+	s.probed = maxProbeKeys * maxProbeRounds / 2
+	q, _, _ = s.next(1)
+	want := "SELECT * FROM (SELECT * FROM `t` WHERE RAND() < ? AND `id` NOT IN (?)) AS `sample` ORDER BY RAND() LIMIT 99"
+	if q.query != want || q.args[1] != int64(42) {
+		t.Errorf("fallback = %q %v, want %q excluding 42", q.query, q.args, want)
+	}
+	if len(recorder.events) != 2 {
+		t.Errorf("%d warnings, want 2", len(recorder.events))
+	}
+}
+
+func TestNewRandomSampler(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataType string
+		min, max driver.Value
+		want     bool
+	}{
+		{"integer key", "int", int64(1), int64(100), true},
+		{"bigint key", "BIGINT", int64(-5), int64(5), true},
+		// Numeric strings scan into integers but are ordered as strings
+		{"numeric string key", "varchar", "1", "100", false},
+		{"decimal key", "decimal", "1", "100", false},
+		{"minimum above maximum", "int", int64(100), int64(1), false},
+		{"unsigned beyond int64", "bigint", "1", "18446744073709551615", false},
+	}
+	for _, test := range tests {
+		e, f := newFakeExporter(t, Config{}, func(string) fakeResult {
+			return fakeResult{columns: []string{"min", "max"}, rows: [][]driver.Value{{test.min, test.max}}}
+		})
+		info := &TableInfo{Name: "t", Columns: []ColumnInfo{{Name: "id", DataType: test.dataType}, {Name: "v"}}}
+		s, ok, err := e.newRandomSampler(info, "id", 10)
+		if err != nil || ok != test.want {
+			t.Errorf("%s: newRandomSampler = %v, %v, want %v", test.name, ok, err, test.want)
+			continue
+		}
+		if !test.want {
+			continue
+		}
+		r := s.(*randomSampler)
+		if r.min != test.min || r.max != test.max || r.keyIndex != 0 {
+			t.Errorf("%s: sampler of %d..%d at column %d, want %v..%v at 0", test.name, r.min, r.max, r.keyIndex, test.min, test.max)
+		}
+		if len(f.sent()) != 1 {
+			t.Errorf("%s: queries = %q, want one", test.name, f.sent())
+		}
+	}
+
+	// The type decides before the key range is read
+	e, f := newFakeExporter(t, Config{}, func(string) fakeResult { return fakeResult{} })
+	info := &TableInfo{Name: "t", Columns: []ColumnInfo{{Name: "code", DataType: "char"}}}
+	if _, ok, _ := e.newRandomSampler(info, "code", 10); ok || len(f.sent()) > 0 {
+		t.Errorf("string key: ok = %v, queries = %q, want false and none", ok, f.sent())
+	}
+}
+
+func TestStratifiedSampler(t *testing.T) {
+	e, f := newFakeExporter(t, Config{}, func(string) fakeResult {
+		return fakeResult{columns: []string{"value", "count"}, rows: [][]driver.Value{{"a", "3"}, {"b", "1"}}}
+	})
+	s, err := e.newStratifiedSampler("t", "kind", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ANY_VALUE is missing from MySQL 5.7 and MariaDB
+	want := "SELECT MIN(`kind`), COUNT(*) FROM `t` GROUP BY CAST(`kind` AS BINARY)"
+	if sent := f.sent(); len(sent) != 1 || sent[0] != want {
+		t.Errorf("queries = %q, want %q", sent, want)
+	}
+
+	q, ok, err := s.next(0)
+	if err != nil || !ok || q.keep == nil {
+		t.Fatalf("next = %v, %v, want a filtered scan", ok, err)
+	}
+	kept := map[string]int{}
+	for i, kind := range []string{"a", "b", "a", "a"} {
+		if q.keep([][]byte{[]byte(fmt.Sprint(i)), []byte(kind)}) {
+			kept[kind]++
+		}
+	}
+	if want := map[string]int{"a": 1, "b": 1}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept = %v, want %v", kept, want)
+	}
+}
+
+// transientRows answers the queries of a table read with results in turn,
+// after the last one the table is empty
+func transientRows(results ...fakeResult) func(query string) fakeResult {
+	next := 0
+	return func(query string) fakeResult {
+		if next == len(results) {
+			return fakeResult{columns: []string{"id", "v"}}
+		}
+		next++
+		return results[next-1]
+	}
+}
+
+// idRows returns rows of the columns id and v with the given ids
+func idRows(ids ...string) [][]driver.Value {
+	rows := make([][]driver.Value, len(ids))
+	for i, id := range ids {
+		rows[i] = []driver.Value{id, "v" + id}
+	}
+	return rows
+}
+
+func TestScanTableResumes(t *testing.T) {
+	columns := []string{"id", "v"}
+	dropped := io.ErrUnexpectedEOF
+	tests := []struct {
+		name       string
+		primaryKey []string
+		maxRows    int
+		retries    int
+		results    []fakeResult
+		// want are the ids read, queries the queries sent with the
+		// argument of the resumed ones
+		want    []string
+		queries []string
+		wantErr bool
+	}{
+		{"resumed", []string{"id"}, 0, 2,
+			[]fakeResult{{columns: columns, rows: idRows("1", "2", "3"), rowsErr: dropped}, {columns: columns, rows: idRows("4", "5")}},
+			[]string{"1", "2", "3", "4", "5"},
+			[]string{"SELECT * FROM `t` ORDER BY `id`", "SELECT * FROM `t` WHERE (`id`) > (?) ORDER BY `id` [3]"}, false},
+		// The resumed query only reads the rest of the limit
+		{"resumed with a limit", []string{"id"}, 4, 2,
+			[]fakeResult{{columns: columns, rows: idRows("1", "2", "3"), rowsErr: dropped}, {columns: columns, rows: idRows("4")}},
+			[]string{"1", "2", "3", "4"},
+			[]string{"SELECT * FROM `t` ORDER BY `id` LIMIT 4", "SELECT * FROM `t` WHERE (`id`) > (?) ORDER BY `id` LIMIT 1 [3]"}, false},
+		{"no retries left", []string{"id"}, 0, 1,
+			[]fakeResult{{columns: columns, rows: idRows("1", "2"), rowsErr: dropped}, {columns: columns, rows: idRows("3"), rowsErr: dropped}},
+			[]string{"1", "2", "3"},
+			[]string{"SELECT * FROM `t` ORDER BY `id`", "SELECT * FROM `t` WHERE (`id`) > (?) ORDER BY `id` [2]"}, true},
+		{"not transient", []string{"id"}, 0, 2,
+			[]fakeResult{{columns: columns, rows: idRows("1"), rowsErr: errors.New("fakeDB: syntax error")}},
+			[]string{"1"},
+			[]string{"SELECT * FROM `t` ORDER BY `id`"}, true},
+		// Without a key order the rows read cannot be skipped
+		{"not resumable", nil, 0, 2,
+			[]fakeResult{{columns: columns, rows: idRows("1", "2"), rowsErr: dropped}},
+			[]string{"1", "2"},
+			[]string{"SELECT * FROM `t`"}, true},
+	}
+	for _, test := range tests {
+		e, f := newFakeExporter(t, Config{MaxRows: test.maxRows, Retries: test.retries}, transientRows(test.results...))
+		info := &TableInfo{Name: "t", PrimaryKey: test.primaryKey, Columns: []ColumnInfo{{Name: "id"}, {Name: "v"}}}
+		var ids []string
+		rows, err := e.scanTable(info, func(s *rowScanner) error {
+			ids = append(ids, string(s.raw[0]))
+			return nil
+		})
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: scanTable = %v, want error %v", test.name, err, test.wantErr)
+		}
+		if !reflect.DeepEqual(ids, test.want) || rows != len(test.want) {
+			t.Errorf("%s: read %q, counted %d, want %q", test.name, ids, rows, test.want)
+		}
+		var queries []string
+		for i, query := range f.sent() {
+			for _, arg := range f.sentArgs()[i] {
+				query += fmt.Sprintf(" [%s]", arg)
+			}
+			queries = append(queries, query)
+		}
+		if !reflect.DeepEqual(queries, test.queries) {
+			t.Errorf("%s: queries = %q, want %q", test.name, queries, test.queries)
+		}
+	}
+}
+
+func TestScanTableRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e, f := newFakeExporter(t, Config{Retries: 5}, func(string) fakeResult {
+		// The run is canceled while it waits to retry
+		time.AfterFunc(50*time.Millisecond, cancel)
+		return fakeResult{columns: []string{"id", "v"}, rows: idRows("1"), rowsErr: io.ErrUnexpectedEOF}
+	})
+	defer e.bind(ctx)()
+	info := &TableInfo{Name: "t", PrimaryKey: []string{"id"}, Columns: []ColumnInfo{{Name: "id"}, {Name: "v"}}}
+
+	start := time.Now()
+	if _, err := e.scanTable(info, func(*rowScanner) error { return nil }); err == nil {
+		t.Fatal("scanTable of a canceled run succeeded")
+	}
+	if elapsed := time.Since(start); elapsed >= retryDelay {
+		t.Errorf("scanTable returned after %v, want the wait cut short", elapsed)
+	}
+	if len(f.sent()) != 1 {
+		t.Errorf("queries = %q, want no retry", f.sent())
+	}
+}
+
+func TestScanTableThrottles(t *testing.T) {
+	e, _ := newFakeExporter(t, Config{}, func(query string) fakeResult {
+		if strings.HasPrefix(query, "SHOW GLOBAL STATUS") {
+			return fakeResult{err: errors.New("fakeDB: access denied")}
+		}
+		return fakeResult{columns: []string{"id", "v"}, rows: [][]driver.Value{{"1", "ab"}, {"2", "cde"}, {"3", nil}}}
+	})
+	e.throttler = &throttler{rowsPerSecond: 1e6, lastCheck: time.Now()}
+	info := &TableInfo{Name: "t", Columns: []ColumnInfo{{Name: "id"}, {Name: "v"}}}
+	rows, err := e.scanTable(info, func(*rowScanner) error { return nil })
+	if err != nil || rows != 3 {
+		t.Fatalf("scanTable = %d, %v", rows, err)
+	}
+	if e.throttler.rows != 3 || e.throttler.bytes != 8 {
+		t.Errorf("throttled %d rows and %d bytes, want 3 and 8", e.throttler.rows, e.throttler.bytes)
+	}
+
+	// Throttling that fails is not mistaken for a failed read
+	e.throttler = &throttler{maxLoad: []loadLimit{{"Threads_running", 25}}}
+	_, err = e.scanTable(info, func(*rowScanner) error { return nil })
+	var throttled *throttleError
+	if !errors.As(err, &throttled) {
+		t.Errorf("scanTable = %v, want a throttleError", err)
+	}
+}
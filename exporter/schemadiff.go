@@ -0,0 +1,609 @@
+package exporter
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Schema is a normalized model of the tables, views and routines of a database
+type Schema struct {
+	Tables map[string]*TableDef
+	// Views and Routines hold the CREATE statements, routines are keyed by "PROCEDURE name" or "FUNCTION name".
+	// Routines is nil when the schema does not record them, like a schema.sql written by an export.
+	Views    map[string]string
+	Routines map[string]string
+}
+
+// TableDef is a parsed CREATE TABLE statement
+type TableDef struct {
+	Name    string
+	Columns []SchemaItem
+	Indexes []SchemaItem
+	// Options are the normalized table options, sorted and without AUTO_INCREMENT
+	Options []string
+	// Create is the original statement with the auto-increment value reset
+	Create string
+}
+
+// SchemaItem is a named column or index definition
+type SchemaItem struct {
+	Name       string
+	Kind       string
+	Definition string
+}
+
+// SchemaDiff lists the differences between a source and a target schema.
+// Changes are described from the source's point of view: added objects only
+// exist in the target, removed objects only exist in the source.
+type SchemaDiff struct {
+	AddedTables   []string
+	RemovedTables []string
+	ChangedTables []TableDiff
+
+	AddedViews   []string
+	RemovedViews []string
+	ChangedViews []string
+
+	AddedRoutines   []string
+	RemovedRoutines []string
+	ChangedRoutines []string
+	// RoutinesNotCompared is set when one of the schemas does not record
+	// routines, which are then neither listed nor changed by AlterScript
+	RoutinesNotCompared bool
+
+	source, target *Schema
+}
+
+// TableDiff lists the differences of a table present in both schemas
+type TableDiff struct {
+	Table          string
+	AddedColumns   []string
+	RemovedColumns []string
+	ChangedColumns []string
+	AddedIndexes   []string
+	RemovedIndexes []string
+	ChangedIndexes []string
+	SourceOptions  []string
+	TargetOptions  []string
+}
+
+// Empty reports whether the schemas are equivalent
+func (d *SchemaDiff) Empty() bool {
+	return len(d.AddedTables)+len(d.RemovedTables)+len(d.ChangedTables)+
+		len(d.AddedViews)+len(d.RemovedViews)+len(d.ChangedViews)+
+		len(d.AddedRoutines)+len(d.RemovedRoutines)+len(d.ChangedRoutines) == 0
+}
+
+// LoadSchema reads the schema of the connected database
+func (e *Exporter) LoadSchema() (*Schema, error) {
+	schema := newSchema()
+
+	tables, err := e.loadMetadata()
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		create, err := e.showCreate(table.Name, table.IsView)
+		if err != nil {
+			return nil, err
+		}
+		if table.IsView {
+			schema.Views[table.Name] = create
+		} else {
+			schema.Tables[table.Name] = parseCreateTable(table.Name, create)
+		}
+	}
+
+	if err := e.loadRoutines(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// loadRoutines reads the stored procedures and functions of the connected database
+func (e *Exporter) loadRoutines(schema *Schema) error {
+	rows, err := e.q.Query("SELECT ROUTINE_TYPE, ROUTINE_NAME FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ?",
+		e.config.Database)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrGetRoutines, err)
+	}
+	var routines [][2]string
+	for rows.Next() {
+		var routineType, name string
+		if err := rows.Scan(&routineType, &name); err != nil {
+			rows.Close()
+			return fmt.Errorf(msgs.ErrGetRoutines, err)
+		}
+		routines = append(routines, [2]string{routineType, name})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf(msgs.ErrGetRoutines, err)
+	}
+
+	for _, routine := range routines {
+		create, err := e.showCreateRoutine(routine[0], routine[1])
+		if err != nil {
+			return err
+		}
+		schema.Routines[routine[0]+" "+routine[1]] = create
+	}
+	return nil
+}
+
+// showCreateRoutine returns the CREATE statement of a stored procedure or function
+func (e *Exporter) showCreateRoutine(routineType, name string) (string, error) {
+	rows, err := e.q.Query("SHOW CREATE " + routineType + " " + quoteIdent(name))
+	if err != nil {
+		return "", fmt.Errorf(msgs.ErrGetRoutineCreateStmt, name, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf(msgs.ErrGetRoutineCreateStmt, name, err)
+	}
+	if !rows.Next() {
+		return "", fmt.Errorf(msgs.ErrGetRoutineCreateStmt, name, sql.ErrNoRows)
+	}
+	values := make([]sql.NullString, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return "", fmt.Errorf(msgs.ErrGetRoutineCreateStmt, name, err)
+	}
+	for i, column := range columns {
+		if strings.HasPrefix(column, "Create ") {
+			return values[i].String, nil
+		}
+	}
+	return "", fmt.Errorf(msgs.ErrGetRoutineCreateStmt, name, sql.ErrNoRows)
+}
+
+// ParseSchemaFile reads the schema from a SQL script such as an exported
+// schema.sql. Exports do not write routines, so the routines of the script
+// are not read and not compared.
+func ParseSchemaFile(path string) (*Schema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(msgs.ErrOpenFile, path, err)
+	}
+
+	schema := newSchema()
+	schema.Routines = nil
+	for _, statement := range splitStatements(string(content)) {
+		tokens := significant(tokenize(statement))
+		if len(tokens) < 3 || !tokens[0].is("CREATE") {
+			continue
+		}
+		kind, name := createdObject(tokens)
+		switch kind {
+		case "TABLE":
+			schema.Tables[name] = parseCreateTable(name, statement)
+		case "VIEW":
+			schema.Views[name] = statement
+		}
+	}
+	return schema, nil
+}
+
+// createdObject returns the kind and name of the object created by a CREATE statement
+func createdObject(tokens []token) (string, string) {
+	for i := 1; i < len(tokens); i++ {
+		for _, kind := range []string{"TABLE", "VIEW", "PROCEDURE", "FUNCTION"} {
+			if !tokens[i].is(kind) {
+				continue
+			}
+			j := i + 1
+			// Skip IF NOT EXISTS
+			for j < len(tokens) && (tokens[j].is("IF") || tokens[j].is("NOT") || tokens[j].is("EXISTS")) {
+				j++
+			}
+			// Qualified names use the last part
+			for j+2 < len(tokens) && tokens[j+1].is(".") {
+				j += 2
+			}
+			if j < len(tokens) {
+				return kind, tokens[j].value()
+			}
+		}
+	}
+	return "", ""
+}
+
+func newSchema() *Schema {
+	return &Schema{
+		Tables:   map[string]*TableDef{},
+		Views:    map[string]string{},
+		Routines: map[string]string{},
+	}
+}
+
+// parseCreateTable parses the output of SHOW CREATE TABLE into its columns, indexes and options
+func parseCreateTable(name, create string) *TableDef {
+	table := &TableDef{Name: name, Create: resetAutoIncrement(create)}
+	tokens := tokenize(create)
+
+	// Find the parenthesized definition list
+	open := -1
+	for i, t := range tokens {
+		if t.is("(") {
+			open = i
+			break
+		}
+	}
+	if open < 0 {
+		return table
+	}
+
+	depth := 0
+	itemStart := open + 1
+	closeIndex := len(tokens) - 1
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].is("("):
+			depth++
+		case tokens[i].is(")"):
+			depth--
+			if depth == 0 {
+				table.addItem(tokens[itemStart:i])
+				closeIndex = i
+			}
+		case tokens[i].is(",") && depth == 1:
+			table.addItem(tokens[itemStart:i])
+			itemStart = i + 1
+		}
+		if depth == 0 {
+			break
+		}
+	}
+
+	table.Options = parseTableOptions(tokens[closeIndex+1:])
+	return table
+}
+
+// addItem classifies one entry of the definition list as a column or an index
+func (t *TableDef) addItem(tokens []token) {
+	definition := normalizeSQL(joinTokens(tokens))
+	words := significant(tokens)
+	if len(words) == 0 {
+		return
+	}
+
+	first := words[0]
+	if first.kind == tokenIdent {
+		t.Columns = append(t.Columns, SchemaItem{Name: first.value(), Kind: "COLUMN", Definition: definition})
+		return
+	}
+
+	kind := strings.ToUpper(first.text)
+	name := ""
+	switch {
+	case first.is("PRIMARY"):
+		name = "PRIMARY"
+	case first.is("CONSTRAINT"):
+		// CONSTRAINT `name` FOREIGN KEY ... or CONSTRAINT `name` CHECK ...
+		if len(words) > 2 {
+			name = words[1].value()
+			kind = strings.ToUpper(words[2].text)
+		}
+	default:
+		for _, w := range words[1:] {
+			if w.kind == tokenIdent {
+				name = w.value()
+				break
+			}
+		}
+	}
+	t.Indexes = append(t.Indexes, SchemaItem{Name: name, Kind: kind, Definition: definition})
+}
+
+// parseTableOptions normalizes table options: the auto-increment counter is
+// dropped, DEFAULT prefixes are removed and the options are sorted
+func parseTableOptions(tokens []token) []string {
+	var options []string
+	var words []token
+	for _, t := range tokens {
+		switch {
+		case t.kind == tokenComment && strings.HasPrefix(t.text, "/*!"):
+			// Versioned comments carry partitioning
+			options = append(options, strings.Join(strings.Fields(t.text), " "))
+		case t.kind != tokenSpace && t.kind != tokenComment:
+			words = append(words, t)
+		}
+	}
+
+	for i := 0; i < len(words); {
+		start := i
+		var key []string
+		for i < len(words) && words[i].kind == tokenWord {
+			if !words[i].is("DEFAULT") {
+				key = append(key, strings.ToUpper(words[i].text))
+			}
+			i++
+			if i < len(words) && words[i].is("=") {
+				break
+			}
+		}
+		if i >= len(words) || !words[i].is("=") || i+1 >= len(words) {
+			// Anything that is not KEY=value, such as PARTITION BY, is kept as it is
+			options = append(options, normalizeSQL(joinTokens(words[start:])))
+			break
+		}
+		value := words[i+1].text
+		i += 2
+
+		option := strings.Join(key, " ")
+		switch option {
+		case "AUTO_INCREMENT":
+			continue
+		case "CHARACTER SET":
+			option = "CHARSET"
+		}
+		options = append(options, option+"="+value)
+	}
+	sort.Strings(options)
+	return options
+}
+
+// normalizeView normalizes a view or routine definition. The definer and SQL
+// security characteristics differ between environments and are ignored.
+func normalizeView(create string) string {
+	tokens := significant(tokenize(create))
+	var kept []token
+	for i := 0; i < len(tokens); i++ {
+		switch {
+		case tokens[i].is("DEFINER") && i+1 < len(tokens) && tokens[i+1].is("="):
+			i += 2
+			// user@host, CURRENT_USER or CURRENT_USER()
+			if i+2 < len(tokens) && tokens[i+1].is("@") {
+				i += 2
+			} else if i+2 < len(tokens) && tokens[i+1].is("(") && tokens[i+2].is(")") {
+				i += 2
+			}
+		case tokens[i].is("SQL") && i+2 < len(tokens) && tokens[i+1].is("SECURITY"):
+			i += 2
+		case tokens[i].is("ALGORITHM") && i+2 < len(tokens) && tokens[i+1].is("="):
+			i += 2
+		default:
+			kept = append(kept, tokens[i])
+		}
+	}
+	var parts []string
+	for _, t := range kept {
+		parts = append(parts, t.text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// DiffSchemas compares a source schema with a target schema
+func DiffSchemas(source, target *Schema) *SchemaDiff {
+	diff := &SchemaDiff{source: source, target: target}
+
+	for _, name := range sortedKeys(target.Tables) {
+		if _, ok := source.Tables[name]; !ok {
+			diff.AddedTables = append(diff.AddedTables, name)
+		}
+	}
+	for _, name := range sortedKeys(source.Tables) {
+		targetTable, ok := target.Tables[name]
+		if !ok {
+			diff.RemovedTables = append(diff.RemovedTables, name)
+			continue
+		}
+		if tableDiff := diffTables(source.Tables[name], targetTable); tableDiff != nil {
+			diff.ChangedTables = append(diff.ChangedTables, *tableDiff)
+		}
+	}
+
+	diff.AddedViews, diff.RemovedViews, diff.ChangedViews = diffDefinitions(source.Views, target.Views)
+	if source.Routines == nil || target.Routines == nil {
+		diff.RoutinesNotCompared = true
+	} else {
+		diff.AddedRoutines, diff.RemovedRoutines, diff.ChangedRoutines = diffDefinitions(source.Routines, target.Routines)
+	}
+	return diff
+}
+
+// diffTables compares two versions of a table, it returns nil when they are equivalent
+func diffTables(source, target *TableDef) *TableDiff {
+	diff := &TableDiff{Table: source.Name}
+	diff.AddedColumns, diff.RemovedColumns, diff.ChangedColumns = diffItems(source.Columns, target.Columns)
+	diff.AddedIndexes, diff.RemovedIndexes, diff.ChangedIndexes = diffItems(source.Indexes, target.Indexes)
+	if strings.Join(source.Options, " ") != strings.Join(target.Options, " ") {
+		diff.SourceOptions = source.Options
+		diff.TargetOptions = target.Options
+	}
+
+	if len(diff.AddedColumns)+len(diff.RemovedColumns)+len(diff.ChangedColumns)+
+		len(diff.AddedIndexes)+len(diff.RemovedIndexes)+len(diff.ChangedIndexes)+
+		len(diff.SourceOptions)+len(diff.TargetOptions) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// diffItems compares named definitions, keeping the order of the target
+func diffItems(source, target []SchemaItem) (added, removed, changed []string) {
+	sourceByName := map[string]SchemaItem{}
+	for _, item := range source {
+		sourceByName[item.Name] = item
+	}
+	targetByName := map[string]bool{}
+	for _, item := range target {
+		targetByName[item.Name] = true
+		sourceItem, ok := sourceByName[item.Name]
+		switch {
+		case !ok:
+			added = append(added, item.Name)
+		case sourceItem.Definition != item.Definition:
+			changed = append(changed, item.Name)
+		}
+	}
+	for _, item := range source {
+		if !targetByName[item.Name] {
+			removed = append(removed, item.Name)
+		}
+	}
+	return added, removed, changed
+}
+
+// diffDefinitions compares named view or routine definitions
+func diffDefinitions(source, target map[string]string) (added, removed, changed []string) {
+	for _, name := range sortedKeys(target) {
+		definition, ok := source[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case normalizeView(definition) != normalizeView(target[name]):
+			changed = append(changed, name)
+		}
+	}
+	for _, name := range sortedKeys(source) {
+		if _, ok := target[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed, changed
+}
+
+// AlterScript returns the statements that turn the source schema into the
+// target schema. The script is meant to be run on the source database, the
+// target is the desired state.
+func (d *SchemaDiff) AlterScript() string {
+	var b strings.Builder
+	b.WriteString("SET FOREIGN_KEY_CHECKS=0;\n\n")
+
+	// Views may depend on tables, so they are dropped first and created last
+	for _, name := range append(append([]string{}, d.RemovedViews...), d.ChangedViews...) {
+		fmt.Fprintf(&b, "DROP VIEW IF EXISTS %s;\n", quoteIdent(name))
+	}
+	for _, name := range append(append([]string{}, d.RemovedRoutines...), d.ChangedRoutines...) {
+		fmt.Fprintf(&b, "DROP %s IF EXISTS %s;\n", routineKind(name), quoteIdent(routineName(name)))
+	}
+	for _, name := range d.RemovedTables {
+		fmt.Fprintf(&b, "DROP TABLE IF EXISTS %s;\n", quoteIdent(name))
+	}
+	for _, name := range d.AddedTables {
+		fmt.Fprintf(&b, "%s;\n", d.target.Tables[name].Create)
+	}
+	for _, table := range d.ChangedTables {
+		if clauses := d.alterClauses(table); len(clauses) > 0 {
+			fmt.Fprintf(&b, "ALTER TABLE %s\n  %s;\n", quoteIdent(table.Table), strings.Join(clauses, ",\n  "))
+		}
+	}
+	for _, name := range append(append([]string{}, d.AddedViews...), d.ChangedViews...) {
+		fmt.Fprintf(&b, "%s;\n", d.target.Views[name])
+	}
+	for _, name := range append(append([]string{}, d.AddedRoutines...), d.ChangedRoutines...) {
+		fmt.Fprintf(&b, "DELIMITER ;;\n%s;;\nDELIMITER ;\n", d.target.Routines[name])
+	}
+
+	b.WriteString("\nSET FOREIGN_KEY_CHECKS=1;\n")
+	return b.String()
+}
+
+// alterClauses builds the ALTER TABLE clauses for a changed table
+func (d *SchemaDiff) alterClauses(table TableDiff) []string {
+	source := d.source.Tables[table.Table]
+	target := d.target.Tables[table.Table]
+	var clauses []string
+
+	// Indexes are dropped before columns, since dropping a column may fail
+	// while an index or foreign key still uses it
+	for _, name := range append(append([]string{}, table.RemovedIndexes...), table.ChangedIndexes...) {
+		clauses = append(clauses, dropIndexClause(findItem(source.Indexes, name)))
+	}
+	for _, name := range table.RemovedColumns {
+		clauses = append(clauses, "DROP COLUMN "+quoteIdent(name))
+	}
+
+	previous := ""
+	for _, column := range target.Columns {
+		position := " FIRST"
+		if previous != "" {
+			position = " AFTER " + quoteIdent(previous)
+		}
+		previous = column.Name
+		if contains(table.AddedColumns, column.Name) {
+			clauses = append(clauses, "ADD COLUMN "+column.Definition+position)
+		} else if contains(table.ChangedColumns, column.Name) {
+			clauses = append(clauses, "MODIFY COLUMN "+column.Definition)
+		}
+	}
+
+	for _, name := range append(append([]string{}, table.AddedIndexes...), table.ChangedIndexes...) {
+		clauses = append(clauses, "ADD "+findItem(target.Indexes, name).Definition)
+	}
+	if len(table.TargetOptions) > 0 {
+		// Partitioning cannot be changed together with other table options
+		var options []string
+		for _, option := range table.TargetOptions {
+			if !contains(table.SourceOptions, option) && !strings.HasPrefix(option, "PARTITION") &&
+				!strings.HasPrefix(option, "/*") {
+				options = append(options, option)
+			}
+		}
+		if len(options) > 0 {
+			clauses = append(clauses, strings.Join(options, " "))
+		}
+	}
+	return clauses
+}
+
+// dropIndexClause returns the clause that drops an index or constraint
+func dropIndexClause(item SchemaItem) string {
+	switch item.Kind {
+	case "PRIMARY":
+		return "DROP PRIMARY KEY"
+	case "FOREIGN":
+		return "DROP FOREIGN KEY " + quoteIdent(item.Name)
+	case "CHECK":
+		return "DROP CHECK " + quoteIdent(item.Name)
+	default:
+		return "DROP INDEX " + quoteIdent(item.Name)
+	}
+}
+
+func findItem(items []SchemaItem, name string) SchemaItem {
+	for _, item := range items {
+		if item.Name == name {
+			return item
+		}
+	}
+	return SchemaItem{Name: name}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// routineKind and routineName split the "PROCEDURE name" keys of Schema.Routines
+func routineKind(key string) string {
+	kind, _, _ := strings.Cut(key, " ")
+	return kind
+}
+
+func routineName(key string) string {
+	_, name, _ := strings.Cut(key, " ")
+	return name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,146 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseCreateTable(t *testing.T) {
+	table := parseCreateTable("orders", ordersTable)
+
+	var columns []string
+	for _, column := range table.Columns {
+		columns = append(columns, column.Name)
+	}
+	if want := []string{"id", "note", "customer_id", "created_at"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns = %q, want %q", columns, want)
+	}
+	if got, want := table.Columns[1].Definition,
+		"`note` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT 'AUTO_INCREMENT=5; -- not a comment'"; got != want {
+		t.Errorf("note definition = %q, want %q", got, want)
+	}
+
+	var indexes []string
+	for _, index := range table.Indexes {
+		indexes = append(indexes, index.Kind+" "+index.Name)
+	}
+	if want := []string{"PRIMARY PRIMARY", "KEY idx_customer", "FOREIGN fk_customer"}; !reflect.DeepEqual(indexes, want) {
+		t.Errorf("indexes = %q, want %q", indexes, want)
+	}
+
+	// The counter is dropped, DEFAULT is removed and the options are sorted
+	want := []string{"CHARSET=utf8mb4", "COLLATE=utf8mb4_unicode_ci", "COMMENT='orders, AUTO_INCREMENT=7'", "ENGINE=InnoDB"}
+	if !reflect.DeepEqual(table.Options, want) {
+		t.Errorf("options = %q, want %q", table.Options, want)
+	}
+}
+
+func TestParseTableOptionsPartitioning(t *testing.T) {
+	create := "CREATE TABLE `logs` (\n  `id` int NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1\n" +
+		"/*!50100 PARTITION BY HASH (`id`)\nPARTITIONS 4 */"
+	want := []string{"/*!50100 PARTITION BY HASH (`id`) PARTITIONS 4 */", "CHARSET=latin1", "ENGINE=InnoDB"}
+	if got := parseCreateTable("logs", create).Options; !reflect.DeepEqual(got, want) {
+		t.Errorf("options = %q, want %q", got, want)
+	}
+}
+
+func TestDiffSchemasIgnoresCounterAndFormatting(t *testing.T) {
+	target := strings.ReplaceAll(ordersTable, "AUTO_INCREMENT=1042", "AUTO_INCREMENT=7")
+	target = strings.ReplaceAll(target, "  `customer_id`", "\t`customer_id`")
+	source := &Schema{Tables: map[string]*TableDef{"orders": parseCreateTable("orders", ordersTable)}}
+	other := &Schema{Tables: map[string]*TableDef{"orders": parseCreateTable("orders", target)}}
+	if diff := DiffSchemas(source, other); !diff.Empty() {
+		t.Errorf("diff = %+v, want no differences", diff)
+	}
+}
+
+func TestAlterScript(t *testing.T) {
+	target := strings.Replace(ordersTable, "  PRIMARY KEY (`id`),\n",
+		"  `status` enum('new','paid') NOT NULL DEFAULT 'new',\n  PRIMARY KEY (`id`),\n  KEY `idx_status` (`status`),\n", 1)
+	target = strings.Replace(target, "  KEY `idx_customer` (`customer_id`),\n", "", 1)
+	target = strings.Replace(target, "ENGINE=InnoDB", "ENGINE=MyISAM", 1)
+	source := &Schema{Tables: map[string]*TableDef{
+		"orders":  parseCreateTable("orders", ordersTable),
+		"old`one": parseCreateTable("old`one", "CREATE TABLE `old``one` (`a` int)"),
+	}}
+	other := &Schema{Tables: map[string]*TableDef{"orders": parseCreateTable("orders", target)}}
+
+	diff := DiffSchemas(source, other)
+	if want := []string{"old`one"}; !reflect.DeepEqual(diff.RemovedTables, want) {
+		t.Errorf("removed tables = %q, want %q", diff.RemovedTables, want)
+	}
+	script := diff.AlterScript()
+	for _, want := range []string{
+		"DROP TABLE IF EXISTS `old``one`;\n",
+		"ALTER TABLE `orders`\n  DROP INDEX `idx_customer`,\n" +
+			"  ADD COLUMN `status` enum('new','paid') NOT NULL DEFAULT 'new' AFTER `created_at`,\n" +
+			"  ADD KEY `idx_status` (`status`),\n  ENGINE=MyISAM;\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script does not contain %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestParseSchemaFile(t *testing.T) {
+	content := "-- MySQL导出 表结构导出\n\nSET FOREIGN_KEY_CHECKS=0;\n\n" +
+		"DROP TABLE IF EXISTS `orders`;\n" + ordersTable + ";\n\n" +
+		"DROP VIEW IF EXISTS `paid`;\n" +
+		"CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`10.0.%` SQL SECURITY DEFINER VIEW `paid` AS select 1 AS `x`;\n\n" +
+		"DELIMITER ;;\n" +
+		"CREATE DEFINER=`root`@`localhost` PROCEDURE `cleanup`(IN days int)\nBEGIN\n" +
+		"  DELETE FROM `orders` WHERE created_at < NOW() - INTERVAL days DAY;\nEND ;;\n" +
+		"DELIMITER ;\n"
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := ParseSchemaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := append(sortedKeys(schema.Tables), sortedKeys(schema.Views)...)
+	if want := []string{"orders", "paid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("objects = %q, want %q", got, want)
+	}
+	if schema.Routines != nil {
+		t.Errorf("routines = %q, want none read", sortedKeys(schema.Routines))
+	}
+
+	// The routines of the source are not dropped for missing from the file
+	source := newSchema()
+	source.Tables["orders"] = parseCreateTable("orders", ordersTable)
+	source.Views["paid"] = "CREATE VIEW `paid` AS select 1 AS `x`"
+	source.Routines["PROCEDURE cleanup"] = "CREATE PROCEDURE `cleanup`() BEGIN END"
+	diff := DiffSchemas(source, schema)
+	if !diff.RoutinesNotCompared || len(diff.RemovedRoutines) > 0 {
+		t.Errorf("diff = %+v, want routines not compared", diff)
+	}
+	if script := diff.AlterScript(); strings.Contains(script, "PROCEDURE") {
+		t.Errorf("script changes routines:\n%s", script)
+	}
+	if !diff.Empty() {
+		t.Errorf("diff = %+v, want empty", diff)
+	}
+}
+
+func TestNormalizeViewIgnoresDefiner(t *testing.T) {
+	views := []string{
+		"CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS select `t`.`a` AS `a` from `t`",
+		"CREATE ALGORITHM=MERGE DEFINER='app'@'localhost' SQL SECURITY INVOKER VIEW `v` AS select `t`.`a` AS `a` from `t`",
+		"CREATE DEFINER=CURRENT_USER() VIEW `v` AS\n  select `t`.`a`   AS `a` from `t`",
+	}
+	var normalized []string
+	for _, view := range views {
+		normalized = append(normalized, normalizeView(view))
+	}
+	sort.Strings(normalized)
+	if normalized[0] != normalized[len(normalized)-1] {
+		t.Errorf("normalized views differ: %q", normalized)
+	}
+}
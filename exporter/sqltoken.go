@@ -0,0 +1,254 @@
+package exporter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind classifies the tokens of a SQL statement
+type tokenKind int
+
+const (
+	tokenSpace tokenKind = iota
+	tokenComment
+	// tokenIdent is a backtick quoted identifier
+	tokenIdent
+	tokenString
+	// tokenWord is a keyword, unquoted identifier or number
+	tokenWord
+	tokenSymbol
+)
+
+// token is a lexical token of a SQL statement. Concatenating the text of all
+// tokens reproduces the input exactly.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// is reports whether the token is the given keyword or symbol, ignoring case
+func (t token) is(text string) bool {
+	return (t.kind == tokenWord || t.kind == tokenSymbol) && strings.EqualFold(t.text, text)
+}
+
+// value returns the unquoted value of identifiers and strings, and the text of other tokens
+func (t token) value() string {
+	switch t.kind {
+	case tokenIdent:
+		return strings.ReplaceAll(t.text[1:len(t.text)-1], "``", "`")
+	case tokenString:
+		return unquoteString(t.text)
+	default:
+		return t.text
+	}
+}
+
+// tokenize splits a SQL text into tokens following MySQL's lexical rules
+// for quoting and comments
+func tokenize(sql string) []token {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		t, next := nextToken(sql, i)
+		tokens = append(tokens, t)
+		i = next
+	}
+	return tokens
+}
+
+// nextToken scans the token starting at position i
+func nextToken(sql string, i int) (token, int) {
+	c := sql[i]
+	switch {
+	case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+		j := i + 1
+		for j < len(sql) && strings.IndexByte(" \t\n\r\f\v", sql[j]) >= 0 {
+			j++
+		}
+		return token{tokenSpace, sql[i:j]}, j
+	case c == '#' || (c == '-' && strings.HasPrefix(sql[i:], "--") &&
+		(i+2 == len(sql) || strings.IndexByte(" \t\n\r", sql[i+2]) >= 0)):
+		j := strings.IndexByte(sql[i:], '\n')
+		if j < 0 {
+			return token{tokenComment, sql[i:]}, len(sql)
+		}
+		return token{tokenComment, sql[i : i+j]}, i + j
+	case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+		j := strings.Index(sql[i+2:], "*/")
+		if j < 0 {
+			return token{tokenComment, sql[i:]}, len(sql)
+		}
+		return token{tokenComment, sql[i : i+j+4]}, i + j + 4
+	case c == '`':
+		j := scanQuoted(sql, i, '`', false)
+		return token{tokenIdent, sql[i:j]}, j
+	case c == '\'' || c == '"':
+		j := scanQuoted(sql, i, c, true)
+		return token{tokenString, sql[i:j]}, j
+	case isWordByte(sql, i):
+		j := i
+		for j < len(sql) && isWordByte(sql, j) {
+			_, size := utf8.DecodeRuneInString(sql[j:])
+			j += size
+		}
+		return token{tokenWord, sql[i:j]}, j
+	default:
+		_, size := utf8.DecodeRuneInString(sql[i:])
+		return token{tokenSymbol, sql[i : i+size]}, i + size
+	}
+}
+
+// scanQuoted returns the end of the quoted token starting at i. A doubled
+// quote character stands for itself; strings also accept backslash escapes.
+func scanQuoted(sql string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+// isWordByte reports whether the rune at position i can be part of an unquoted word
+func isWordByte(sql string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(sql[i:])
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// unquoteString removes the quotes and escapes of a string literal
+func unquoteString(text string) string {
+	quote := text[0]
+	body := text[1 : len(text)-1]
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == quote && i+1 < len(body) && body[i+1] == quote {
+			i++
+		} else if c == '\\' && i+1 < len(body) {
+			i++
+			switch body[i] {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case '0':
+				c = 0
+			case 'b':
+				c = '\b'
+			case 'Z':
+				c = 26
+			default:
+				c = body[i]
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// significant drops whitespace and comments from a token list
+func significant(tokens []token) []token {
+	var result []token
+	for _, t := range tokens {
+		if t.kind != tokenSpace && t.kind != tokenComment {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// joinTokens renders tokens back into SQL text
+func joinTokens(tokens []token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// normalizeSQL renders a statement with comments removed and all whitespace
+// collapsed to single spaces, so that formatting differences do not matter
+func normalizeSQL(sql string) string {
+	var b strings.Builder
+	space := false
+	for _, t := range tokenize(sql) {
+		if t.kind == tokenSpace || t.kind == tokenComment {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// splitStatements splits a SQL script into statements. Comments and quoted
+// text are respected, as are DELIMITER lines as written by the mysql client
+// around stored routines.
+func splitStatements(sql string) []string {
+	var statements []string
+	delimiter := ";"
+	start := 0
+	lineStart := true
+
+	flush := func(end int) {
+		if statement := strings.TrimSpace(sql[start:end]); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+
+	for i := 0; i < len(sql); {
+		if lineStart && hasPrefixFold(strings.TrimLeft(sql[i:], " \t"), "DELIMITER ") {
+			flush(i)
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			fields := strings.Fields(sql[i : i+end])
+			if len(fields) > 1 {
+				delimiter = fields[1]
+			}
+			i += end
+			start = i
+			continue
+		}
+		if strings.HasPrefix(sql[i:], delimiter) {
+			flush(i)
+			i += len(delimiter)
+			start = i
+			lineStart = false
+			continue
+		}
+
+		t, next := nextToken(sql, i)
+		// A delimiter such as $$ may directly follow a word, which it would be part of
+		if t.kind == tokenWord {
+			if j := strings.Index(t.text, delimiter); j > 0 {
+				next = i + j
+			}
+		}
+		lineStart = t.kind == tokenSpace && strings.Contains(t.text, "\n")
+		i = next
+	}
+	flush(len(sql))
+	return statements
+}
+
+// hasPrefixFold is strings.HasPrefix ignoring ASCII case
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -0,0 +1,147 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ordersTable is SHOW CREATE TABLE output with AUTO_INCREMENT, semicolons
+// and comment markers inside defaults and comments
+const ordersTable = "CREATE TABLE `orders` (\n" +
+	"  `id` bigint unsigned NOT NULL AUTO_INCREMENT,\n" +
+	"  `note` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT 'AUTO_INCREMENT=5; -- not a comment',\n" +
+	"  `customer_id` int NOT NULL COMMENT 'FK to customers (id)',\n" +
+	"  `created_at` datetime(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  KEY `idx_customer` (`customer_id`),\n" +
+	"  CONSTRAINT `fk_customer` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`) ON DELETE CASCADE\n" +
+	") ENGINE=InnoDB AUTO_INCREMENT=1042 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='orders, AUTO_INCREMENT=7'"
+
+func TestTokenizeRoundTrip(t *testing.T) {
+	tests := []string{
+		ordersTable,
+		"SELECT 'unterminated",
+		"SELECT `unterminated",
+		"/* unterminated",
+		"SELECT 1 -- trailing comment",
+		"SELECT 'a\\'b', \"c\"\"d\", `e``f` FROM t # comment\n",
+		"SELECT 'ünïcödé' AS `名前`",
+		"",
+	}
+	for _, sql := range tests {
+		if got := joinTokens(tokenize(sql)); got != sql {
+			t.Errorf("joinTokens(tokenize(%q)) = %q", sql, got)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []token
+	}{
+		{"`a``b`", []token{{tokenIdent, "`a``b`"}}},
+		{"'it''s'", []token{{tokenString, "'it''s'"}}},
+		{"'a\\'b' x", []token{{tokenString, "'a\\'b'"}, {tokenSpace, " "}, {tokenWord, "x"}}},
+		{"\"a;b\"", []token{{tokenString, "\"a;b\""}}},
+		{"-- c\nx", []token{{tokenComment, "-- c"}, {tokenSpace, "\n"}, {tokenWord, "x"}}},
+		// MySQL only starts a comment at "--" followed by whitespace
+		{"1--2", []token{{tokenWord, "1"}, {tokenSymbol, "-"}, {tokenSymbol, "-"}, {tokenWord, "2"}}},
+		{"# c", []token{{tokenComment, "# c"}}},
+		{"/* a */b", []token{{tokenComment, "/* a */"}, {tokenWord, "b"}}},
+		{"/*!50100 PARTITION BY HASH (`id`) */", []token{{tokenComment, "/*!50100 PARTITION BY HASH (`id`) */"}}},
+		{"`u`@`%`", []token{{tokenIdent, "`u`"}, {tokenSymbol, "@"}, {tokenIdent, "`%`"}}},
+		{"AUTO_INCREMENT=12", []token{{tokenWord, "AUTO_INCREMENT"}, {tokenSymbol, "="}, {tokenWord, "12"}}},
+	}
+	for _, test := range tests {
+		if got := tokenize(test.sql); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("tokenize(%q) = %v, want %v", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestTokenValue(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"`a``b`", "a`b"},
+		{"'it''s'", "it's"},
+		{"'a\\'b'", "a'b"},
+		{"'line\\nbreak\\ttab\\\\'", "line\nbreak\ttab\\"},
+		{"\"say \"\"hi\"\"\"", "say \"hi\""},
+		{"word", "word"},
+	}
+	for _, test := range tests {
+		if got := tokenize(test.sql)[0].value(); got != test.want {
+			t.Errorf("value of %q = %q, want %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"  `a`   int\n\tNOT NULL  ", "`a` int NOT NULL"},
+		{"`a` int /* comment */ DEFAULT '  two  spaces'", "`a` int DEFAULT '  two  spaces'"},
+		{"`a` int -- comment\n, `b` int", "`a` int , `b` int"},
+	}
+	for _, test := range tests {
+		if got := normalizeSQL(test.sql); got != test.want {
+			t.Errorf("normalizeSQL(%q) = %q, want %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "quoted semicolons",
+			sql:  "INSERT INTO `a;b` VALUES ('x;y', \"z;\"); -- c;\nSELECT 1; /* ; */ SELECT 2;",
+			want: []string{"INSERT INTO `a;b` VALUES ('x;y', \"z;\")", "-- c;\nSELECT 1", "/* ; */ SELECT 2"},
+		},
+		{
+			name: "delimiter blocks",
+			sql: "DROP PROCEDURE IF EXISTS `p`;\n" +
+				"DELIMITER ;;\n" +
+				"CREATE DEFINER=`root`@`%` PROCEDURE `p`()\n" +
+				"BEGIN\n" +
+				"  SELECT 'a;b';\n" +
+				"  SELECT 1;\n" +
+				"END ;;\n" +
+				"DELIMITER ;\n" +
+				"CREATE TABLE `t` (`a` int);\n",
+			want: []string{
+				"DROP PROCEDURE IF EXISTS `p`",
+				"CREATE DEFINER=`root`@`%` PROCEDURE `p`()\nBEGIN\n  SELECT 'a;b';\n  SELECT 1;\nEND",
+				"CREATE TABLE `t` (`a` int)",
+			},
+		},
+		{
+			name: "lower case delimiter",
+			sql:  "delimiter $$\nCREATE FUNCTION f() RETURNS int RETURN 1$$\ndelimiter ;\nSELECT f();",
+			want: []string{"CREATE FUNCTION f() RETURNS int RETURN 1", "SELECT f()"},
+		},
+		{
+			name: "delimiter inside a string",
+			sql:  "SELECT '\nDELIMITER ;;\n'; SELECT 2;",
+			want: []string{"SELECT '\nDELIMITER ;;\n'", "SELECT 2"},
+		},
+		{
+			name: "no trailing delimiter",
+			sql:  "SELECT 1;\n  SELECT 2  ",
+			want: []string{"SELECT 1", "SELECT 2"},
+		},
+	}
+	for _, test := range tests {
+		if got := splitStatements(test.sql); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: splitStatements = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// throttleCheckInterval is the time between two checks of the server load,
+// and between two checks while reading is paused
+const throttleCheckInterval = time.Second
+
+// loadLimit is one Variable=limit pair of Config.MaxLoad
+type loadLimit struct {
+	variable string
+	limit    int64
+}
+
+// parseMaxLoad parses a list like Threads_running=25,Threads_connected=500
+func parseMaxLoad(value string) ([]loadLimit, error) {
+	var limits []loadLimit
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		variable, limit, ok := strings.Cut(part, "=")
+		n, err := strconv.ParseInt(strings.TrimSpace(limit), 10, 64)
+		if !ok || err != nil || strings.TrimSpace(variable) == "" {
+			return nil, fmt.Errorf(msgs.ErrInvalidMaxLoad, value)
+		}
+		limits = append(limits, loadLimit{variable: strings.TrimSpace(variable), limit: n})
+	}
+	return limits, nil
+}
+
+// throttler slows down the row loop of an export to protect the source.
+// The rates are kept per table, and reading pauses while a status variable
+// is over its limit or the watched replica lags behind.
+type throttler struct {
+	rowsPerSecond  float64
+	bytesPerSecond float64
+	maxLoad        []loadLimit
+	replica        *sql.DB
+	maxLag         time.Duration
+	// sourceLag is set when the source itself is a replica whose lag is
+	// checked by waitForReplica
+	sourceLag bool
+
+	start     time.Time
+	rows      int64
+	bytes     int64
+	lastCheck time.Time
+}
+
+// newThrottler returns nil when no limit is configured
+func newThrottler(ctx context.Context, config Config) (*throttler, error) {
+	maxLoad, err := parseMaxLoad(config.MaxLoad)
+	if err != nil {
+		return nil, err
+	}
+	if !throttled(config) {
+		return nil, nil
+	}
+
+	t := &throttler{
+		rowsPerSecond:  float64(config.MaxRowsPerSecond),
+		bytesPerSecond: float64(config.MaxBytesPerSecond),
+		maxLoad:        maxLoad,
+		maxLag:         config.MaxLag,
+		sourceLag:      config.MaxReplicaLag > 0,
+	}
+	if config.CheckReplicaDSN != "" {
+		t.replica, _, err = OpenContext(ctx, Config{DSN: config.CheckReplicaDSN})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// throttleError is returned by scanTable when throttling failed rather than
+// reading, for example because the watched replica lagged for too long
+type throttleError struct {
+	err error
+}
+
+func (t *throttleError) Error() string {
+	return t.err.Error()
+}
+
+func (t *throttleError) Unwrap() error {
+	return t.err
+}
+
+// throttled reports whether the configuration asks for throttling
+func throttled(config Config) bool {
+	return config.MaxRowsPerSecond > 0 || config.MaxBytesPerSecond > 0 || config.MaxLoad != "" ||
+		config.CheckReplicaDSN != "" || config.MaxReplicaLag > 0
+}
+
+// close closes the pool of the watched replica
+func (t *throttler) close() {
+	if t != nil && t.replica != nil {
+		t.replica.Close()
+	}
+}
+
+// reset starts the rate window of a new table
+func (t *throttler) reset() {
+	if t != nil {
+		t.start, t.rows, t.bytes = time.Now(), 0, 0
+	}
+}
+
+// throttle is called after every row read with the size of its values. It
+// sleeps as long as the rates require and checks the server load once per
+// throttleCheckInterval.
+func (e *Exporter) throttle(bytes int) error {
+	t := e.throttler
+	if t == nil {
+		return nil
+	}
+	t.rows++
+	t.bytes += int64(bytes)
+
+	elapsed := time.Since(t.start)
+	var delay time.Duration
+	if t.rowsPerSecond > 0 {
+		delay = time.Duration(float64(t.rows)/t.rowsPerSecond*float64(time.Second)) - elapsed
+	}
+	if t.bytesPerSecond > 0 {
+		if d := time.Duration(float64(t.bytes)/t.bytesPerSecond*float64(time.Second)) - elapsed; d > delay {
+			delay = d
+		}
+	}
+	if err := sleep(e.ctx, delay); err != nil {
+		return err
+	}
+
+	if time.Since(t.lastCheck) < throttleCheckInterval {
+		return nil
+	}
+	if t.sourceLag {
+		if err := e.waitForReplica(); err != nil {
+			return err
+		}
+	}
+	return e.waitForLoad()
+}
+
+// waitForLoad pauses until the source is no longer overloaded
+func (e *Exporter) waitForLoad() error {
+	t := e.throttler
+	warned := false
+	for {
+		t.lastCheck = time.Now()
+		reason, err := e.overloaded()
+		if err != nil || reason == "" {
+			return err
+		}
+		if !warned {
+			e.warn(e.table, fmt.Sprintf(msgs.ThrottlePaused, reason))
+			warned = true
+		}
+		if err := sleep(e.ctx, throttleCheckInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// overloaded describes the first exceeded limit, or returns "" when the
+// source is within all limits. The checks run on the connection pool, since
+// the connection reading the rows is busy.
+func (e *Exporter) overloaded() (string, error) {
+	t := e.throttler
+	for _, l := range t.maxLoad {
+		var name string
+		var value int64
+		err := e.db.QueryRowContext(e.ctx, "SHOW GLOBAL STATUS LIKE ?", l.variable).Scan(&name, &value)
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf(msgs.ErrUnknownStatusVariable, l.variable)
+		}
+		if err != nil {
+			return "", fmt.Errorf(msgs.ErrCheckLoad, err)
+		}
+		if value > l.limit {
+			return fmt.Sprintf("%s=%d > %d", name, value, l.limit), nil
+		}
+	}
+
+	if t.replica != nil {
+		lag, err := replicaLag(e.ctx, t.replica)
+		if err != nil {
+			return "", err
+		}
+		if lag < 0 {
+			return msgs.ThrottleReplicaStopped, nil
+		}
+		if lag > t.maxLag {
+			return fmt.Sprintf(msgs.ThrottleReplicaLag, lag, t.maxLag), nil
+		}
+	}
+	return "", nil
+}
+
+// sleep waits for d or until ctx is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
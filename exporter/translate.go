@@ -0,0 +1,638 @@
+package exporter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// standardDialect translates the MySQL schema and data into PostgreSQL or
+// SQLite. Both use double quoted identifiers and standard string literals,
+// they differ in column types and in how keys and comments are declared.
+type standardDialect struct {
+	postgres bool
+	// ifNotExists adds IF NOT EXISTS to CREATE TABLE and CREATE INDEX
+	ifNotExists bool
+
+	// foreignKeys are added once all tables exist, PostgreSQL refuses to
+	// reference a table that has not been created yet
+	foreignKeys []string
+}
+
+func (d *standardDialect) quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteString renders a standard SQL string literal. PostgreSQL text cannot
+// hold NUL characters, so they are removed.
+func (d *standardDialect) quoteString(s string) string {
+	return string(d.appendQuoted(nil, []byte(s)))
+}
+
+// appendQuoted appends value as a string literal like quoteString
+func (d *standardDialect) appendQuoted(dst, value []byte) []byte {
+	dst = append(dst, '\'')
+	for _, c := range value {
+		switch {
+		case c == '\'':
+			dst = append(dst, '\'', '\'')
+		case c == 0 && d.postgres:
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '\'')
+}
+
+func (d *standardDialect) schemaHeader() string {
+	if d.postgres {
+		return "SET standard_conforming_strings = on;\n\n"
+	}
+	return "PRAGMA foreign_keys=OFF;\n\n"
+}
+
+// schemaFooter adds the foreign keys collected by createTable in PostgreSQL,
+// and forgets them so that the next schema starts without any
+func (d *standardDialect) schemaFooter() string {
+	if d.postgres {
+		footer := "\n" + strings.Join(d.foreignKeys, "")
+		d.foreignKeys = nil
+		return footer
+	}
+	return "\nPRAGMA foreign_keys=ON;\n"
+}
+
+// The data is loaded in one transaction, foreign keys are only checked on
+// commit in PostgreSQL and not at all in SQLite
+func (d *standardDialect) dataHeader() string {
+	if d.postgres {
+		return "SET standard_conforming_strings = on;\nBEGIN;\nSET CONSTRAINTS ALL DEFERRED;\n\n"
+	}
+	return "PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n\n"
+}
+
+func (d *standardDialect) dataFooter() string {
+	if d.postgres {
+		return "\nCOMMIT;\n"
+	}
+	return "\nCOMMIT;\nPRAGMA foreign_keys=ON;\n"
+}
+
+func (d *standardDialect) dropTable(table string) string {
+	if d.postgres {
+		return fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE;\n", d.quoteIdent(table))
+	}
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", d.quoteIdent(table))
+}
+
+func (d *standardDialect) dropView(view string) string {
+	return fmt.Sprintf("DROP VIEW IF EXISTS %s;\n", d.quoteIdent(view))
+}
+
+// setAutoIncrement advances the identity sequence in PostgreSQL. SQLite keeps
+// its counter above the highest inserted ID by itself.
+func (d *standardDialect) setAutoIncrement(table, column string, max uint64) string {
+	if !d.postgres {
+		return ""
+	}
+	return fmt.Sprintf("SELECT setval(pg_get_serial_sequence(%s, %s), %d);\n",
+		d.quoteString(d.quoteIdent(table)), d.quoteString(column), max)
+}
+
+func (d *standardDialect) lockTable(table string) string { return "" }
+func (d *standardDialect) unlockTables() string          { return "" }
+
+func (d *standardDialect) appendLiteral(dst, value []byte, kind valueKind) []byte {
+	switch {
+	case value == nil:
+		return append(dst, "NULL"...)
+	case kind == kindBinary:
+		if d.postgres {
+			dst = append(dst, `'\x`...)
+		} else {
+			dst = append(dst, "X'"...)
+		}
+		dst = hex.AppendEncode(dst, value)
+		return append(dst, '\'')
+	case kind == kindBit:
+		dst = append(dst, '\'')
+		dst = strconv.AppendUint(dst, bitValue(value), 10)
+		return append(dst, '\'')
+	}
+	return d.appendQuoted(dst, value)
+}
+
+// bitValue decodes the big endian bytes of a BIT column
+func bitValue(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+// translateSQL renders MySQL tokens with standard identifier and string
+// quoting. Comments and character set introducers are dropped, everything
+// else is copied as it is.
+func (d *standardDialect) translateSQL(tokens []token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		switch t.kind {
+		case tokenWord:
+			if !strings.HasPrefix(t.text, "_") || i+1 >= len(tokens) || tokens[i+1].kind != tokenString {
+				b.WriteString(t.text)
+			}
+		case tokenIdent:
+			b.WriteString(d.quoteIdent(t.value()))
+		case tokenString:
+			b.WriteString(d.quoteString(t.value()))
+		case tokenComment:
+		default:
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
+
+// sqlWords holds the significant tokens of a definition together with their
+// positions, so that parts of it can be rendered with the original spacing
+type sqlWords struct {
+	tokens []token
+	words  []token
+	pos    []int
+}
+
+func newSQLWords(sql string) *sqlWords {
+	w := &sqlWords{tokens: tokenize(sql)}
+	for i, t := range w.tokens {
+		if t.kind != tokenSpace && t.kind != tokenComment {
+			w.words = append(w.words, t)
+			w.pos = append(w.pos, i)
+		}
+	}
+	return w
+}
+
+// span returns the tokens of words[from:to]
+func (w *sqlWords) span(from, to int) []token {
+	if from >= to {
+		return nil
+	}
+	return w.tokens[w.pos[from] : w.pos[to-1]+1]
+}
+
+// groupEnd returns the index after the parenthesized group opening at words[i]
+func (w *sqlWords) groupEnd(i int) int {
+	depth := 0
+	for ; i < len(w.words); i++ {
+		switch {
+		case w.words[i].is("("):
+			depth++
+		case w.words[i].is(")"):
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(w.words)
+}
+
+// expressionEnd returns the index after the value expression starting at
+// words[i]: a literal, a parenthesized expression or a function call
+func (w *sqlWords) expressionEnd(i int) int {
+	if i >= len(w.words) {
+		return i
+	}
+	word := w.words[i]
+	switch {
+	case word.is("("):
+		return w.groupEnd(i)
+	case word.is("-") || word.is("+"):
+		return w.expressionEnd(i + 1)
+	case word.kind == tokenWord && i+1 < len(w.words):
+		if w.words[i+1].is("(") {
+			return w.groupEnd(i + 1)
+		}
+		if w.words[i+1].kind == tokenString && isIntroducer(word) {
+			return i + 2
+		}
+	}
+	return i + 1
+}
+
+// isIntroducer reports whether a word prefixes a string literal, as in
+// b'101', x'ff' or _utf8mb4'text'
+func isIntroducer(t token) bool {
+	return t.is("b") || t.is("x") || t.is("n") || strings.HasPrefix(t.text, "_")
+}
+
+// columnDef is a column definition of SHOW CREATE TABLE taken apart
+type columnDef struct {
+	name     string
+	dataType string
+	// args are the words between the parentheses after the type
+	argsFrom, argsTo int
+	unsigned         bool
+	notNull          bool
+	defaultValue     string
+	autoIncrement    bool
+	comment          string
+	generated        string
+	stored           bool
+}
+
+// parseColumn takes a column definition apart, attributes that have no
+// equivalent outside MySQL are reported as warnings
+func (d *standardDialect) parseColumn(table string, w *sqlWords) (*columnDef, []string) {
+	var warnings []string
+	words := w.words
+	col := &columnDef{name: words[0].value()}
+	if len(words) > 1 {
+		col.dataType = strings.ToLower(words[1].text)
+	}
+
+	i := 2
+	if i < len(words) && words[i].is("(") {
+		end := w.groupEnd(i)
+		col.argsFrom, col.argsTo = i+1, end-1
+		i = end
+	}
+	for ; i < len(words); i++ {
+		word := words[i]
+		next := func(text string) bool { return i+1 < len(words) && words[i+1].is(text) }
+		switch {
+		case word.is("UNSIGNED"):
+			col.unsigned = true
+		case word.is("SIGNED"), word.is("ZEROFILL"), word.is("NULL"), word.is("VISIBLE"),
+			word.is("GENERATED"), word.is("ALWAYS"), word.is("VIRTUAL"):
+		case word.is("CHARACTER") && next("SET"):
+			i += 2
+		case word.is("CHARSET"), word.is("COLLATE"):
+			i++
+		case word.is("NOT") && next("NULL"):
+			col.notNull = true
+			i++
+		case word.is("DEFAULT"):
+			end := w.expressionEnd(i + 1)
+			col.defaultValue = d.defaultValue(w, i+1, end)
+			i = end - 1
+		case word.is("AUTO_INCREMENT"):
+			col.autoIncrement = true
+		case word.is("COMMENT") && i+1 < len(words):
+			col.comment = words[i+1].value()
+			i++
+		case word.is("AS") && next("("):
+			end := w.groupEnd(i + 1)
+			col.generated = d.translateSQL(w.span(i+1, end))
+			i = end - 1
+		case word.is("STORED"):
+			col.stored = true
+		case word.is("ON") && next("UPDATE"):
+			end := w.expressionEnd(i + 2)
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectDroppedAttribute,
+				table, col.name, normalizeSQL(joinTokens(w.span(i, end)))))
+			i = end - 1
+		default:
+			end := w.expressionEnd(i)
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectDroppedAttribute,
+				table, col.name, normalizeSQL(joinTokens(w.span(i, end)))))
+			i = end - 1
+		}
+	}
+	return col, warnings
+}
+
+// defaultValue translates the default expression in words[from:to]
+func (d *standardDialect) defaultValue(w *sqlWords, from, to int) string {
+	if from >= to {
+		return ""
+	}
+	first := w.words[from]
+	switch {
+	case first.is("CURRENT_TIMESTAMP"), first.is("NOW"), first.is("LOCALTIME"), first.is("LOCALTIMESTAMP"):
+		return "CURRENT_TIMESTAMP"
+	case to-from == 2 && first.is("b"):
+		n, _ := strconv.ParseUint(w.words[from+1].value(), 2, 64)
+		return "'" + strconv.FormatUint(n, 10) + "'"
+	case to-from == 2 && strings.HasPrefix(first.text, "_"):
+		return d.quoteString(w.words[from+1].value())
+	}
+	return d.translateSQL(w.span(from, to))
+}
+
+// columnType maps a MySQL column type to the target database
+func (d *standardDialect) columnType(w *sqlWords, col *columnDef) (string, bool) {
+	args := ""
+	if col.argsTo > col.argsFrom {
+		args = "(" + d.translateSQL(w.span(col.argsFrom, col.argsTo)) + ")"
+	}
+
+	if !d.postgres {
+		switch col.dataType {
+		case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "bit", "bool", "boolean", "year":
+			return "INTEGER", true
+		case "decimal", "numeric", "dec", "fixed":
+			return "NUMERIC", true
+		case "float", "double", "real":
+			return "REAL", true
+		case "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "json",
+			"date", "datetime", "timestamp", "time":
+			return "TEXT", true
+		case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+			return "BLOB", true
+		case "set":
+			return "TEXT", false
+		default:
+			return "BLOB", false
+		}
+	}
+
+	switch col.dataType {
+	case "tinyint":
+		// tinyint(1) is how MySQL declares BOOLEAN
+		if args == "(1)" {
+			return "boolean", true
+		}
+		return "smallint", true
+	case "bool", "boolean":
+		return "boolean", true
+	case "smallint":
+		if col.unsigned {
+			return "integer", true
+		}
+		return "smallint", true
+	case "mediumint", "int", "integer":
+		if col.unsigned {
+			return "bigint", true
+		}
+		return "integer", true
+	case "bigint":
+		// An identity column needs an integer type, numeric(20) would only be
+		// needed for values beyond the signed range
+		if col.unsigned && !col.autoIncrement {
+			return "numeric(20)", true
+		}
+		return "bigint", true
+	case "decimal", "numeric", "dec", "fixed":
+		return "numeric" + args, true
+	case "float":
+		return "real", true
+	case "double", "real":
+		return "double precision", true
+	case "bit":
+		if args == "" || args == "(1)" {
+			return "boolean", true
+		}
+		return "bigint", true
+	case "char", "varchar":
+		return col.dataType + args, true
+	case "tinytext", "text", "mediumtext", "longtext":
+		return "text", true
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return "bytea", true
+	case "date":
+		return "date", true
+	case "datetime", "timestamp":
+		return "timestamp" + args, true
+	case "time":
+		return "time" + args, true
+	case "year":
+		return "smallint", true
+	case "json":
+		return "jsonb", true
+	case "enum":
+		return "varchar(255)", true
+	case "set":
+		return "text", false
+	default:
+		return "bytea", false
+	}
+}
+
+// createTable translates SHOW CREATE TABLE output into a CREATE TABLE
+// statement followed by the indexes and comments of the table
+func (d *standardDialect) createTable(table, create string) (string, []string) {
+	def := parseCreateTable(table, create)
+	var warnings []string
+	var lines, after []string
+
+	var primaryKey []string
+	for _, index := range def.Indexes {
+		if index.Kind == "PRIMARY" {
+			primaryKey, _ = d.keyParts(table, index)
+		}
+	}
+	// SQLite only auto-increments an INTEGER PRIMARY KEY declared on the column
+	inlinePrimaryKey := false
+
+	for _, item := range def.Columns {
+		w := newSQLWords(item.Definition)
+		col, columnWarnings := d.parseColumn(table, w)
+		warnings = append(warnings, columnWarnings...)
+
+		dataType, supported := d.columnType(w, col)
+		if !supported {
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectUnsupportedType, table, col.name, col.dataType, dataType))
+		}
+
+		line := "  " + d.quoteIdent(col.name) + " " + dataType
+		switch {
+		case col.autoIncrement && d.postgres:
+			line += " GENERATED BY DEFAULT AS IDENTITY"
+		case col.autoIncrement && len(primaryKey) == 1 && primaryKey[0] == d.quoteIdent(col.name):
+			line = "  " + d.quoteIdent(col.name) + " INTEGER PRIMARY KEY AUTOINCREMENT"
+			inlinePrimaryKey = true
+		case col.autoIncrement:
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectDroppedAttribute, table, col.name, "AUTO_INCREMENT"))
+		}
+		if col.notNull {
+			line += " NOT NULL"
+		}
+		if col.defaultValue != "" && col.defaultValue != "NULL" {
+			line += " DEFAULT " + col.defaultValue
+		}
+		if col.generated != "" {
+			switch {
+			case d.postgres && !col.stored:
+				// PostgreSQL only has stored generated columns
+				warnings = append(warnings, fmt.Sprintf(msgs.DialectDroppedAttribute, table, col.name, "VIRTUAL"))
+				line += " GENERATED ALWAYS AS " + col.generated + " STORED"
+			case col.stored:
+				line += " GENERATED ALWAYS AS " + col.generated + " STORED"
+			default:
+				line += " GENERATED ALWAYS AS " + col.generated + " VIRTUAL"
+			}
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectExpression, table, col.name))
+		}
+		if col.dataType == "enum" {
+			line += fmt.Sprintf(" CHECK (%s IN (%s))", d.quoteIdent(col.name),
+				d.translateSQL(w.span(col.argsFrom, col.argsTo)))
+		}
+		lines = append(lines, line)
+
+		if col.comment != "" && d.postgres {
+			after = append(after, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;\n",
+				d.quoteIdent(table), d.quoteIdent(col.name), d.quoteString(col.comment)))
+		}
+	}
+
+	for _, index := range def.Indexes {
+		switch index.Kind {
+		case "PRIMARY":
+			if !inlinePrimaryKey {
+				parts, partWarnings := d.keyParts(table, index)
+				warnings = append(warnings, partWarnings...)
+				lines = append(lines, "  PRIMARY KEY ("+strings.Join(parts, ", ")+")")
+			}
+		case "UNIQUE", "KEY", "INDEX":
+			parts, partWarnings := d.keyParts(table, index)
+			warnings = append(warnings, partWarnings...)
+			unique := ""
+			if index.Kind == "UNIQUE" {
+				unique = "UNIQUE "
+			}
+			after = append(after, fmt.Sprintf("CREATE %sINDEX %s%s ON %s (%s);\n", unique, d.ifNotExistsClause(),
+				d.quoteIdent(d.objectName(table+"_"+index.Name)), d.quoteIdent(table), strings.Join(parts, ", ")))
+		case "FOREIGN":
+			constraint := d.constraint(index)
+			if d.postgres {
+				d.foreignKeys = append(d.foreignKeys, fmt.Sprintf("ALTER TABLE %s ADD %s DEFERRABLE INITIALLY DEFERRED;\n",
+					d.quoteIdent(table), constraint))
+			} else {
+				lines = append(lines, "  "+constraint)
+			}
+		case "CHECK":
+			lines = append(lines, "  "+d.constraint(index))
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectExpression, table, index.Name))
+		default:
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectDroppedIndex, table, index.Kind, index.Name))
+		}
+	}
+
+	for _, option := range def.Options {
+		switch {
+		case strings.HasPrefix(option, "COMMENT="):
+			if d.postgres {
+				comment := unquoteString(strings.TrimPrefix(option, "COMMENT="))
+				after = append(after, fmt.Sprintf("COMMENT ON TABLE %s IS %s;\n", d.quoteIdent(table), d.quoteString(comment)))
+			}
+		case strings.HasPrefix(option, "/*!"), !strings.Contains(option, "="):
+			// Partitioning has no counterpart
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectDroppedOption, table, option))
+		}
+	}
+
+	statement := fmt.Sprintf("CREATE TABLE %s%s (\n%s\n);\n", d.ifNotExistsClause(), d.quoteIdent(table), strings.Join(lines, ",\n"))
+	return statement + strings.Join(after, ""), warnings
+}
+
+// maxPostgresIdent is the length in bytes beyond which PostgreSQL truncates identifiers
+const maxPostgresIdent = 63
+
+// objectName returns the name of a generated index or of a constraint.
+// PostgreSQL would truncate a longer name, so that two names with a long
+// common prefix collide; they are cut short and end with a hash of the full
+// name instead.
+func (d *standardDialect) objectName(name string) string {
+	if !d.postgres || len(name) <= maxPostgresIdent {
+		return name
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	suffix := fmt.Sprintf("_%08x", hash.Sum32())
+	cut := maxPostgresIdent - len(suffix)
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut] + suffix
+}
+
+// constraint translates a FOREIGN KEY or CHECK constraint, shortening its
+// name like objectName
+func (d *standardDialect) constraint(index SchemaItem) string {
+	tokens := tokenize(index.Definition)
+	if name := d.objectName(index.Name); name != index.Name {
+		// CONSTRAINT `name` ...
+		for i, t := range tokens {
+			if t.kind == tokenIdent {
+				tokens[i] = token{kind: tokenIdent, text: quoteIdent(name)}
+				break
+			}
+		}
+	}
+	return d.translateSQL(tokens)
+}
+
+func (d *standardDialect) ifNotExistsClause() string {
+	if d.ifNotExists {
+		return "IF NOT EXISTS "
+	}
+	return ""
+}
+
+// keyParts renders the column list of an index. Prefix lengths are dropped,
+// the key parts of functional indexes are translated like expressions.
+func (d *standardDialect) keyParts(table string, index SchemaItem) ([]string, []string) {
+	var warnings []string
+	w := newSQLWords(index.Definition)
+	open := -1
+	for i, word := range w.words {
+		if word.is("(") {
+			open = i
+			break
+		}
+	}
+	if open < 0 {
+		return nil, nil
+	}
+	end := w.groupEnd(open) - 1
+
+	var parts []string
+	for i := open + 1; i < end; i++ {
+		start := i
+		for i < end && !w.words[i].is(",") {
+			if w.words[i].is("(") {
+				i = w.groupEnd(i) - 1
+			}
+			i++
+		}
+
+		part := w.words[start:i]
+		switch {
+		case len(part) == 0:
+		case part[0].is("("):
+			parts = append(parts, d.translateSQL(w.span(start, i)))
+			warnings = append(warnings, fmt.Sprintf(msgs.DialectExpression, table, index.Name))
+		default:
+			rendered := d.quoteIdent(part[0].value())
+			for j := 1; j < len(part); j++ {
+				if part[j].is("(") {
+					warnings = append(warnings, fmt.Sprintf(msgs.DialectIndexPrefix, table, index.Name, part[0].value()))
+					j = w.groupEnd(start+j) - start - 1
+					continue
+				}
+				rendered += " " + strings.ToUpper(part[j].text)
+			}
+			parts = append(parts, rendered)
+		}
+	}
+	return parts, warnings
+}
+
+// createView rewrites CREATE ... VIEW without the MySQL specific algorithm,
+// definer and security characteristics. The query itself only gets its
+// quoting translated.
+func (d *standardDialect) createView(view, create string) (string, []string) {
+	w := newSQLWords(create)
+	for i, word := range w.words {
+		if word.is("VIEW") {
+			body := d.translateSQL(w.tokens[w.pos[i]+1:])
+			return "CREATE VIEW" + body + ";\n", []string{fmt.Sprintf(msgs.DialectView, view)}
+		}
+	}
+	return d.translateSQL(w.tokens) + ";\n", []string{fmt.Sprintf(msgs.DialectView, view)}
+}
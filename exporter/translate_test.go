@@ -0,0 +1,195 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// usersTable is SHOW CREATE TABLE output with attributes, indexes and
+// options that have no equivalent outside MySQL
+const usersTable = "CREATE TABLE `users` (\n" +
+	"  `id` int unsigned NOT NULL AUTO_INCREMENT,\n" +
+	"  `email` varchar(191) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL COMMENT 'login, it''s unique',\n" +
+	"  `active` tinyint(1) NOT NULL DEFAULT '1',\n" +
+	"  `flags` bit(1) DEFAULT b'0',\n" +
+	"  `role` enum('admin','user') DEFAULT 'user',\n" +
+	"  `bio` text COLLATE utf8mb4_unicode_ci,\n" +
+	"  `note` varchar(64) DEFAULT 'AUTO_INCREMENT=5',\n" +
+	"  `updated_at` timestamp(3) NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  UNIQUE KEY `uk_email` (`email`),\n" +
+	"  KEY `idx_bio` (`bio`(20)),\n" +
+	"  FULLTEXT KEY `ft_bio` (`bio`),\n" +
+	"  CONSTRAINT `fk_role` FOREIGN KEY (`role`) REFERENCES `roles` (`name`)\n" +
+	") ENGINE=InnoDB AUTO_INCREMENT=12 DEFAULT CHARSET=utf8mb4 COMMENT='app users'"
+
+func TestCreateTable(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     *standardDialect
+		want        string
+		foreignKeys []string
+	}{
+		{
+			name:    "postgres",
+			dialect: &standardDialect{postgres: true},
+			want: "CREATE TABLE \"users\" (\n" +
+				"  \"id\" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL,\n" +
+				"  \"email\" varchar(191) NOT NULL,\n" +
+				"  \"active\" boolean NOT NULL DEFAULT '1',\n" +
+				"  \"flags\" boolean DEFAULT '0',\n" +
+				"  \"role\" varchar(255) DEFAULT 'user' CHECK (\"role\" IN ('admin','user')),\n" +
+				"  \"bio\" text,\n" +
+				"  \"note\" varchar(64) DEFAULT 'AUTO_INCREMENT=5',\n" +
+				"  \"updated_at\" timestamp(3) DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  PRIMARY KEY (\"id\")\n" +
+				");\n" +
+				"COMMENT ON COLUMN \"users\".\"email\" IS 'login, it''s unique';\n" +
+				"CREATE UNIQUE INDEX \"users_uk_email\" ON \"users\" (\"email\");\n" +
+				"CREATE INDEX \"users_idx_bio\" ON \"users\" (\"bio\");\n" +
+				"COMMENT ON TABLE \"users\" IS 'app users';\n",
+			foreignKeys: []string{"ALTER TABLE \"users\" ADD CONSTRAINT \"fk_role\" FOREIGN KEY (\"role\") " +
+				"REFERENCES \"roles\" (\"name\") DEFERRABLE INITIALLY DEFERRED;\n"},
+		},
+		{
+			name:    "sqlite",
+			dialect: &standardDialect{ifNotExists: true},
+			want: "CREATE TABLE IF NOT EXISTS \"users\" (\n" +
+				"  \"id\" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\n" +
+				"  \"email\" TEXT NOT NULL,\n" +
+				"  \"active\" INTEGER NOT NULL DEFAULT '1',\n" +
+				"  \"flags\" INTEGER DEFAULT '0',\n" +
+				"  \"role\" TEXT DEFAULT 'user' CHECK (\"role\" IN ('admin','user')),\n" +
+				"  \"bio\" TEXT,\n" +
+				"  \"note\" TEXT DEFAULT 'AUTO_INCREMENT=5',\n" +
+				"  \"updated_at\" TEXT DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  CONSTRAINT \"fk_role\" FOREIGN KEY (\"role\") REFERENCES \"roles\" (\"name\")\n" +
+				");\n" +
+				"CREATE UNIQUE INDEX IF NOT EXISTS \"users_uk_email\" ON \"users\" (\"email\");\n" +
+				"CREATE INDEX IF NOT EXISTS \"users_idx_bio\" ON \"users\" (\"bio\");\n",
+		},
+	}
+	for _, test := range tests {
+		got, warnings := test.dialect.createTable("users", usersTable)
+		if got != test.want {
+			t.Errorf("%s: createTable =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+		// ON UPDATE, the prefix length of idx_bio and the FULLTEXT index
+		if len(warnings) != 3 {
+			t.Errorf("%s: warnings = %q, want 3", test.name, warnings)
+		}
+		if len(test.dialect.foreignKeys) != len(test.foreignKeys) ||
+			(len(test.foreignKeys) > 0 && test.dialect.foreignKeys[0] != test.foreignKeys[0]) {
+			t.Errorf("%s: foreign keys = %q, want %q", test.name, test.dialect.foreignKeys, test.foreignKeys)
+		}
+	}
+}
+
+func TestCreateTableDroppedOptions(t *testing.T) {
+	create := "CREATE TABLE `logs` (\n  `id` int NOT NULL,\n  `tags` set('a','b') DEFAULT NULL\n) ENGINE=InnoDB\n" +
+		"/*!50100 PARTITION BY HASH (`id`) PARTITIONS 4 */"
+	d := &standardDialect{postgres: true}
+	got, warnings := d.createTable("logs", create)
+	want := "CREATE TABLE \"logs\" (\n  \"id\" integer NOT NULL,\n  \"tags\" text\n);\n"
+	if got != want {
+		t.Errorf("createTable =\n%s\nwant\n%s", got, want)
+	}
+	// The SET type and the partitioning
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %q, want 2", warnings)
+	}
+}
+
+func TestCreateView(t *testing.T) {
+	create := "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS " +
+		"select `users`.`id` AS `id`,_utf8mb4'x' AS `c` from `users` where (`users`.`note` = 'it''s -- not a comment')"
+	want := "CREATE VIEW \"v\" AS select \"users\".\"id\" AS \"id\",'x' AS \"c\" from \"users\" " +
+		"where (\"users\".\"note\" = 'it''s -- not a comment');\n"
+	for _, d := range []*standardDialect{{postgres: true}, {}} {
+		if got, _ := d.createView("v", create); got != want {
+			t.Errorf("createView = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestStandardLiterals(t *testing.T) {
+	tests := []struct {
+		postgres bool
+		value    []byte
+		kind     valueKind
+		want     string
+	}{
+		{true, nil, kindText, "NULL"},
+		{true, []byte("a'b\\c"), kindText, `'a''b\c'`},
+		{true, []byte("a\x00b"), kindText, "'ab'"},
+		{false, []byte("a\x00b"), kindText, "'a\x00b'"},
+		{true, []byte{0x01, 0xff}, kindBinary, `'\x01ff'`},
+		{false, []byte{0x01, 0xff}, kindBinary, "X'01ff'"},
+		{true, []byte{0x01, 0x02}, kindBit, "'258'"},
+	}
+	for _, test := range tests {
+		d := &standardDialect{postgres: test.postgres}
+		if got := string(d.appendLiteral(nil, test.value, test.kind)); got != test.want {
+			t.Errorf("appendLiteral(%q, postgres %v) = %q, want %q", test.value, test.postgres, got, test.want)
+		}
+	}
+}
+
+func TestCreateTableLongNames(t *testing.T) {
+	long := strings.Repeat("x", 60)
+	create := "CREATE TABLE `orders` (\n  `id` int NOT NULL,\n  `customer_id` int NOT NULL,\n" +
+		"  KEY `" + long + "_a` (`id`),\n  KEY `" + long + "_b` (`customer_id`),\n" +
+		"  CONSTRAINT `" + long + "_fk` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`),\n" +
+		"  CONSTRAINT `" + long + "_chk` CHECK ((`id` > 0))\n) ENGINE=InnoDB"
+	d := &standardDialect{postgres: true}
+	got, _ := d.createTable("orders", create)
+	var names []string
+	for _, statement := range append([]string{got}, d.foreignKeys...) {
+		for _, token := range tokenize(statement) {
+			// The MySQL tokenizer reads double quoted identifiers as strings
+			if (token.kind == tokenIdent || token.kind == tokenString) && len(token.value()) > 20 {
+				names = append(names, token.value())
+			}
+		}
+	}
+	if len(names) != 4 {
+		t.Fatalf("long names = %q, want 4", names)
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if len(name) > maxPostgresIdent {
+			t.Errorf("%q is longer than %d bytes", name, maxPostgresIdent)
+		}
+		if seen[name[:50]+name[len(name)-8:]] {
+			t.Errorf("%q is not unique", name)
+		}
+		seen[name[:50]+name[len(name)-8:]] = true
+	}
+
+	// Names are shortened the same way every time, and only for PostgreSQL
+	if again, _ := (&standardDialect{postgres: true}).createTable("orders", create); again != got {
+		t.Errorf("createTable is not deterministic:\n%s\n%s", got, again)
+	}
+	if name := d.objectName("orders_" + long); name != d.objectName("orders_"+long) || len(name) != maxPostgresIdent {
+		t.Errorf("objectName = %q, want %d bytes", name, maxPostgresIdent)
+	}
+	if name := (&standardDialect{}).objectName("orders_" + long); name != "orders_"+long {
+		t.Errorf("SQLite objectName = %q, want it unchanged", name)
+	}
+	// Multi-byte characters are not cut in half
+	if name := d.objectName(strings.Repeat("名", 30)); !utf8.ValidString(name) || len(name) > maxPostgresIdent {
+		t.Errorf("objectName = %q, want valid UTF-8 of at most %d bytes", name, maxPostgresIdent)
+	}
+}
+
+func TestSchemaFooterResetsForeignKeys(t *testing.T) {
+	d := &standardDialect{postgres: true}
+	d.createTable("users", usersTable)
+	if footer := d.schemaFooter(); !strings.Contains(footer, "fk_role") {
+		t.Errorf("schemaFooter = %q, want the foreign key", footer)
+	}
+	if footer := d.schemaFooter(); strings.Contains(footer, "fk_role") {
+		t.Errorf("second schemaFooter = %q, want no foreign keys", footer)
+	}
+}
@@ -0,0 +1,300 @@
+package exporter
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// VerifyReport is the result of comparing an export with a restored database
+type VerifyReport struct {
+	Database string
+	Tables   []TableVerification
+}
+
+// OK reports whether every table matched the export
+func (r *VerifyReport) OK() bool {
+	for _, table := range r.Tables {
+		if !table.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// TableVerification is the result of verifying a single table. Row keys
+// are formatted as JSON arrays of the primary key values; for tables without
+// a primary key the row digest is used instead.
+type TableVerification struct {
+	Table        string
+	Missing      bool
+	ExpectedRows int64
+	// ActualRows and ActualChecksum cover the exported rows found in the
+	// target. Without row digests they cover the whole target table.
+	ActualRows       int64
+	ExpectedChecksum string
+	ActualChecksum   string
+	// MissingRows were exported but are not in the target
+	MissingRows []string
+	// ChangedRows exist in the target with different values
+	ChangedRows []string
+}
+
+// OK reports whether the table matched the export
+func (t *TableVerification) OK() bool {
+	return !t.Missing && t.ExpectedRows == t.ActualRows && t.ExpectedChecksum == t.ActualChecksum &&
+		len(t.MissingRows) == 0 && len(t.ChangedRows) == 0
+}
+
+// verifyChunkRows is the number of exported rows looked up in the target
+// with one query
+const verifyChunkRows = 500
+
+// Verify compares the tables of the connected database with the export
+// described by the manifest. Only the exported rows are read from the
+// target, looked up by primary key, so a target may hold other rows too.
+func (e *Exporter) Verify(manifestPath string) (*VerifyReport, error) {
+	return e.VerifyContext(context.Background(), manifestPath)
+}
+
+// VerifyContext is Verify stopping once ctx is canceled with a *CanceledError
+func (e *Exporter) VerifyContext(ctx context.Context, manifestPath string) (*VerifyReport, error) {
+	defer e.bind(ctx)()
+	e.operation = OperationVerify
+	report, err := e.verify(manifestPath)
+	return report, e.canceled(err)
+}
+
+func (e *Exporter) verify(manifestPath string) (*VerifyReport, error) {
+	manifest, err := ReadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.ChecksumsFile == "" {
+		return nil, fmt.Errorf(msgs.ErrManifestNoChecksums, manifestPath)
+	}
+	dir := filepath.Dir(manifestPath)
+	checksums, err := ReadChecksums(filepath.Join(dir, manifest.ChecksumsFile))
+	if err != nil {
+		return nil, err
+	}
+	var digests *digestReader
+	if manifest.RowDigestsFile != "" {
+		path := filepath.Join(dir, manifest.RowDigestsFile)
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf(msgs.ErrReadChecksums, path, err)
+		}
+		defer file.Close()
+		digests = newDigestReader(path, file)
+	}
+
+	tables, err := e.loadMetadata()
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]bool{}
+	for _, table := range tables {
+		existing[table.Name] = true
+	}
+
+	e.emit(Event{Type: EventStarted})
+	report := &VerifyReport{Database: e.config.Database}
+	for _, expected := range checksums {
+		if err := e.ctx.Err(); err != nil {
+			return nil, err
+		}
+		e.table = expected.Table
+		e.emit(Event{Type: EventTableStarted, Table: expected.Table})
+		result := TableVerification{
+			Table:            expected.Table,
+			ExpectedRows:     expected.Rows,
+			ExpectedChecksum: expected.Checksum,
+		}
+		if !existing[expected.Table] {
+			result.Missing = true
+			report.Tables = append(report.Tables, result)
+			if digests != nil {
+				if err := digests.skip(expected.Table); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if err := e.verifyTable(expected, digests, &result); err != nil {
+			return nil, err
+		}
+		report.Tables = append(report.Tables, result)
+		e.emit(Event{Type: EventTableFinished, Table: expected.Table, Rows: result.ActualRows})
+	}
+	e.emit(Event{Type: EventFinished})
+	return report, nil
+}
+
+// verifyTable recomputes the checksums of a table in the target and compares
+// them with the export. digests is nil when the export has no row digests.
+func (e *Exporter) verifyTable(expected TableChecksum, digests *digestReader, result *TableVerification) error {
+	actual := newTableChecksum(expected.Table, expected.Columns, expected.PrimaryKey, nil)
+	var err error
+	switch {
+	case digests == nil:
+		// Only the whole table can be compared
+		err = e.readTarget(actual, verifyQuery(actual, 0), nil, func(raw [][]byte) {
+			actual.addDigest(actual.digest(raw))
+		})
+	case len(expected.PrimaryKey) == 0:
+		err = e.verifyDigests(actual, digests, result)
+	default:
+		err = e.verifyKeys(actual, digests, result)
+	}
+	if err != nil {
+		return err
+	}
+	actual.finish()
+	result.ActualRows = actual.Rows
+	result.ActualChecksum = actual.Checksum
+	return nil
+}
+
+// verifyKeys looks up the exported rows of a table in the target by primary
+// key, a chunk of rows at a time
+func (e *Exporter) verifyKeys(actual *TableChecksum, digests *digestReader, result *TableVerification) error {
+	var key, digest []byte
+	for {
+		// Read the next chunk of exported rows
+		var keys []string
+		expected := make(map[string]string, verifyChunkRows)
+		var args []interface{}
+		for len(keys) < verifyChunkRows {
+			row, err := digests.read(actual.Table)
+			if err != nil {
+				return err
+			}
+			if row == nil {
+				break
+			}
+			formatted := formatKey(row.Key)
+			keys = append(keys, formatted)
+			expected[formatted] = row.Digest
+			for _, value := range row.Key {
+				args = append(args, keyArg(value))
+			}
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		err := e.readTarget(actual, verifyQuery(actual, len(keys)), args, func(raw [][]byte) {
+			key = actual.rowKey(key[:0], raw)
+			want, ok := expected[string(key)]
+			if !ok {
+				// A key that only matches under the collation of the target
+				return
+			}
+			delete(expected, string(key))
+			sum := actual.digest(raw)
+			actual.addDigest(sum)
+			digest = hex.AppendEncode(digest[:0], sum)
+			if string(digest) != want {
+				result.ChangedRows = append(result.ChangedRows, string(key))
+			}
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if _, ok := expected[key]; ok {
+				result.MissingRows = append(result.MissingRows, key)
+			}
+		}
+	}
+}
+
+// keyArg returns a primary key value read from the row digests file as a
+// query argument. Values that are not valid UTF-8 are bound as []byte, which
+// the driver sends as a binary string.
+func keyArg(value string) interface{} {
+	if !utf8.ValidString(value) {
+		return []byte(value)
+	}
+	return value
+}
+
+// verifyDigests compares the rows of a table without a primary key as
+// multisets of digests. The whole target table is read, and rows that were
+// not exported are ignored; a changed row is reported as missing.
+func (e *Exporter) verifyDigests(actual *TableChecksum, digests *digestReader, result *TableVerification) error {
+	counts := map[string]int{}
+	for {
+		row, err := digests.read(actual.Table)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			break
+		}
+		counts[row.Digest]++
+	}
+
+	var digest []byte
+	err := e.readTarget(actual, verifyQuery(actual, 0), nil, func(raw [][]byte) {
+		sum := actual.digest(raw)
+		digest = hex.AppendEncode(digest[:0], sum)
+		if counts[string(digest)] > 0 {
+			counts[string(digest)]--
+			actual.addDigest(sum)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for digest, count := range counts {
+		for ; count > 0; count-- {
+			result.MissingRows = append(result.MissingRows, digest)
+		}
+	}
+	sort.Strings(result.MissingRows)
+	return nil
+}
+
+// verifyQuery selects the exported columns of a table in the exported order,
+// so that the digests are comparable. With keys > 0 only the rows with that
+// many primary key values given as arguments are selected.
+func verifyQuery(c *TableChecksum, keys int) string {
+	query := "SELECT " + quoteIdents(c.Columns) + " FROM " + quoteIdent(c.Table)
+	if keys == 0 {
+		return query
+	}
+	column, tuple := quoteIdents(c.PrimaryKey), "?"
+	if len(c.PrimaryKey) > 1 {
+		column = "(" + column + ")"
+		tuple = "(" + strings.TrimSuffix(strings.Repeat("?, ", len(c.PrimaryKey)), ", ") + ")"
+	}
+	return query + " WHERE " + column + " IN (" + strings.TrimSuffix(strings.Repeat(tuple+", ", keys), ", ") + ")"
+}
+
+// readTarget runs a query on a table of the target and calls fn with every row
+func (e *Exporter) readTarget(c *TableChecksum, query string, args []interface{}, fn func(raw [][]byte)) error {
+	rows, err := e.q.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf(msgs.ErrQueryTableData, c.Table, err)
+	}
+	defer rows.Close()
+
+	s := newRowScanner(rows, len(c.Columns))
+	for rows.Next() {
+		if err := s.scan(rows); err != nil {
+			return fmt.Errorf(msgs.ErrReadTableData, c.Table, err)
+		}
+		fn(s.raw)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf(msgs.ErrReadTableData, c.Table, err)
+	}
+	return nil
+}
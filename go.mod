@@ -7,11 +7,11 @@ toolchain go1.24.2
 require (
 	github.com/go-sql-driver/mysql v1.7.1
 	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
 	golang.org/x/term v0.31.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 )
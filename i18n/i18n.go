@@ -32,54 +32,182 @@ func GetSystemLanguage() Language {
 // Messages contains all the messages for a specific language
 type Messages struct {
 	// Command descriptions
-	CmdShort string
-	CmdLong  string
+	CmdShort       string
+	CmdLong        string
+	CmdVerifyShort string
+	CmdVerifyLong  string
+	CmdDiffShort   string
+	CmdDiffLong    string
+	CmdCloneShort  string
+	CmdCloneLong   string
 
 	// Flag descriptions
-	FlagHost     string
-	FlagPort     string
-	FlagUser     string
-	FlagPassword string
-	FlagDatabase string
-	FlagRows     string
-	FlagOutput   string
-	FlagCompress string
+	FlagHost                   string
+	FlagPort                   string
+	FlagUser                   string
+	FlagPassword               string
+	FlagSocket                 string
+	FlagDatabase               string
+	FlagRows                   string
+	FlagOutput                 string
+	FlagOverwrite              string
+	FlagKeepPartial            string
+	FlagCompress               string
+	FlagDSN                    string
+	FlagDefaultsFile           string
+	FlagPasswordFile           string
+	FlagSSLMode                string
+	FlagSSLCA                  string
+	FlagEnableCleartextPlugin  string
+	FlagServerPublicKeyPath    string
+	FlagConnectTimeout         string
+	FlagReadTimeout            string
+	FlagWriteTimeout           string
+	FlagRetries                string
+	FlagLogFormat              string
+	FlagQuiet                  string
+	FlagVerbose                string
+	FlagManifest               string
+	FlagTargetDSN              string
+	FlagDiffTargetDSN          string
+	FlagTargetSchema           string
+	FlagAlterFile              string
+	FlagExisting               string
+	FlagBatchSize              string
+	FlagSingleTransaction      string
+	FlagSourceData             string
+	FlagDialect                string
+	FlagMaxStatementBytes      string
+	FlagRowsPerStatement       string
+	FlagCloneMaxStatementBytes string
+	FlagExtendedInsert         string
+	FlagInsertMode             string
+	FlagUpsertRowAlias         string
+	FlagSample                 string
+	FlagSampleColumn           string
+	FlagSamplePercent          string
+	FlagNoData                 string
+	FlagNoCreateInfo           string
+	FlagNoRowDigests           string
+	FlagNoDrop                 string
+	FlagIfNotExists            string
+	FlagAutoIncrement          string
+	FlagMaxRowsPerSecond       string
+	FlagMaxBytesPerSecond      string
+	FlagMaxLoad                string
+	FlagCheckReplicaDSN        string
+	FlagMaxLag                 string
+	FlagMaxReplicaLag          string
+	FlagReplicaLagTimeout      string
+	FlagStopSQLThread          string
+	FlagDefiner                string
+	FlagSQLSecurityInvoker     string
 
 	// User prompts
 	PromptPassword string
 
 	// Error messages
 	ErrReadPassword     string
-	ErrMarkRequiredFlag string
+	ErrNoPassword       string
+	ErrReadPasswordFile string
+	ErrReadOptionFile   string
+	ErrInvalidPort      string
+	ErrMissingDatabase  string
 
 	// Exporter messages
-	ExportStart       string
-	ExportComplete    string
-	ExportFoundTables string
-	ExportTableStart  string
-	ExportTableRows   string
-	ExportCreateZip   string
+	ExportStart             string
+	ExportComplete          string
+	ExportFoundTables       string
+	ExportTableStart        string
+	ExportTableRows         string
+	ExportFileWritten       string
+	ExportPartialKept       string
+	ThrottlePaused          string
+	ThrottleReplicaLag      string
+	ThrottleReplicaStopped  string
+	RetryAfterError         string
+	ReplicaLagWaiting       string
+	StartSQLThreadFailed    string
+	VerifyTableStart        string
+	VerifyTableOK           string
+	VerifyTableMissing      string
+	VerifyTableMismatch     string
+	VerifyMissingRows       string
+	VerifyChangedRows       string
+	VerifyComplete          string
+	DiffIdentical           string
+	DiffAdded               string
+	DiffRemoved             string
+	DiffChanged             string
+	DiffOptions             string
+	DiffRoutinesNotCompared string
+	CloneStart              string
+	CloneTableStart         string
+	CloneTableRows          string
+	CloneTableSkipped       string
+	CloneComplete           string
 
 	// Table structure
 	TableStructure string
 	ViewStructure  string
 
 	// Table data
-	TableData    string
-	ViewData     string
-	ViewDataNote string
+	TableData               string
+	ViewData                string
+	Warning                 string
+	ProgressRows            string
+	ProgressOverall         string
+	ProgressETA             string
+	ProgressTable           string
+	DialectUnsupportedType  string
+	DialectDroppedAttribute string
+	DialectDroppedIndex     string
+	DialectIndexPrefix      string
+	DialectDroppedOption    string
+	DialectExpression       string
+	DialectView             string
+	SampleFallback          string
+	SampleSparseKeys        string
+	ViewDataNote            string
+	ReplicationInfoPosition string
+	ReplicationInfoGTID     string
+	ReplicationInfoMissing  string
 
 	// Entity types
-	EntityTable string
-	EntityView  string
+	EntityTable   string
+	EntityView    string
+	EntityColumn  string
+	EntityIndex   string
+	EntityRoutine string
 
 	// Error messages for exporter
 	ErrConnectDB             string
 	ErrPingDB                string
+	ErrParseDSN              string
+	ErrNoDatabase            string
+	ErrInvalidSSLMode        string
+	ErrReadSSLCA             string
+	ErrReadPublicKey         string
+	ErrInvalidSourceData     string
+	ErrInvalidDialect        string
+	ErrInvalidStatementBytes string
+	ErrInvalidInsertMode     string
+	ErrNothingToExport       string
+	ErrInvalidSample         string
+	ErrInvalidSamplePercent  string
+	ErrSampleColumnRequired  string
+	ErrInvalidDefiner        string
+	ErrInvalidAutoIncrement  string
+	ErrInvalidLogFormat      string
+	ErrInvalidMaxLoad        string
+	ErrCanceled              string
+	ErrCanceledTable         string
 	ErrCreateOutputDir       string
+	ErrOutputNotEmpty        string
+	ErrPublishOutput         string
+	ErrRestoreOutput         string
 	ErrGetTables             string
 	ErrReadTableInfo         string
-	ErrCheckTableType        string
 	ErrCreateSchemaFile      string
 	ErrCreateDataFile        string
 	ErrWriteSchemaHeader     string
@@ -91,14 +219,19 @@ type Messages struct {
 	ErrQueryTableData        string
 	ErrWriteTableDataComment string
 	ErrWriteViewDataComment  string
-	ErrGetTableColumns       string
-	ErrReadTableColumns      string
+	ErrGetColumns            string
+	ErrGetPrimaryKeys        string
 	ErrReadTableData         string
 	ErrReadViewData          string
-	ErrWriteInsertStmt       string
 	ErrWriteDataValues       string
-	ErrWriteInsertEnd        string
 	ErrWriteUnlockTables     string
+	ErrWriteAutoIncrement    string
+	ErrUnknownStatusVariable string
+	ErrCheckLoad             string
+	ErrReplicaStatus         string
+	ErrNotReplica            string
+	ErrReplicaLag            string
+	ErrStopSQLThread         string
 	ErrCreateZipFile         string
 	ErrOpenFile              string
 	ErrGetFileInfo           string
@@ -107,6 +240,29 @@ type Messages struct {
 	ErrWriteZipContent       string
 	ErrWriteSchemaFooter     string
 	ErrWriteDataFooter       string
+	ErrStartSnapshot         string
+	ErrReadBinlogStatus      string
+	ErrWriteReplicationInfo  string
+	ErrWriteManifest         string
+	ErrReadManifest          string
+	ErrChecksumFile          string
+	ErrWriteChecksums        string
+	ErrReadChecksums         string
+	ErrManifestNoChecksums   string
+	ErrVerifyFailed          string
+	ErrGetRoutines           string
+	ErrGetRoutineCreateStmt  string
+	ErrDiffTarget            string
+	ErrWriteAlterScript      string
+	ErrInvalidExistingMode   string
+	ErrConnectTarget         string
+	ErrTargetNoDatabase      string
+	ErrCloneSameDatabase     string
+	ErrCheckCloneTarget      string
+	ErrCloneSchema           string
+	ErrCloneInsert           string
+	ErrMissingTargetDSN      string
+	ErrTargetDSNNoDatabase   string
 }
 
 // GetMessages returns the messages for the specified language
@@ -122,54 +278,182 @@ func GetMessages(lang Language) Messages {
 // Chinese messages
 var chineseMessages = Messages{
 	// Command descriptions
-	CmdShort: "MySQL数据库导出工具",
-	CmdLong:  "MySQL Exporter 是一个用于导出MySQL数据库表结构和数据的工具。\n可以导出指定数据库的所有表结构（包括索引）以及每张表的指定数量数据记录。\n导出的文件可以方便地导入到其他MySQL数据库中。",
+	CmdShort:       "MySQL数据库导出工具",
+	CmdLong:        "MySQL Exporter 是一个用于导出MySQL数据库表结构和数据的工具。\n可以导出指定数据库的所有表结构（包括索引）以及每张表的指定数量数据记录。\n导出的文件可以方便地导入到其他MySQL数据库中。",
+	CmdVerifyShort: "校验恢复后的数据是否与导出一致",
+	CmdVerifyLong:  "根据导出的清单文件，在目标数据库中重新计算每张表的行数和按主键的行级校验和，并报告不一致的表和行。\n目标数据库使用与导出相同的连接参数指定。",
+	CmdDiffShort:   "比较两个数据库的表结构差异",
+	CmdDiffLong:    "获取源数据库（连接参数指定）与目标数据库（--target-dsn）或schema.sql导出文件（--target-schema）的建表语句，\n规范化后报告新增、删除和修改的表、列、索引、视图和存储过程。\n--alter-file 可以生成将源数据库结构转换为目标结构的ALTER脚本。该脚本在源数据库上执行，目标是期望的结构，不会被修改。",
+	CmdCloneShort:  "将数据库直接复制到另一个数据库",
+	CmdCloneLong:   "使用与导出相同的表发现、结构提取和数据读取逻辑，把源数据库（连接参数指定）的表结构和数据\n通过批量INSERT直接写入目标数据库（--target-dsn），不生成中间文件。",
 
 	// Flag descriptions
-	FlagHost:     "MySQL服务器地址",
-	FlagPort:     "MySQL服务器端口",
-	FlagUser:     "MySQL用户名",
-	FlagPassword: "MySQL密码（如果不提供，将会提示输入）",
-	FlagDatabase: "要导出的数据库名",
-	FlagRows:     "每张表导出的最大行数",
-	FlagOutput:   "输出目录路径",
-	FlagCompress: "是否压缩输出文件",
+	FlagHost:                   "MySQL服务器地址",
+	FlagPort:                   "MySQL服务器端口",
+	FlagUser:                   "MySQL用户名",
+	FlagPassword:               "MySQL密码（如果不提供，将会提示输入）",
+	FlagSocket:                 "MySQL Unix套接字文件路径，设置后代替主机和端口",
+	FlagDatabase:               "要导出的数据库名",
+	FlagRows:                   "每张表导出的最大行数（0表示全部）",
+	FlagOutput:                 "输出目录路径",
+	FlagOverwrite:              "允许导出到非空的输出目录，替换之前导出的文件",
+	FlagKeepPartial:            "导出失败时保留暂存目录中的部分文件，便于调试",
+	FlagCompress:               "是否压缩输出文件",
+	FlagDSN:                    "完整的go-sql-driver DSN（如 user:pass@tcp(host:3306)/db），显式指定的连接参数优先",
+	FlagDefaultsFile:           "只从指定的选项文件读取[client]配置，代替默认的my.cnf查找路径",
+	FlagPasswordFile:           "从文件读取MySQL密码（仅使用第一行）",
+	FlagSSLMode:                "TLS模式：DISABLED、PREFERRED、REQUIRED、VERIFY_CA或VERIFY_IDENTITY",
+	FlagSSLCA:                  "用于验证服务器证书的CA证书文件（PEM）",
+	FlagEnableCleartextPlugin:  "允许mysql_clear_password认证插件（用于IAM等令牌认证，建议配合TLS使用）",
+	FlagServerPublicKeyPath:    "caching_sha2_password/sha256_password使用的服务器RSA公钥文件（PEM）",
+	FlagConnectTimeout:         "建立连接的超时时间",
+	FlagReadTimeout:            "每次网络读取的超时时间，0 表示不限制",
+	FlagWriteTimeout:           "每次网络写入的超时时间，0 表示不限制",
+	FlagRetries:                "连接断开等临时错误的重试次数",
+	FlagLogFormat:              "日志格式: text 或 json，日志写入标准错误",
+	FlagQuiet:                  "只输出警告和错误",
+	FlagVerbose:                "输出调试日志，包括执行的查询",
+	FlagManifest:               "导出生成的manifest.json路径",
+	FlagTargetDSN:              "目标数据库的DSN",
+	FlagDiffTargetDSN:          "具有期望结构的目标数据库的DSN（ALTER脚本在源数据库上执行）",
+	FlagTargetSchema:           "作为目标的schema.sql文件路径",
+	FlagAlterFile:              "将在源数据库上执行的ALTER脚本写入该文件（- 表示标准输出）",
+	FlagExisting:               "目标中已存在的表的处理方式：drop（删除重建）、truncate（清空后写入）或skip（跳过，默认）",
+	FlagBatchSize:              "每条INSERT语句写入的行数",
+	FlagSingleTransaction:      "在一个一致性快照事务中导出所有表",
+	FlagSourceData:             "记录快照的binlog/GTID位置：0 不记录，1 写入生效的复制语句，2 写入注释掉的复制语句（隐含 --single-transaction）",
+	FlagDialect:                "导出SQL的目标数据库方言: mysql、postgres 或 sqlite",
+	FlagMaxStatementBytes:      "每条INSERT语句的最大字节数，0表示使用源库的 max_allowed_packet",
+	FlagRowsPerStatement:       "每条多行INSERT语句的最大行数",
+	FlagCloneMaxStatementBytes: "每条INSERT语句参数的最大字节数，0表示使用目标库的 max_allowed_packet",
+	FlagExtendedInsert:         "使用多行INSERT语句，设为false时每行一条语句",
+	FlagInsertMode:             "数据语句的写入方式: insert、ignore、replace 或 upsert",
+	FlagUpsertRowAlias:         "upsert 使用MySQL 8.0.19的行别名语法代替 VALUES()",
+	FlagSample:                 "采样策略: first、newest、random、percent 或 stratified",
+	FlagSampleColumn:           "newest 排序使用的列（默认为主键），或 stratified 分层使用的列",
+	FlagSamplePercent:          "percent 采样读取的行百分比",
+	FlagNoData:                 "不导出表数据，不生成 data.sql",
+	FlagNoCreateInfo:           "不导出表结构，不生成 schema.sql",
+	FlagNoRowDigests:           "不写入 row_digests.jsonl，verify 只比较整张表的行数和校验和",
+	FlagNoDrop:                 "不在CREATE语句前写入 DROP TABLE/VIEW IF EXISTS",
+	FlagIfNotExists:            "使用 CREATE TABLE IF NOT EXISTS",
+	FlagAutoIncrement:          "AUTO_INCREMENT计数器: keep、reset、strip 或 max-exported",
+	FlagMaxRowsPerSecond:       "每秒最多读取的行数，0 表示不限制",
+	FlagMaxBytesPerSecond:      "每秒最多读取的行数据字节数，0 表示不限制",
+	FlagMaxLoad:                "源库负载上限，超过时暂停导出，例如 Threads_running=25",
+	FlagCheckReplicaDSN:        "监控复制延迟的从库 DSN",
+	FlagMaxLag:                 "--check-replica-dsn 从库允许的最大延迟",
+	FlagMaxReplicaLag:          "源库为从库时允许的最大复制延迟，导出前和每个表之前检查，0 表示不检查",
+	FlagReplicaLagTimeout:      "等待从库追上的最长时间，超时后导出失败",
+	FlagStopSQLThread:          "导出从库时停止其SQL线程，在无法使用快照事务时获得一致的数据",
+	FlagDefiner:                "视图的DEFINER: keep、strip、current-user 或 user@host 账号",
+	FlagSQLSecurityInvoker:     "视图使用 SQL SECURITY INVOKER",
 
 	// User prompts
 	PromptPassword: "请输入MySQL密码: ",
 
 	// Error messages
 	ErrReadPassword:     "读取密码失败: %w",
-	ErrMarkRequiredFlag: "标记必需标志时出错: %v",
+	ErrNoPassword:       "未提供密码，且标准输入不是终端，无法提示输入",
+	ErrReadPasswordFile: "读取密码文件 %s 失败: %w",
+	ErrReadOptionFile:   "读取选项文件 %s 失败: %w",
+	ErrInvalidPort:      "来自%[2]s的端口 %[1]q 无效",
+	ErrMissingDatabase:  "必须通过 --database 或 --dsn 指定要导出的数据库",
 
 	// Exporter messages
-	ExportStart:       "开始导出数据库 %s...",
-	ExportComplete:    "导出完成!",
-	ExportFoundTables: "找到 %d 张表",
-	ExportTableStart:  "导出表 %s...",
-	ExportTableRows:   "  导出了%s %s 的 %d 行数据",
-	ExportCreateZip:   "创建压缩文件 %s...",
+	ExportStart:             "开始导出数据库 %s...",
+	ExportComplete:          "导出完成!",
+	ExportFoundTables:       "找到 %d 张表",
+	ExportTableStart:        "导出表 %s...",
+	ExportTableRows:         "  导出了%[2]s %[3]s 的 %[1]d 行数据",
+	ExportFileWritten:       "  写入文件 %s",
+	ExportPartialKept:       "导出失败，部分文件保留在: %s",
+	ThrottlePaused:          "源库负载过高 (%s)，暂停导出",
+	ThrottleReplicaLag:      "从库延迟 %s 超过 %s",
+	ThrottleReplicaStopped:  "从库复制已停止",
+	RetryAfterError:         "%[4]v，%[1]s 后重试 (%[2]d/%[3]d)",
+	ReplicaLagWaiting:       "等待源库追上主库 (%s)",
+	StartSQLThreadFailed:    "重新启动从库SQL线程失败，请手动执行 START REPLICA SQL_THREAD: %v",
+	VerifyTableStart:        "校验表 %s...",
+	VerifyTableOK:           "  %s: 一致（%d 行）",
+	VerifyTableMissing:      "  %s: 目标数据库中不存在该表",
+	VerifyTableMismatch:     "  %s: 不一致，期望 %d 行（校验和 %s），实际 %d 行（校验和 %s）",
+	VerifyMissingRows:       "    缺失的行: %s",
+	VerifyChangedRows:       "    不同的行: %s",
+	VerifyComplete:          "校验通过: %d 张表与导出一致",
+	DiffIdentical:           "表结构一致",
+	DiffAdded:               "%s+ %s %s",
+	DiffRemoved:             "%s- %s %s",
+	DiffChanged:             "%s~ %s %s",
+	DiffOptions:             "    ~ 表选项: %s -> %s",
+	DiffRoutinesNotCompared: "存储过程和函数未比较：schema.sql文件不包含它们",
+	CloneStart:              "开始复制数据库 %s...",
+	CloneTableStart:         "复制表 %s...",
+	CloneTableRows:          "  复制了%[2]s 的 %[1]d 行数据",
+	CloneTableSkipped:       "跳过已存在的 %s",
+	CloneComplete:           "复制完成!",
 
 	// Table structure
-	TableStructure: "-- 表结构 `%s`\nDROP TABLE IF EXISTS `%s`;\n%s;\n\n",
-	ViewStructure:  "-- 视图结构 `%s`\nDROP VIEW IF EXISTS `%s`;\n%s;\n\n",
+	TableStructure: "-- 表结构 %s\n",
+	ViewStructure:  "-- 视图结构 %s\n",
 
 	// Table data
-	TableData:    "\n-- 表数据 `%s`\nLOCK TABLES `%s` WRITE;\n",
-	ViewData:     "\n-- 视图数据 `%s`\n-- 注意：视图数据仅供参考，不会被导入\n",
-	ViewDataNote: "-- 注意：视图数据仅供参考，不会被导入",
+	TableData:               "\n-- 表数据 %s\n",
+	ViewData:                "\n-- 视图数据 %s\n-- 注意：视图数据仅供参考，不会被导入\n",
+	Warning:                 "  警告: %s",
+	ProgressRows:            "  已读取表 %[2]s 的 %[1]d 行",
+	ProgressOverall:         "%3.0f%% %d/%d 行  %.0f 行/秒  已写入 %s",
+	ProgressETA:             "  预计剩余 %s",
+	ProgressTable:           "  | %s %.0f%%",
+	DialectUnsupportedType:  "表 %s 列 %s: 不支持的类型 %s 已转换为 %s",
+	DialectDroppedAttribute: "表 %s 列 %s: 不支持的属性 %s 已被忽略",
+	DialectDroppedIndex:     "表 %s: 不支持的 %s 索引 %s 已被忽略",
+	DialectIndexPrefix:      "表 %s 索引 %s: 列 %s 的前缀长度已被忽略",
+	DialectDroppedOption:    "表 %s: 不支持的表选项 %s 已被忽略",
+	DialectExpression:       "表 %s: %s 中的表达式只转换了引号，可能需要手动调整",
+	DialectView:             "视图 %s: 查询只转换了引号，MySQL特有的函数需要手动调整",
+	SampleFallback:          "表 %s 缺少 %s 采样所需的列，改为导出前面的行",
+	SampleSparseKeys:        "表 %s 的主键 %s 过于稀疏，无法按键随机查找，改为扫描全表随机采样",
+	ViewDataNote:            "-- 注意：视图数据仅供参考，不会被导入",
+	ReplicationInfoPosition: "-- 快照的binlog位置: %s:%d",
+	ReplicationInfoGTID:     "-- 快照已执行的GTID集合: %s",
+	ReplicationInfoMissing:  "-- 未找到binlog位置，服务器可能未开启binlog",
 
 	// Entity types
-	EntityTable: "表",
-	EntityView:  "视图",
+	EntityTable:   "表",
+	EntityView:    "视图",
+	EntityColumn:  "列",
+	EntityIndex:   "索引",
+	EntityRoutine: "存储过程",
 
 	// Error messages for exporter
 	ErrConnectDB:             "连接数据库失败: %w",
 	ErrPingDB:                "无法连接到数据库: %w",
+	ErrParseDSN:              "解析DSN失败: %w",
+	ErrNoDatabase:            "没有选择数据库，请通过 Config.Database 或 DSN 指定",
+	ErrInvalidSSLMode:        "无效的TLS模式 %q",
+	ErrReadSSLCA:             "读取CA证书文件 %s 失败: %w",
+	ErrReadPublicKey:         "读取服务器公钥文件 %s 失败: %w",
+	ErrInvalidSourceData:     "无效的 --source-data 值 %d，应为0、1或2",
+	ErrInvalidDialect:        "无效的方言 %q，应为 mysql、postgres 或 sqlite",
+	ErrInvalidStatementBytes: "无效的 --max-statement-bytes 值 %d，不能为负数",
+	ErrInvalidInsertMode:     "无效的插入模式 %q，应为 insert、ignore、replace 或 upsert",
+	ErrNothingToExport:       "--no-data 和 --no-create-info 不能同时使用，否则没有可导出的内容",
+	ErrInvalidSample:         "无效的采样策略 %q，应为 first、newest、random、percent 或 stratified",
+	ErrInvalidSamplePercent:  "无效的 --sample-percent 值 %g，应大于0且不超过100",
+	ErrSampleColumnRequired:  "采样策略 %s 需要 --sample-column",
+	ErrInvalidDefiner:        "无效的 --definer 值 %q，应为 keep、strip、current-user 或 user@host",
+	ErrInvalidAutoIncrement:  "无效的 --auto-increment 值 %q，应为 keep、reset、strip 或 max-exported",
+	ErrInvalidLogFormat:      "无效的 --log-format 值 %q，应为 text 或 json",
+	ErrInvalidMaxLoad:        "无效的 --max-load 值 %q，应为 变量=上限[,变量=上限...]",
+	ErrCanceled:              "操作已取消: %v",
+	ErrCanceledTable:         "处理表 %s 时操作已取消: %v",
 	ErrCreateOutputDir:       "创建输出目录失败: %w",
+	ErrOutputNotEmpty:        "输出目录 %s 不为空，使用 --overwrite 替换之前的导出",
+	ErrPublishOutput:         "将 %s 移入输出目录失败: %w",
+	ErrRestoreOutput:         "恢复之前的导出失败，其文件保留在 %s: %w",
 	ErrGetTables:             "获取表列表失败: %w",
 	ErrReadTableInfo:         "读取表信息失败: %w",
-	ErrCheckTableType:        "检查表类型失败: %w",
 	ErrCreateSchemaFile:      "创建schema文件失败: %w",
 	ErrCreateDataFile:        "创建data文件失败: %w",
 	ErrWriteSchemaHeader:     "写入schema文件头部失败: %w",
@@ -181,14 +465,19 @@ var chineseMessages = Messages{
 	ErrQueryTableData:        "查询表 %s 的数据失败: %w",
 	ErrWriteTableDataComment: "写入表 %s 的数据注释失败: %w",
 	ErrWriteViewDataComment:  "写入视图 %s 的数据注释失败: %w",
-	ErrGetTableColumns:       "获取%s %s 的列信息失败: %w",
-	ErrReadTableColumns:      "读取%s %s 的列信息失败: %w",
+	ErrGetColumns:            "获取列信息失败: %w",
+	ErrGetPrimaryKeys:        "获取主键信息失败: %w",
 	ErrReadTableData:         "读取表 %s 的行数据失败: %w",
 	ErrReadViewData:          "读取视图 %s 的行数据失败: %v",
-	ErrWriteInsertStmt:       "写入%s %s 的INSERT语句失败: %w",
 	ErrWriteDataValues:       "写入%s %s 的数据值失败: %w",
-	ErrWriteInsertEnd:        "写入%s %s 的INSERT语句结束符失败: %w",
 	ErrWriteUnlockTables:     "写入表 %s 的解锁语句失败: %w",
+	ErrWriteAutoIncrement:    "写入表 %s 的自增计数器失败: %w",
+	ErrUnknownStatusVariable: "未知的状态变量: %s",
+	ErrCheckLoad:             "检查源库负载失败: %w",
+	ErrReplicaStatus:         "读取从库状态失败: %w",
+	ErrNotReplica:            "该服务器不是从库",
+	ErrReplicaLag:            "源库在 %s 内没有追上主库: %s",
+	ErrStopSQLThread:         "停止从库SQL线程失败: %w",
 	ErrCreateZipFile:         "创建zip文件失败: %w",
 	ErrOpenFile:              "打开文件 %s 失败: %w",
 	ErrGetFileInfo:           "获取文件 %s 信息失败: %w",
@@ -197,59 +486,210 @@ var chineseMessages = Messages{
 	ErrWriteZipContent:       "写入zip文件内容失败: %w",
 	ErrWriteSchemaFooter:     "写入schema文件尾部失败: %w",
 	ErrWriteDataFooter:       "写入data文件尾部失败: %w",
+	ErrStartSnapshot:         "开启一致性快照失败: %w",
+	ErrReadBinlogStatus:      "读取binlog位置失败: %w",
+	ErrWriteReplicationInfo:  "写入复制位置信息失败: %w",
+	ErrWriteManifest:         "写入清单文件失败: %w",
+	ErrReadManifest:          "读取清单文件 %s 失败: %w",
+	ErrChecksumFile:          "计算文件 %s 的校验和失败: %w",
+	ErrWriteChecksums:        "写入校验和文件失败: %w",
+	ErrReadChecksums:         "读取校验和文件 %s 失败: %w",
+	ErrManifestNoChecksums:   "清单文件 %s 不包含行校验和",
+	ErrVerifyFailed:          "校验失败: %d/%d 张表与导出不一致",
+	ErrGetRoutines:           "获取存储过程和函数列表失败: %w",
+	ErrGetRoutineCreateStmt:  "获取存储过程或函数 %s 的创建语句失败: %w",
+	ErrDiffTarget:            "必须指定 --target-dsn 或 --target-schema 其中之一",
+	ErrWriteAlterScript:      "写入ALTER脚本失败: %w",
+	ErrInvalidExistingMode:   "无效的已存在表处理方式 %q，应为drop、truncate或skip",
+	ErrConnectTarget:         "连接目标数据库失败: %w",
+	ErrTargetNoDatabase:      "目标连接没有选择数据库",
+	ErrCloneSameDatabase:     "目标数据库 %s 就是源数据库，复制会删除或清空源表",
+	ErrCheckCloneTarget:      "无法确认目标数据库不是源数据库: %w",
+	ErrCloneSchema:           "在目标数据库中创建 %s 失败: %w",
+	ErrCloneInsert:           "向目标表 %s 写入数据失败: %w",
+	ErrMissingTargetDSN:      "必须通过 --target-dsn 指定目标数据库",
+	ErrTargetDSNNoDatabase:   "--target-dsn 必须指定数据库，例如 user:pass@tcp(host:3306)/db",
 }
 
 // English messages
 var englishMessages = Messages{
 	// Command descriptions
-	CmdShort: "MySQL Database Export Tool",
-	CmdLong:  "MySQL Exporter is a tool for exporting MySQL database table structures and data.\nIt can export all table structures (including indexes) of a specified database and a specified number of data records for each table.\nThe exported files can be easily imported into other MySQL databases.",
+	CmdShort:       "MySQL Database Export Tool",
+	CmdLong:        "MySQL Exporter is a tool for exporting MySQL database table structures and data.\nIt can export all table structures (including indexes) of a specified database and a specified number of data records for each table.\nThe exported files can be easily imported into other MySQL databases.",
+	CmdVerifyShort: "Verify that restored data matches an export",
+	CmdVerifyLong:  "Recompute per-table row counts and row-level checksums keyed by primary key in the target database\nfrom an export's manifest, and report mismatched tables and rows.\nThe target database is given with the same connection flags as an export.",
+	CmdDiffShort:   "Compare the schemas of two databases",
+	CmdDiffLong:    "Fetch the CREATE statements of the source database (given by the connection flags) and of a target database\n(--target-dsn) or a schema.sql export (--target-schema), normalize them and report added, removed and changed\ntables, columns, indexes, views and routines. --alter-file writes the ALTER script that turns the source\nschema into the target schema. The script is run on the source database; the target is the desired state\nand is not changed.",
+	CmdCloneShort:  "Copy a database straight into another database",
+	CmdCloneLong:   "Copy the table structures and data of the source database (given by the connection flags) straight into\nthe target database (--target-dsn) with batched INSERT statements, using the same table discovery, schema\nextraction and row reading as an export, without writing intermediate files.",
 
 	// Flag descriptions
-	FlagHost:     "MySQL server address",
-	FlagPort:     "MySQL server port",
-	FlagUser:     "MySQL username",
-	FlagPassword: "MySQL password (if not provided, will prompt for input)",
-	FlagDatabase: "Database name to export",
-	FlagRows:     "Maximum number of rows to export per table",
-	FlagOutput:   "Output directory path",
-	FlagCompress: "Whether to compress output files",
+	FlagHost:                   "MySQL server address",
+	FlagPort:                   "MySQL server port",
+	FlagUser:                   "MySQL username",
+	FlagPassword:               "MySQL password (if not provided, will prompt for input)",
+	FlagSocket:                 "MySQL Unix socket path, used instead of host and port when set",
+	FlagDatabase:               "Database name to export",
+	FlagRows:                   "Maximum number of rows to export per table (0 for all rows)",
+	FlagOutput:                 "Output directory path",
+	FlagOverwrite:              "Allow exporting into a non-empty output directory, replacing the files of a previous export",
+	FlagKeepPartial:            "Keep the partial files in the staging directory when the export fails, for debugging",
+	FlagCompress:               "Whether to compress output files",
+	FlagDSN:                    "Full go-sql-driver DSN (e.g. user:pass@tcp(host:3306)/db); explicitly set connection flags take precedence",
+	FlagDefaultsFile:           "Read [client] settings only from this option file instead of the default my.cnf locations",
+	FlagPasswordFile:           "Read the MySQL password from a file (first line only)",
+	FlagSSLMode:                "TLS mode: DISABLED, PREFERRED, REQUIRED, VERIFY_CA or VERIFY_IDENTITY",
+	FlagSSLCA:                  "CA certificate file (PEM) used to verify the server certificate",
+	FlagEnableCleartextPlugin:  "Allow the mysql_clear_password auth plugin (for IAM style token auth, use together with TLS)",
+	FlagServerPublicKeyPath:    "Server RSA public key file (PEM) for caching_sha2_password/sha256_password",
+	FlagConnectTimeout:         "Timeout for establishing a connection",
+	FlagReadTimeout:            "Timeout for every network read, 0 means none",
+	FlagWriteTimeout:           "Timeout for every network write, 0 means none",
+	FlagRetries:                "How often to retry after transient errors such as a dropped connection",
+	FlagLogFormat:              "Log format: text or json, logs go to standard error",
+	FlagQuiet:                  "Only log warnings and errors",
+	FlagVerbose:                "Log debug messages including the executed queries",
+	FlagManifest:               "Path to the manifest.json written by an export",
+	FlagTargetDSN:              "DSN of the target database",
+	FlagDiffTargetDSN:          "DSN of the target database with the desired schema (the ALTER script runs on the source)",
+	FlagTargetSchema:           "Path to a schema.sql file used as the target",
+	FlagAlterFile:              "Write the ALTER script for the source database to this file (- for standard output)",
+	FlagExisting:               "How to treat tables that exist in the target: drop (drop and recreate), truncate (empty and reload) or skip (the default)",
+	FlagBatchSize:              "Number of rows per INSERT statement",
+	FlagSingleTransaction:      "Export all tables from one consistent snapshot transaction",
+	FlagSourceData:             "Record the binlog/GTID coordinates of the snapshot: 0 off, 1 active replication statements, 2 commented statements (implies --single-transaction)",
+	FlagDialect:                "Target database dialect of the exported SQL: mysql, postgres or sqlite",
+	FlagMaxStatementBytes:      "Maximum size in bytes of one INSERT statement, 0 uses the source's max_allowed_packet",
+	FlagRowsPerStatement:       "Maximum number of rows of one multi-row INSERT statement",
+	FlagCloneMaxStatementBytes: "Maximum size in bytes of the values of one INSERT statement, 0 uses the target's max_allowed_packet",
+	FlagExtendedInsert:         "Use multi-row INSERT statements, false writes one statement per row",
+	FlagInsertMode:             "How data statements treat existing rows: insert, ignore, replace or upsert",
+	FlagUpsertRowAlias:         "Write upserts with the MySQL 8.0.19 row alias syntax instead of VALUES()",
+	FlagSample:                 "Sampling strategy: first, newest, random, percent or stratified",
+	FlagSampleColumn:           "Column ordering newest samples (defaults to the primary key) or stratifying stratified samples",
+	FlagSamplePercent:          "Percentage of rows read by percent sampling",
+	FlagNoData:                 "Skip table data, no data.sql is written",
+	FlagNoCreateInfo:           "Skip table structure, no schema.sql is written",
+	FlagNoRowDigests:           "Skip row_digests.jsonl, verify then only compares row counts and checksums of whole tables",
+	FlagNoDrop:                 "Do not write DROP TABLE/VIEW IF EXISTS before CREATE statements",
+	FlagIfNotExists:            "Write CREATE TABLE IF NOT EXISTS",
+	FlagAutoIncrement:          "AUTO_INCREMENT counter: keep, reset, strip or max-exported",
+	FlagMaxRowsPerSecond:       "Maximum rows read per second, 0 means no limit",
+	FlagMaxBytesPerSecond:      "Maximum bytes of row data read per second, 0 means no limit",
+	FlagMaxLoad:                "Pause the export while the source is over this load, e.g. Threads_running=25",
+	FlagCheckReplicaDSN:        "DSN of a replica whose lag is watched",
+	FlagMaxLag:                 "Maximum lag of the --check-replica-dsn replica",
+	FlagMaxReplicaLag:          "Maximum replication lag when the source is a replica, checked before the export and each table, 0 disables the check",
+	FlagReplicaLagTimeout:      "How long to wait for the replica to catch up before the export fails",
+	FlagStopSQLThread:          "Stop the SQL thread of a replica source during the export, for consistent data without a snapshot transaction",
+	FlagDefiner:                "DEFINER of views: keep, strip, current-user or a user@host account",
+	FlagSQLSecurityInvoker:     "Write views with SQL SECURITY INVOKER",
 
 	// User prompts
 	PromptPassword: "Enter MySQL password: ",
 
 	// Error messages
 	ErrReadPassword:     "Failed to read password: %w",
-	ErrMarkRequiredFlag: "Error marking required flag: %v",
+	ErrNoPassword:       "No password provided and standard input is not a terminal to prompt for one",
+	ErrReadPasswordFile: "Failed to read password file %s: %w",
+	ErrReadOptionFile:   "Failed to read option file %s: %w",
+	ErrInvalidPort:      "Invalid port %q from %s",
+	ErrMissingDatabase:  "A database to export must be given with --database or --dsn",
 
 	// Exporter messages
-	ExportStart:       "Starting export of database %s...",
-	ExportComplete:    "Export completed!",
-	ExportFoundTables: "Found %d tables",
-	ExportTableStart:  "Exporting table %s...",
-	ExportTableRows:   "  Exported %d rows from %s %s",
-	ExportCreateZip:   "Creating zip file %s...",
+	ExportStart:             "Starting export of database %s...",
+	ExportComplete:          "Export completed!",
+	ExportFoundTables:       "Found %d tables",
+	ExportTableStart:        "Exporting table %s...",
+	ExportTableRows:         "  Exported %d rows from %s %s",
+	ExportFileWritten:       "  Wrote file %s",
+	ExportPartialKept:       "Export failed, the partial files were kept in: %s",
+	ThrottlePaused:          "The source is overloaded (%s), pausing the export",
+	ThrottleReplicaLag:      "replica lag %s is over %s",
+	ThrottleReplicaStopped:  "replication on the replica is stopped",
+	RetryAfterError:         "%[4]v, retrying in %[1]s (%[2]d/%[3]d)",
+	ReplicaLagWaiting:       "Waiting for the source to catch up with its primary (%s)",
+	StartSQLThreadFailed:    "Failed to restart the replica SQL thread, run START REPLICA SQL_THREAD manually: %v",
+	VerifyTableStart:        "Verifying table %s...",
+	VerifyTableOK:           "  %s: OK (%d rows)",
+	VerifyTableMissing:      "  %s: table is missing in the target",
+	VerifyTableMismatch:     "  %s: MISMATCH, expected %d rows (checksum %s), found %d rows (checksum %s)",
+	VerifyMissingRows:       "    missing rows: %s",
+	VerifyChangedRows:       "    changed rows: %s",
+	VerifyComplete:          "Verification passed: %d tables match the export",
+	DiffIdentical:           "Schemas are identical",
+	DiffAdded:               "%s+ %s %s",
+	DiffRemoved:             "%s- %s %s",
+	DiffChanged:             "%s~ %s %s",
+	DiffOptions:             "    ~ table options: %s -> %s",
+	DiffRoutinesNotCompared: "Routines were not compared: schema.sql files do not contain them",
+	CloneStart:              "Starting clone of database %s...",
+	CloneTableStart:         "Cloning table %s...",
+	CloneTableRows:          "  Copied %d rows into %s",
+	CloneTableSkipped:       "Skipping existing %s",
+	CloneComplete:           "Clone completed!",
 
 	// Table structure
-	TableStructure: "-- Table structure for `%s`\nDROP TABLE IF EXISTS `%s`;\n%s;\n\n",
-	ViewStructure:  "-- View structure for `%s`\nDROP VIEW IF EXISTS `%s`;\n%s;\n\n",
+	TableStructure: "-- Table structure for %s\n",
+	ViewStructure:  "-- View structure for %s\n",
 
 	// Table data
-	TableData:    "\n-- Data for table `%s`\nLOCK TABLES `%s` WRITE;\n",
-	ViewData:     "\n-- Data for view `%s`\n-- Note: View data is for reference only and will not be imported\n",
-	ViewDataNote: "-- Note: View data is for reference only and will not be imported",
+	TableData:               "\n-- Data for table %s\n",
+	ViewData:                "\n-- Data for view %s\n-- Note: View data is for reference only and will not be imported\n",
+	Warning:                 "  Warning: %s",
+	ProgressRows:            "  Read %d rows from %s",
+	ProgressOverall:         "%3.0f%% %d/%d rows  %.0f rows/s  %s written",
+	ProgressETA:             "  ETA %s",
+	ProgressTable:           "  | %s %.0f%%",
+	DialectUnsupportedType:  "Table %s column %s: unsupported type %s was converted to %s",
+	DialectDroppedAttribute: "Table %s column %s: unsupported attribute %s was dropped",
+	DialectDroppedIndex:     "Table %s: unsupported %s index %s was dropped",
+	DialectIndexPrefix:      "Table %s index %s: the prefix length of column %s was dropped",
+	DialectDroppedOption:    "Table %s: unsupported table option %s was dropped",
+	DialectExpression:       "Table %s: only the quoting of the expression in %s was translated, it may need manual changes",
+	DialectView:             "View %s: only the quoting of the query was translated, MySQL specific functions need manual changes",
+	SampleFallback:          "Table %s lacks the column %s sampling needs, exporting the first rows instead",
+	SampleSparseKeys:        "The primary key %[2]s of table %[1]s is too sparse to look up random keys, sampling in a table scan instead",
+	ViewDataNote:            "-- Note: View data is for reference only and will not be imported",
+	ReplicationInfoPosition: "-- Binary log position of the snapshot: %s:%d",
+	ReplicationInfoGTID:     "-- GTID set executed at the snapshot: %s",
+	ReplicationInfoMissing:  "-- No binary log position found, binary logging may be disabled on the server",
 
 	// Entity types
-	EntityTable: "table",
-	EntityView:  "view",
+	EntityTable:   "table",
+	EntityView:    "view",
+	EntityColumn:  "column",
+	EntityIndex:   "index",
+	EntityRoutine: "routine",
 
 	// Error messages for exporter
 	ErrConnectDB:             "Failed to connect to database: %w",
 	ErrPingDB:                "Unable to connect to database: %w",
+	ErrParseDSN:              "Failed to parse DSN: %w",
+	ErrNoDatabase:            "No database selected, name one in Config.Database or the DSN",
+	ErrInvalidSSLMode:        "Invalid TLS mode %q",
+	ErrReadSSLCA:             "Failed to read CA certificate file %s: %w",
+	ErrReadPublicKey:         "Failed to read server public key file %s: %w",
+	ErrInvalidSourceData:     "Invalid --source-data value %d, expected 0, 1 or 2",
+	ErrInvalidDialect:        "Invalid dialect %q, expected mysql, postgres or sqlite",
+	ErrInvalidStatementBytes: "Invalid --max-statement-bytes value %d, it must not be negative",
+	ErrInvalidInsertMode:     "Invalid insert mode %q, expected insert, ignore, replace or upsert",
+	ErrNothingToExport:       "--no-data and --no-create-info cannot be combined, nothing would be exported",
+	ErrInvalidSample:         "Invalid sampling strategy %q, expected first, newest, random, percent or stratified",
+	ErrInvalidSamplePercent:  "Invalid --sample-percent value %g, expected more than 0 and at most 100",
+	ErrSampleColumnRequired:  "Sampling strategy %s requires --sample-column",
+	ErrInvalidDefiner:        "Invalid --definer value %q, expected keep, strip, current-user or user@host",
+	ErrInvalidAutoIncrement:  "Invalid --auto-increment value %q, expected keep, reset, strip or max-exported",
+	ErrInvalidLogFormat:      "Invalid --log-format value %q, expected text or json",
+	ErrInvalidMaxLoad:        "Invalid --max-load value %q, expected Variable=limit[,Variable=limit...]",
+	ErrCanceled:              "Operation canceled: %v",
+	ErrCanceledTable:         "Operation canceled while processing table %s: %v",
 	ErrCreateOutputDir:       "Failed to create output directory: %w",
+	ErrOutputNotEmpty:        "Output directory %s is not empty, use --overwrite to replace the previous export",
+	ErrPublishOutput:         "Failed to move %s into the output directory: %w",
+	ErrRestoreOutput:         "Failed to restore the previous export, its files were kept in %s: %w",
 	ErrGetTables:             "Failed to get table list: %w",
 	ErrReadTableInfo:         "Failed to read table information: %w",
-	ErrCheckTableType:        "Failed to check table type: %w",
 	ErrCreateSchemaFile:      "Failed to create schema file: %w",
 	ErrCreateDataFile:        "Failed to create data file: %w",
 	ErrWriteSchemaHeader:     "Failed to write schema file header: %w",
@@ -261,14 +701,19 @@ var englishMessages = Messages{
 	ErrQueryTableData:        "Failed to query data for table %s: %w",
 	ErrWriteTableDataComment: "Failed to write data comment for table %s: %w",
 	ErrWriteViewDataComment:  "Failed to write data comment for view %s: %w",
-	ErrGetTableColumns:       "Failed to get column information for %s %s: %w",
-	ErrReadTableColumns:      "Failed to read column information for %s %s: %w",
+	ErrGetColumns:            "Failed to get column information: %w",
+	ErrGetPrimaryKeys:        "Failed to get primary key information: %w",
 	ErrReadTableData:         "Failed to read row data for table %s: %w",
 	ErrReadViewData:          "Failed to read row data for view %s: %v",
-	ErrWriteInsertStmt:       "Failed to write INSERT statement for %s %s: %w",
 	ErrWriteDataValues:       "Failed to write data values for %s %s: %w",
-	ErrWriteInsertEnd:        "Failed to write INSERT statement end for %s %s: %w",
 	ErrWriteUnlockTables:     "Failed to write UNLOCK TABLES statement for table %s: %w",
+	ErrWriteAutoIncrement:    "Failed to write the auto-increment counter of table %s: %w",
+	ErrUnknownStatusVariable: "Unknown status variable: %s",
+	ErrCheckLoad:             "Failed to check the source load: %w",
+	ErrReplicaStatus:         "Failed to read the replica status: %w",
+	ErrNotReplica:            "The server is not a replica",
+	ErrReplicaLag:            "The source did not catch up with its primary within %s: %s",
+	ErrStopSQLThread:         "Failed to stop the replica SQL thread: %w",
 	ErrCreateZipFile:         "Failed to create zip file: %w",
 	ErrOpenFile:              "Failed to open file %s: %w",
 	ErrGetFileInfo:           "Failed to get file information for %s: %w",
@@ -277,6 +722,29 @@ var englishMessages = Messages{
 	ErrWriteZipContent:       "Failed to write zip file content: %w",
 	ErrWriteSchemaFooter:     "Failed to write schema file footer: %w",
 	ErrWriteDataFooter:       "Failed to write data file footer: %w",
+	ErrStartSnapshot:         "Failed to start consistent snapshot: %w",
+	ErrReadBinlogStatus:      "Failed to read binary log status: %w",
+	ErrWriteReplicationInfo:  "Failed to write replication coordinates: %w",
+	ErrWriteManifest:         "Failed to write manifest: %w",
+	ErrReadManifest:          "Failed to read manifest %s: %w",
+	ErrChecksumFile:          "Failed to checksum file %s: %w",
+	ErrWriteChecksums:        "Failed to write checksums file: %w",
+	ErrReadChecksums:         "Failed to read checksums file %s: %w",
+	ErrManifestNoChecksums:   "Manifest %s has no row checksums",
+	ErrVerifyFailed:          "Verification failed: %d of %d tables do not match the export",
+	ErrGetRoutines:           "Failed to get stored routines: %w",
+	ErrGetRoutineCreateStmt:  "Failed to get CREATE statement for routine %s: %w",
+	ErrDiffTarget:            "Exactly one of --target-dsn or --target-schema is required",
+	ErrWriteAlterScript:      "Failed to write ALTER script: %w",
+	ErrInvalidExistingMode:   "Invalid existing table mode %q, expected drop, truncate or skip",
+	ErrConnectTarget:         "Failed to connect to target database: %w",
+	ErrTargetNoDatabase:      "The target connection has no database selected",
+	ErrCloneSameDatabase:     "The target database %s is the source database, cloning would drop or truncate the source tables",
+	ErrCheckCloneTarget:      "Failed to check that the target is not the source database: %w",
+	ErrCloneSchema:           "Failed to create %s in target database: %w",
+	ErrCloneInsert:           "Failed to insert rows into target table %s: %w",
+	ErrMissingTargetDSN:      "A target database must be given with --target-dsn",
+	ErrTargetDSNNoDatabase:   "--target-dsn must name a database, for example user:pass@tcp(host:3306)/db",
 }
 
 // Current language based on system settings